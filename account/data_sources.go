@@ -3,12 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"time"
 
-	"cloud.google.com/go/storage"
+	gcstorage "cloud.google.com/go/storage"
 	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
@@ -16,11 +16,13 @@ import (
 type dataSources struct {
 	DB *sqlx.DB
 	RedisClient *redis.Client
-	StorageClient *storage.Client
+	StorageClient *gcstorage.Client
 }
 
-func initDS() (*dataSources, error) {
-	log.Printf("Initializing data sources\n")
+func initDS(logger model.Logger) (*dataSources, error) {
+	ctx := context.Background()
+
+	logger.Info(ctx, "Initializing data sources")
 
 	pgHost := os.Getenv("PG_HOST")
 	pgPort := os.Getenv("PG_PORT")
@@ -31,7 +33,7 @@ func initDS() (*dataSources, error) {
 
 	pgConnString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s", pgHost, pgPort, pgUser, pgPassword, pgDB, pgSSL)
 	
-	log.Printf("Connecting to Postgresql\n")
+	logger.Info(ctx, "Connecting to Postgresql")
 	db, err := sqlx.Open("postgres", pgConnString)
 
 	if err != nil {
@@ -45,23 +47,31 @@ func initDS() (*dataSources, error) {
 	redisHost := os.Getenv("REDIS_HOST")
 	redisPort := os.Getenv("REDIS_PORT")
 
-	log.Printf("Connecting to Redis\n")
+	logger.Info(ctx, "Connecting to Redis")
 	rdb := redis.NewClient(&redis.Options{
 		Addr:		fmt.Sprintf("%s:%s", redisHost, redisPort),
 		Password: 	"",
 		DB: 		0,
 	})
 
-	_, err = rdb.Ping(context.Background()).Result()
-
-	log.Printf("Connecting to Cloud Storage\n")
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-	storage, err := storage.NewClient(ctx)
+	_, err = rdb.Ping(ctx).Result()
 
 	if err != nil {
-		return nil, fmt.Errorf("error connection to redis: %w", err)
+		return nil, fmt.Errorf("error connecting to redis: %w", err)
+	}
+
+	// Cloud Storage is only required when images are kept there
+	var storage *gcstorage.Client
+
+	if imageStore := os.Getenv("IMAGE_STORE"); imageStore == "" || imageStore == imageStoreGC {
+		logger.Info(ctx, "Connecting to Cloud Storage")
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		storage, err = gcstorage.NewClient(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("error creating cloud storage client: %w", err)
+		}
 	}
 
 	return &dataSources{
@@ -80,8 +90,10 @@ func (d *dataSources) close() error {
 		return fmt.Errorf("error closing Redis Client: %w", err)
 	}
 
-	if err := d.StorageClient.Close(); err != nil {
-		return fmt.Errorf("error closing Cloud Storage Client: %w", err)
+	if d.StorageClient != nil {
+		if err := d.StorageClient.Close(); err != nil {
+			return fmt.Errorf("error closing Cloud Storage Client: %w", err)
+		}
 	}
 
 	return nil
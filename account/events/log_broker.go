@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type logBroker struct {
+	Logger model.Logger
+}
+
+// NewLogBroker is a factory for initializing an event broker which
+// writes events to logger instead of publishing them, for development
+func NewLogBroker(logger model.Logger) model.EventBroker {
+	return &logBroker{
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+func (b *logBroker) Publish(ctx context.Context, event *model.Event) error {
+	data, err := json.Marshal(event)
+
+	if err != nil {
+		b.Logger.Error(ctx, "Unable to marshal event", "type", event.Type, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	b.Logger.Info(ctx, "Event", "event", json.RawMessage(data))
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// MemoryBroker keeps the events published to it in memory, for tests
+type MemoryBroker struct {
+	mu     sync.Mutex
+	events []*model.Event
+}
+
+// NewMemoryBroker is a factory for initializing an empty MemoryBroker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event *model.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = append(b.events, event)
+
+	return nil
+}
+
+// Events returns the events published so far, oldest first
+func (b *MemoryBroker) Events() []*model.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]*model.Event(nil), b.events...)
+}
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// PublishFunc sends data to a subject of a messaging system, such as a
+// NATS subject or a Pub/Sub topic
+type PublishFunc func(ctx context.Context, subject string, data []byte) error
+
+type messageBroker struct {
+	Publisher     PublishFunc
+	SubjectPrefix string
+	Logger        model.Logger
+}
+
+// NewMessageBroker is a factory for initializing an event broker which
+// sends each event as JSON to the subject named by subjectPrefix and the
+// event's type, eg "memrizr.account.user.deleted", with publish. It
+// adapts the client of any messaging system, eg for NATS
+//
+//	events.NewMessageBroker(func(ctx context.Context, subject string, data []byte) error {
+//		return nc.Publish(subject, data)
+//	}, "memrizr.account.", logger)
+func NewMessageBroker(publish PublishFunc, subjectPrefix string, logger model.Logger) model.EventBroker {
+	return &messageBroker{
+		Publisher:     publish,
+		SubjectPrefix: subjectPrefix,
+		Logger:        logging.OrDefault(logger),
+	}
+}
+
+func (b *messageBroker) Publish(ctx context.Context, event *model.Event) error {
+	data, err := json.Marshal(event)
+
+	if err != nil {
+		b.Logger.Error(ctx, "Unable to marshal event", "type", event.Type, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	subject := b.SubjectPrefix + event.Type
+
+	if err := b.Publisher(ctx, subject, data); err != nil {
+		b.Logger.Error(ctx, "Unable to publish event", "id", event.ID, "subject", subject, "err", err)
+		return apperrors.NewServiceUnavailable()
+	}
+
+	return nil
+}
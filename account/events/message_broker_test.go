@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBroker(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	event := &model.Event{ID: uuid.New(), Type: model.EventUserDeleted, UID: uid}
+
+	t.Run("Success", func(t *testing.T) {
+		var subject string
+		var sent model.Event
+
+		b := NewMessageBroker(func(ctx context.Context, s string, data []byte) error {
+			subject = s
+			return json.Unmarshal(data, &sent)
+		}, "memrizr.account.", logging.Nop())
+
+		err := b.Publish(context.TODO(), event)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "memrizr.account.user.deleted", subject)
+		assert.Equal(t, *event, sent)
+	})
+
+	t.Run("Broker down", func(t *testing.T) {
+		b := NewMessageBroker(func(ctx context.Context, s string, data []byte) error {
+			return errors.New("nats: connection closed")
+		}, "memrizr.account.", logging.Nop())
+
+		err := b.Publish(context.TODO(), event)
+
+		assert.Equal(t, http.StatusServiceUnavailable, apperrors.Status(err))
+	})
+}
@@ -0,0 +1,25 @@
+package events
+
+import (
+	"context"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+type outboxBroker struct {
+	OutboxRepository model.OutboxRepository
+}
+
+// NewOutboxBroker is a factory for initializing an event broker which
+// adds events to the outbox, in the transaction of the context they're
+// published with, for a Relay to send on. Events are then kept even
+// while the real broker is down
+func NewOutboxBroker(r model.OutboxRepository) model.EventBroker {
+	return &outboxBroker{
+		OutboxRepository: r,
+	}
+}
+
+func (b *outboxBroker) Publish(ctx context.Context, event *model.Event) error {
+	return b.OutboxRepository.Add(ctx, event)
+}
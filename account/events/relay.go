@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// Relay sends the events kept in the outbox on to a broker
+type Relay struct {
+	OutboxRepository model.OutboxRepository
+	Broker           model.EventBroker
+	BatchSize        int
+	Interval         time.Duration
+	Logger           model.Logger
+}
+
+// RelayConfig holds the outbox to drain and the broker to send its
+// events to. The outbox is checked every Interval, BatchSize events
+// at a time. Failures are logged with the Logger
+type RelayConfig struct {
+	OutboxRepository model.OutboxRepository
+	Broker           model.EventBroker
+	BatchSize        int
+	Interval         time.Duration
+	Logger           model.Logger
+}
+
+// NewRelay is a factory for initializing a Relay
+func NewRelay(c *RelayConfig) *Relay {
+	return &Relay{
+		OutboxRepository: c.OutboxRepository,
+		Broker:           c.Broker,
+		BatchSize:        c.BatchSize,
+		Interval:         c.Interval,
+		Logger:           logging.OrDefault(c.Logger),
+	}
+}
+
+// RelayOnce sends the next batch of events, returning how many were sent
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	return r.OutboxRepository.Relay(ctx, r.BatchSize, r.Broker.Publish)
+}
+
+// Run relays events until ctx is done. A full batch is followed
+// straight away by the next, so a backlog is drained quickly once the
+// broker is back
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		relayed, err := r.RelayOnce(ctx)
+
+		if err != nil {
+			r.Logger.Error(ctx, "Failed to relay events", "relayed", relayed, "err", err)
+		}
+
+		if err == nil && relayed == r.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.Interval)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRelay(t *testing.T) {
+	t.Run("RelayOnce publishes to broker", func(t *testing.T) {
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+		broker := NewMemoryBroker()
+
+		r := NewRelay(&RelayConfig{
+			OutboxRepository: mockOutboxRepository,
+			Broker:           broker,
+			BatchSize:        10,
+			Interval:         time.Second,
+		})
+
+		event := &model.Event{ID: uuid.New(), Type: model.EventUserCreated}
+
+		mockOutboxRepository.On("Relay", mock.Anything, 10, mock.Anything).Run(func(args mock.Arguments) {
+			publish := args.Get(2).(func(ctx context.Context, event *model.Event) error)
+			assert.NoError(t, publish(context.TODO(), event))
+		}).Return(1, nil)
+
+		relayed, err := r.RelayOnce(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, relayed)
+		assert.Equal(t, []*model.Event{event}, broker.Events())
+	})
+
+	t.Run("Run drains a backlog without waiting", func(t *testing.T) {
+		mockOutboxRepository := new(mocks.MockOutboxRepository)
+
+		r := NewRelay(&RelayConfig{
+			OutboxRepository: mockOutboxRepository,
+			Broker:           NewMemoryBroker(),
+			BatchSize:        2,
+			Interval:         time.Hour,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		drained := make(chan struct{})
+
+		mockOutboxRepository.On("Relay", mock.Anything, 2, mock.Anything).Return(2, nil).Twice()
+		mockOutboxRepository.On("Relay", mock.Anything, 2, mock.Anything).Run(func(args mock.Arguments) {
+			close(drained)
+		}).Return(1, nil).Once()
+
+		done := make(chan struct{})
+		go func() {
+			r.Run(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-drained:
+		case <-time.After(5 * time.Second):
+			t.Fatal("backlog wasn't drained")
+		}
+
+		cancel()
+		<-done
+
+		mockOutboxRepository.AssertNumberOfCalls(t, "Relay", 3)
+	})
+}
@@ -3,7 +3,8 @@ module github.com/jacobsngoodwin/memrizr/account
 go 1.14
 
 require (
-	cloud.google.com/go/storage v1.33.0 // indirect
+	cloud.google.com/go/storage v1.33.0
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/gin-gonic/gin v1.8.2
 	github.com/go-pg/pg v8.0.7+incompatible // indirect
@@ -15,6 +16,9 @@ require (
 	github.com/lib/pq v1.10.7
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/stretchr/testify v1.8.3
-	golang.org/x/crypto v0.11.0 // indirect
+	github.com/ugorji/go/codec v1.2.7
+	golang.org/x/crypto v0.23.0
+	golang.org/x/image v0.18.0
+	golang.org/x/oauth2 v0.10.0
 	mellium.im/sasl v0.3.1 // indirect
 )
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type adminUsersReq struct {
+	Search string `form:"q"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// AdminUsers handler lists users, optionally only those whose email or
+// name contains q, a page at a time
+func (h *Handler) AdminUsers(c *gin.Context) {
+	var req adminUsersReq
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		err := apperrors.NewBadRequest("limit and offset must be positive integers")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	page, err := h.AdminService.ListUsers(ctx, &model.UserQuery{
+		Search: req.Search,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+
+	if err != nil {
+		h.logFailure(c, "Failed to list users", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// adminTargetUID reads the uid of the user an admin route acts on,
+// responding with an error if it isn't a valid uid or, unless allowSelf,
+// is the admin's own. Admins can't disable or delete their own account,
+// so there is always someone left to undo it
+func adminTargetUID(c *gin.Context, allowSelf bool) (uuid.UUID, bool) {
+	uid, err := uuid.Parse(c.Param("uid"))
+
+	if err != nil {
+		err := apperrors.NewBadRequest("Invalid uid")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return uuid.Nil, false
+	}
+
+	if authUser := c.MustGet("user").(*model.User); !allowSelf && authUser.UID == uid {
+		err := apperrors.NewBadRequest("Admins can't do that to their own account")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return uuid.Nil, false
+	}
+
+	return uid, true
+}
+
+// AdminUser handler returns a user's details
+func (h *Handler) AdminUser(c *gin.Context) {
+	uid, ok := adminTargetUID(c, true)
+
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.Get(ctx, uid)
+
+	if err != nil {
+		h.logFailure(c, "Unable to find user", err, "uid", uid)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": u,
+	})
+}
+
+// AdminDisableUser handler disables a user's account and signs them
+// out everywhere
+func (h *Handler) AdminDisableUser(c *gin.Context) {
+	h.adminSetDisabled(c, true)
+}
+
+// AdminEnableUser handler enables a disabled account again
+func (h *Handler) AdminEnableUser(c *gin.Context) {
+	h.adminSetDisabled(c, false)
+}
+
+func (h *Handler) adminSetDisabled(c *gin.Context, disabled bool) {
+	uid, ok := adminTargetUID(c, false)
+
+	if !ok {
+		return
+	}
+
+	u, err := h.AdminService.SetDisabled(adminContext(c), uid, disabled)
+
+	if err != nil {
+		h.logFailure(c, "Failed to set disabled for user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.Logger.Info(c.Request.Context(), "Admin set user disabled", "adminUID", c.MustGet("user").(*model.User).UID, "uid", uid, "disabled", disabled)
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": u,
+	})
+}
+
+// AdminSignoutUser handler signs a user out everywhere
+func (h *Handler) AdminSignoutUser(c *gin.Context) {
+	uid, ok := adminTargetUID(c, true)
+
+	if !ok {
+		return
+	}
+
+	if err := h.TokenService.Signout(adminContext(c), uid); err != nil {
+		h.logFailure(c, "Failed to sign out user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully signed out user",
+	})
+}
+
+// AdminDeleteUser handler deletes a user's account
+func (h *Handler) AdminDeleteUser(c *gin.Context) {
+	uid, ok := adminTargetUID(c, false)
+
+	if !ok {
+		return
+	}
+
+	if err := h.UserService.Delete(adminContext(c), uid); err != nil {
+		h.logFailure(c, "Failed to delete user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.Logger.Info(c.Request.Context(), "Admin deleted user", "adminUID", c.MustGet("user").(*model.User).UID, "uid", uid)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully deleted user",
+	})
+}
+
+// adminContext marks the request's context as the signed in admin's, so
+// that actions taken on another user's account are audited as theirs
+func adminContext(c *gin.Context) context.Context {
+	return model.ContextWithAdmin(c.Request.Context(), c.MustGet("user").(*model.User).UID)
+}
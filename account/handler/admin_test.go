@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	uid, _ := uuid.NewRandom()
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+	mockAdminService := new(mocks.MockAdminService)
+
+	setup := func(role string) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{UID: adminUID, Role: role})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			AdminService: mockAdminService,
+		})
+
+		return router
+	}
+
+	router := setup(model.RoleAdmin)
+
+	request := func(router *gin.Engine, method string, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(method, path, nil)
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Not an admin", func(t *testing.T) {
+		rr := request(setup(model.RoleUser), http.MethodGet, "/admin/users")
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockAdminService.AssertNotCalled(t, "ListUsers", mock.Anything, mock.Anything)
+	})
+
+	t.Run("List users", func(t *testing.T) {
+		page := &model.UserPage{
+			Users:  []*model.User{{UID: uid, Email: "bob@bob.com"}},
+			Total:  41,
+			Limit:  20,
+			Offset: 20,
+		}
+		mockAdminService.On("ListUsers", mock.Anything, &model.UserQuery{
+			Search: "bob",
+			Limit:  20,
+			Offset: 20,
+		}).Return(page, nil)
+
+		rr := request(router, http.MethodGet, "/admin/users?q=bob&limit=20&offset=20")
+
+		expected, _ := json.Marshal(page)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expected, rr.Body.Bytes())
+	})
+
+	t.Run("Invalid page", func(t *testing.T) {
+		rr := request(router, http.MethodGet, "/admin/users?limit=-1")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Invalid uid", func(t *testing.T) {
+		rr := request(router, http.MethodPost, "/admin/users/notauid/disable")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAdminService.AssertNotCalled(t, "SetDisabled", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Admin can't disable themselves", func(t *testing.T) {
+		rr := request(router, http.MethodPost, "/admin/users/"+adminUID.String()+"/disable")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockAdminService.AssertNotCalled(t, "SetDisabled", mock.Anything, adminUID, true)
+	})
+
+	t.Run("Disable user", func(t *testing.T) {
+		mockAdminService.On("SetDisabled", mock.Anything, uid, true).Return(&model.User{UID: uid, Disabled: true}, nil)
+
+		rr := request(router, http.MethodPost, "/admin/users/"+uid.String()+"/disable")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAdminService.AssertCalled(t, "SetDisabled", mock.Anything, uid, true)
+	})
+
+	t.Run("Enable user", func(t *testing.T) {
+		mockAdminService.On("SetDisabled", mock.Anything, uid, false).Return(&model.User{UID: uid}, nil)
+
+		rr := request(router, http.MethodPost, "/admin/users/"+uid.String()+"/enable")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockAdminService.AssertCalled(t, "SetDisabled", mock.Anything, uid, false)
+	})
+
+	t.Run("Sign out user", func(t *testing.T) {
+		mockTokenService.On("Signout", mock.Anything, uid).Return(nil)
+
+		rr := request(router, http.MethodPost, "/admin/users/"+uid.String()+"/signout")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertCalled(t, "Signout", mock.Anything, uid)
+	})
+
+	t.Run("Admin can't delete themselves", func(t *testing.T) {
+		rr := request(router, http.MethodDelete, "/admin/users/"+adminUID.String())
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "Delete", mock.Anything, adminUID)
+	})
+
+	t.Run("Delete user", func(t *testing.T) {
+		mockUserService.On("Delete", mock.Anything, uid).Return(nil)
+
+		rr := request(router, http.MethodDelete, "/admin/users/"+uid.String())
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "Delete", mock.Anything, uid)
+	})
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type activityReq struct {
+	Limit  int `form:"limit" binding:"omitempty,min=1"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+// Activity handler lists the signed in user's recent security activity,
+// such as signins and signouts, newest first
+func (h *Handler) Activity(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req activityReq
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		err := apperrors.NewBadRequest("limit and offset must be positive integers")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	page, err := h.UserService.GetActivity(ctx, authUser.UID, req.Limit, req.Offset)
+
+	if err != nil {
+		h.logFailure(c, "Failed to get activity for user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+type adminAuditReq struct {
+	UID       string `form:"uid" binding:"omitempty,uuid"`
+	TargetUID string `form:"targetUid" binding:"omitempty,uuid"`
+	Action    string `form:"action"`
+	Outcome   string `form:"outcome" binding:"omitempty,oneof=success failure"`
+	IP        string `form:"ip" binding:"omitempty,ip"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1"`
+	Offset    int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// AdminAudit handler searches the audit log, newest first, optionally
+// for a user (uid), the user an admin acted on (targetUid), action,
+// outcome or client ip
+func (h *Handler) AdminAudit(c *gin.Context) {
+	var req adminAuditReq
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		err := apperrors.NewBadRequest("Invalid audit log query")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	query := &model.AuditQuery{
+		Action:  req.Action,
+		Outcome: req.Outcome,
+		IP:      req.IP,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+	}
+
+	if req.UID != "" {
+		uid := uuid.MustParse(req.UID)
+		query.UID = &uid
+	}
+
+	if req.TargetUID != "" {
+		targetUID := uuid.MustParse(req.TargetUID)
+		query.TargetUID = &targetUID
+	}
+
+	ctx := c.Request.Context()
+	page, err := h.AdminService.AuditLog(ctx, query)
+
+	if err != nil {
+		h.logFailure(c, "Failed to search audit log", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestActivity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{UID: uid})
+	})
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	request := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		page := &model.AuditPage{
+			Entries: []*model.AuditEntry{{ID: 1, UID: &uid, Action: model.AuditSignin, Outcome: model.AuditSuccess, IP: "203.0.113.7"}},
+			Limit:   10,
+			Offset:  10,
+		}
+		mockUserService.On("GetActivity", mock.Anything, uid, 10, 10).Return(page, nil)
+
+		rr := request("/me/activity?limit=10&offset=10")
+
+		expected, _ := json.Marshal(page)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expected, rr.Body.Bytes())
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		rr := request("/me/activity?limit=abc")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "GetActivity", mock.Anything, uid, 0, 0)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockUserService.On("GetActivity", mock.Anything, uid, 0, 30).Return(nil, apperrors.NewInternal())
+
+		rr := request("/me/activity?offset=30")
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestAdminAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	uid, _ := uuid.NewRandom()
+
+	mockAdminService := new(mocks.MockAdminService)
+
+	setup := func(role string) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{UID: adminUID, Role: role})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			UserService:  new(mocks.MockUserService),
+			TokenService: new(mocks.MockTokenService),
+			AdminService: mockAdminService,
+		})
+
+		return router
+	}
+
+	router := setup(model.RoleAdmin)
+
+	request := func(router *gin.Engine, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Not an admin", func(t *testing.T) {
+		rr := request(setup(model.RoleUser), "/admin/audit")
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockAdminService.AssertNotCalled(t, "AuditLog", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		page := &model.AuditPage{
+			Entries: []*model.AuditEntry{{ID: 1, UID: &uid, Action: model.AuditSignin, Outcome: model.AuditFailure, IP: "203.0.113.7"}},
+			Limit:   20,
+			Offset:  0,
+		}
+		mockAdminService.On("AuditLog", mock.Anything, &model.AuditQuery{
+			UID:       &uid,
+			TargetUID: &uid,
+			Action:    model.AuditSignin,
+			Outcome:   model.AuditFailure,
+			IP:        "203.0.113.7",
+		}).Return(page, nil)
+
+		rr := request(router, "/admin/audit?uid="+uid.String()+"&targetUid="+uid.String()+"&action=signin&outcome=failure&ip=203.0.113.7")
+
+		expected, _ := json.Marshal(page)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expected, rr.Body.Bytes())
+	})
+
+	t.Run("Invalid uid", func(t *testing.T) {
+		rr := request(router, "/admin/audit?uid=notauid")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Invalid outcome", func(t *testing.T) {
+		rr := request(router, "/admin/audit?outcome=maybe")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -2,7 +2,6 @@ package handler
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -16,7 +15,7 @@ type invalidArgument struct {
 	Param 	string `json:"param"`
 }
 
-func bindData(c *gin.Context, req interface{}) bool {
+func (h *Handler) bindData(c *gin.Context, req interface{}) bool {
 	if c.ContentType() != "application/json" {
 		msg := fmt.Sprintf("%s only accepts Content-Type application/json", c.FullPath())
 
@@ -29,7 +28,7 @@ func bindData(c *gin.Context, req interface{}) bool {
 	}
 
 	if err := c.ShouldBind(req); err != nil {
-		log.Printf("Error binding data: %+v\n", err)
+		h.Logger.Debug(c.Request.Context(), "Unable to bind request data", "err", err)
 
 		if errs, ok := err.(validator.ValidationErrors); ok {
 
@@ -38,7 +37,7 @@ func bindData(c *gin.Context, req interface{}) bool {
 			for _, err := range errs {
 				invalidArgs = append(invalidArgs, invalidArgument{
 					err.Field(),
-					err.Value().(string),
+					fmt.Sprintf("%v", err.Value()),
 					err.Tag(),
 					err.Param(),
 				})
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// omitempty must be listed first (tags are evaluated sequentially)
+type detailsReq struct {
+	Name    string `json:"name" binding:"omitempty,max=50"`
+	Email   string `json:"email" binding:"required,email"`
+	Website string `json:"website" binding:"omitempty,url"`
+}
+
+// Details handler
+func (h *Handler) Details(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req detailsReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	// Should be returned with current imageURL
+	u := &model.User{
+		UID:     authUser.UID,
+		Name:    req.Name,
+		Email:   req.Email,
+		Website: req.Website,
+	}
+
+	ctx := c.Request.Context()
+	err := h.UserService.UpdateDetails(ctx, u)
+
+	if err != nil {
+		h.logFailure(c, "Failed to update user", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": u,
+	})
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// file extensions of exported images, by content type
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+type exportReq struct {
+	Format string `form:"format" binding:"omitempty,oneof=json zip"`
+}
+
+// Export handler downloads everything held about the signed in user, as
+// a JSON file or, with format=zip, a zip archive with their profile
+// image as a separate file
+func (h *Handler) Export(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req exportReq
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		err := apperrors.NewBadRequest("format must be json or zip")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	export, err := h.ExportService.Export(ctx, authUser.UID)
+
+	if err != nil {
+		h.logFailure(c, "Failed to export user data", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if req.Format != "zip" {
+		c.Header("Content-Disposition", `attachment; filename="memrizr-account.json"`)
+		c.IndentedJSON(http.StatusOK, export)
+		return
+	}
+
+	archive, err := exportArchive(export)
+
+	if err != nil {
+		h.logFailure(c, "Failed to create export archive", err)
+		e := apperrors.NewInternal()
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="memrizr-account.zip"`)
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// exportArchive zips account.json, along with the profile image
+// under profile-image/, instead of inlining it in the JSON
+func exportArchive(export *model.UserExport) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	account := *export
+
+	if export.ProfileImage != nil {
+		img := *export.ProfileImage
+		img.Data = nil
+		account.ProfileImage = &img
+
+		w, err := zw.Create("profile-image/" + img.Name + imageExtensions[img.ContentType])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := w.Write(export.ProfileImage.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(account, "", "    ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := zw.Create("account.json")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{UID: uid})
+	})
+
+	mockExportService := new(mocks.MockExportService)
+
+	NewHandler(&Config{
+		R:             router,
+		ExportService: mockExportService,
+	})
+
+	export := &model.UserExport{
+		User:     &model.User{UID: uid, Email: "bob@bob.com", Password: "ahash"},
+		Sessions: []*model.Session{{ID: "asession"}},
+		ProfileImage: &model.ExportedImage{
+			Name:        "imageobject",
+			ContentType: "image/png",
+			Data:        []byte("imagebytes"),
+		},
+	}
+	mockExportService.On("Export", mock.Anything, uid).Return(export, nil)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		rr := request("/me/export")
+
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "memrizr-account.json")
+		assert.NotContains(t, rr.Body.String(), "ahash")
+		// images are inlined, base64 encoded
+		assert.Equal(t, "aW1hZ2VieXRlcw==", resp["profileImage"].(map[string]interface{})["data"])
+	})
+
+	t.Run("Zip", func(t *testing.T) {
+		rr := request("/me/export?format=zip")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+
+		zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		assert.NoError(t, err)
+
+		files := make(map[string]string)
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			assert.NoError(t, err)
+			data, _ := ioutil.ReadAll(rc)
+			rc.Close()
+			files[f.Name] = string(data)
+		}
+
+		assert.Equal(t, "imagebytes", files["profile-image/imageobject.png"])
+		assert.Contains(t, files["account.json"], `"name": "imageobject"`)
+		assert.NotContains(t, files["account.json"], `"data"`)
+		assert.NotContains(t, files["account.json"], "ahash")
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		rr := request("/me/export?format=csv")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
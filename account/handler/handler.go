@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jacobsngoodwin/memrizr/account/handler/middleware"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
 	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
 )
@@ -14,6 +16,14 @@ import (
 type Handler struct{
 	UserService 	model.UserService
 	TokenService 	model.TokenService
+	WebAuthnService model.WebAuthnService
+	IdentityService model.IdentityService
+	OIDCService 	model.OIDCService
+	AdminService 	model.AdminService
+	ExportService 	model.ExportService
+	BaseURL 		string
+	MaxBodyBytes 	int64
+	Logger 			model.Logger
 }
 
 // Config will hold services that will eventually be injected into this
@@ -22,8 +32,28 @@ type Config struct {
 	R *gin.Engine
 	UserService 	model.UserService
 	TokenService 	model.TokenService
+	WebAuthnService model.WebAuthnService
+	IdentityService model.IdentityService
+	OIDCService 	model.OIDCService
+	AdminService 	model.AdminService
+	ExportService 	model.ExportService
 	BaseURL 		string
 	TimeoutDuration time.Duration
+	MaxBodyBytes 	int64
+	// rate limiting is disabled without a RateLimitStore. DefaultRateLimit
+	// applies to every route and AuthRateLimit to each of the routes
+	// handling credentials, which are the likeliest targets of abuse.
+	// UserRateLimit applies to signed in users across their routes,
+	// whichever IPs they come from
+	RateLimitStore 	 model.RateLimitStore
+	DefaultRateLimit *middleware.RateLimitConfig
+	AuthRateLimit 	 *middleware.RateLimitConfig
+	UserRateLimit 	 *middleware.RateLimitConfig
+	// when set, users must verify their email before changing their account
+	RequireVerifiedEmail bool
+	// requests and their failures are logged with the Logger, which
+	// defaults to writing to stderr
+	Logger 			model.Logger
 }
 
 // NewHandler initializes the handler with required injected services along with http routes
@@ -33,45 +63,144 @@ func NewHandler(c *Config) {
 	h := &Handler{
 		UserService: 	c.UserService,
 		TokenService: 	c.TokenService,
-	} // currently has no properties
+		WebAuthnService: c.WebAuthnService,
+		IdentityService: c.IdentityService,
+		OIDCService: 	c.OIDCService,
+		AdminService: 	c.AdminService,
+		ExportService: 	c.ExportService,
+		BaseURL: 		c.BaseURL,
+		MaxBodyBytes: 	c.MaxBodyBytes,
+		Logger: 		logging.OrDefault(c.Logger),
+	}
 
 	// Create an account group
 	g := c.R.Group(c.BaseURL)
+	g.Use(middleware.RequestID())
+	g.Use(middleware.RequestLogger(h.Logger))
+	g.Use(middleware.ClientInfo())
+
+	if c.RateLimitStore != nil && c.DefaultRateLimit != nil {
+		g.Use(middleware.RateLimit(c.RateLimitStore, c.DefaultRateLimit, h.Logger))
+	}
+
+	// counted per user, so runs after AuthUser
+	userLimit := next
+	if c.RateLimitStore != nil && c.UserRateLimit != nil {
+		userLimit = middleware.RateLimit(c.RateLimitStore, c.UserRateLimit, h.Logger)
+	}
+
+	verified := next
+	if c.RequireVerifiedEmail {
+		verified = middleware.RequireVerifiedEmail()
+	}
+
+	// support staff's routes, for admins only
+	var admin *gin.RouterGroup
 
 	if gin.Mode() != gin.TestMode {
 		g.Use(middleware.Timeout(c.TimeoutDuration, apperrors.NewServiceUnavailable()))
-		g.GET("/me", middleware.AuthUser(c.TokenService), h.Me)
-		g.POST("/signout", middleware.AuthUser(c.TokenService), h.Signout)
+		admin = g.Group("/admin", middleware.AuthUser(c.TokenService), userLimit, middleware.RequireRole(model.RoleAdmin))
+		g.GET("/me", middleware.AuthUser(c.TokenService), userLimit, h.Me)
+		g.DELETE("/me", middleware.AuthUser(c.TokenService), userLimit, h.DeleteMe)
+		g.GET("/me/export", middleware.AuthUser(c.TokenService), userLimit, h.Export)
+		g.GET("/me/activity", middleware.AuthUser(c.TokenService), userLimit, h.Activity)
+		g.POST("/signout", middleware.AuthUser(c.TokenService), userLimit, h.Signout)
+		g.GET("/sessions", middleware.AuthUser(c.TokenService), userLimit, h.Sessions)
+		g.DELETE("/sessions/:id", middleware.AuthUser(c.TokenService), userLimit, h.RevokeSession)
+		g.POST("/verify-email/send", middleware.AuthUser(c.TokenService), userLimit, h.SendVerificationEmail)
+		g.PUT("/password", middleware.AuthUser(c.TokenService), userLimit, h.ChangePassword)
+		g.POST("/mfa/totp", middleware.AuthUser(c.TokenService), userLimit, h.EnrollTOTP)
+		g.POST("/mfa/totp/confirm", middleware.AuthUser(c.TokenService), userLimit, h.ConfirmTOTP)
+		g.POST("/mfa/totp/disable", middleware.AuthUser(c.TokenService), userLimit, h.DisableTOTP)
+		g.POST("/passkeys/register/begin", middleware.AuthUser(c.TokenService), userLimit, h.BeginPasskeyRegistration)
+		g.POST("/passkeys/register/finish", middleware.AuthUser(c.TokenService), userLimit, h.FinishPasskeyRegistration)
+		g.GET("/passkeys", middleware.AuthUser(c.TokenService), userLimit, h.Passkeys)
+		g.DELETE("/passkeys/:id", middleware.AuthUser(c.TokenService), userLimit, h.DeletePasskey)
+		g.POST("/oidc/authorize", middleware.AuthUser(c.TokenService), userLimit, h.OIDCAuthorize)
+		g.GET("/oidc/userinfo", middleware.AuthOIDCAccess(c.TokenService), h.OIDCUserInfo)
+		g.POST("/oidc/userinfo", middleware.AuthOIDCAccess(c.TokenService), h.OIDCUserInfo)
+		g.PUT("/details", middleware.AuthUser(c.TokenService), userLimit, verified, h.Details)
+		g.POST("/image", middleware.AuthUser(c.TokenService), userLimit, verified, h.Image)
+		g.DELETE("/image", middleware.AuthUser(c.TokenService), userLimit, verified, h.DeleteImage)
 	} else {
-		g.GET("/me", h.Me)
-		g.POST("/signout", h.Signout)
+		admin = g.Group("/admin", userLimit, middleware.RequireRole(model.RoleAdmin))
+		g.GET("/me", userLimit, h.Me)
+		g.DELETE("/me", userLimit, h.DeleteMe)
+		g.GET("/me/export", userLimit, h.Export)
+		g.GET("/me/activity", userLimit, h.Activity)
+		g.POST("/signout", userLimit, h.Signout)
+		g.GET("/sessions", userLimit, h.Sessions)
+		g.DELETE("/sessions/:id", userLimit, h.RevokeSession)
+		g.POST("/verify-email/send", userLimit, h.SendVerificationEmail)
+		g.PUT("/password", userLimit, h.ChangePassword)
+		g.POST("/mfa/totp", userLimit, h.EnrollTOTP)
+		g.POST("/mfa/totp/confirm", userLimit, h.ConfirmTOTP)
+		g.POST("/mfa/totp/disable", userLimit, h.DisableTOTP)
+		g.POST("/passkeys/register/begin", userLimit, h.BeginPasskeyRegistration)
+		g.POST("/passkeys/register/finish", userLimit, h.FinishPasskeyRegistration)
+		g.GET("/passkeys", userLimit, h.Passkeys)
+		g.DELETE("/passkeys/:id", userLimit, h.DeletePasskey)
+		g.POST("/oidc/authorize", userLimit, h.OIDCAuthorize)
+		g.GET("/oidc/userinfo", h.OIDCUserInfo)
+		g.POST("/oidc/userinfo", h.OIDCUserInfo)
+		g.PUT("/details", userLimit, verified, h.Details)
+		g.POST("/image", userLimit, verified, h.Image)
+		g.DELETE("/image", userLimit, verified, h.DeleteImage)
 	}
 
-	g.POST("/signup", h.Signup)
-	g.POST("/signin", h.Signin)
-	g.POST("/tokens", h.Tokens)
-	g.POST("/image", h.Image)
-	g.DELETE("/image", h.DeleteImage)
-	g.PUT("/details", h.Details)
+	admin.GET("/users", h.AdminUsers)
+	admin.GET("/users/:uid", h.AdminUser)
+	admin.POST("/users/:uid/disable", h.AdminDisableUser)
+	admin.POST("/users/:uid/enable", h.AdminEnableUser)
+	admin.POST("/users/:uid/signout", h.AdminSignoutUser)
+	admin.DELETE("/users/:uid", h.AdminDeleteUser)
+	admin.GET("/audit", h.AdminAudit)
+
+	g.POST("/signup", authRateLimit(c, "signup"), h.Signup)
+	g.POST("/signin", authRateLimit(c, "signin"), h.Signin)
+	g.POST("/signin/mfa", authRateLimit(c, "signin-mfa"), h.SigninMFA)
+	g.POST("/signin/passkey/begin", authRateLimit(c, "signin-passkey-begin"), h.BeginPasskeySignin)
+	g.POST("/signin/passkey/finish", authRateLimit(c, "signin-passkey-finish"), h.FinishPasskeySignin)
+	g.POST("/oauth/:provider/authorize", authRateLimit(c, "oauth-authorize"), h.OAuthAuthorize)
+	g.POST("/oauth/callback", authRateLimit(c, "oauth-callback"), h.OAuthCallback)
+	g.POST("/tokens", authRateLimit(c, "tokens"), h.Tokens)
+	g.POST("/verify-email", authRateLimit(c, "verify-email"), h.VerifyEmail)
+	g.POST("/password/forgot", authRateLimit(c, "password-forgot"), h.ForgotPassword)
+	g.POST("/password/reset", authRateLimit(c, "password-reset"), h.ResetPassword)
+	g.GET("/oidc/authorize", h.OIDCStartAuthorization)
+	g.POST("/oidc/token", authRateLimit(c, "oidc-token"), h.OIDCToken)
+	g.GET("/.well-known/openid-configuration", h.OIDCDiscovery)
+	g.GET("/.well-known/jwks.json", h.JWKS)
 }
 
-// Image handler
-func (h *Handler) Image(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"hello": "it's image",
-	})
+// authRateLimit returns the AuthRateLimit middleware for a route,
+// counted separately from other routes
+func authRateLimit(c *Config, route string) gin.HandlerFunc {
+	if c.RateLimitStore == nil || c.AuthRateLimit == nil {
+		return next
+	}
+
+	cfg := *c.AuthRateLimit
+	cfg.Name = fmt.Sprintf("%s:%s", cfg.Name, route)
+
+	return middleware.RateLimit(c.RateLimitStore, &cfg, logging.OrDefault(c.Logger))
 }
 
-// DeleteImage handler
-func (h *Handler) DeleteImage(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"hello": "it's deleteImage",
-	})
+// logFailure logs a request which failed with err. Failures which are the
+// server's fault are errors, while those caused by the client, such as
+// a wrong password, are only worth noting
+func (h *Handler) logFailure(c *gin.Context, msg string, err error, keysAndValues ...interface{}) {
+	keysAndValues = append(keysAndValues, "status", apperrors.Status(err), "err", err)
+
+	if apperrors.Status(err) >= http.StatusInternalServerError {
+		h.Logger.Error(c.Request.Context(), msg, keysAndValues...)
+		return
+	}
+
+	h.Logger.Info(c.Request.Context(), msg, keysAndValues...)
 }
 
-// Details handler
-func (h *Handler) Details(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"hello": "it's details",
-	})
+// next stands in for optional middleware which is turned off
+func next(c *gin.Context) {
+	c.Next()
 }
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/handler/middleware"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/jacobsngoodwin/memrizr/account/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRateLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockTokenService.On("GetJWKS").Return(&model.JWKS{})
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:              router,
+		TokenService:   mockTokenService,
+		RateLimitStore: repository.NewMemoryRateLimitStore(),
+		DefaultRateLimit: &middleware.RateLimitConfig{
+			Name:      "default",
+			Algorithm: middleware.TokenBucket,
+			Limit:     5,
+			Window:    time.Minute,
+			KeyFunc:   middleware.KeyByIP,
+		},
+		AuthRateLimit: &middleware.RateLimitConfig{
+			Name:      "auth",
+			Algorithm: middleware.SlidingWindow,
+			Limit:     2,
+			Window:    time.Minute,
+			KeyFunc:   middleware.KeyByIP,
+		},
+	})
+
+	signup := func(ip string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		// invalid body, the limit applies however the handler responds
+		request, _ := http.NewRequest(http.MethodPost, "/signup", bytes.NewBufferString("{}"))
+		request.Header.Set("Content-Type", "application/json")
+		request.RemoteAddr = ip + ":1234"
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Auth routes have stricter limits", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			rr := signup("10.0.0.1")
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+		}
+
+		rr := signup("10.0.0.1")
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+
+		// limits are kept per client IP
+		rr = signup("10.0.0.2")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Default limit applies to every route", func(t *testing.T) {
+		// 10.0.0.1 made 3 requests to /signup already
+		for i := 0; i < 2; i++ {
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			request.RemoteAddr = "10.0.0.1:1234"
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "5", rr.Header().Get("RateLimit-Limit"))
+		}
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		request.RemoteAddr = "10.0.0.1:1234"
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("User limit applies across IPs", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		user := &model.User{UID: uid}
+
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("Get", mock.Anything, uid).Return(user, nil)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+		})
+
+		NewHandler(&Config{
+			R:              router,
+			UserService:    mockUserService,
+			TokenService:   mockTokenService,
+			RateLimitStore: repository.NewMemoryRateLimitStore(),
+			UserRateLimit: &middleware.RateLimitConfig{
+				Name:      "user",
+				Algorithm: middleware.TokenBucket,
+				Limit:     2,
+				Window:    time.Minute,
+				KeyFunc:   middleware.KeyByUser,
+			},
+		})
+
+		me := func(ip string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, "/me", nil)
+			request.RemoteAddr = ip + ":1234"
+			router.ServeHTTP(rr, request)
+
+			return rr
+		}
+
+		assert.Equal(t, http.StatusOK, me("10.0.0.1").Code)
+		assert.Equal(t, http.StatusOK, me("10.0.0.2").Code)
+
+		rr := me("10.0.0.3")
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockTokenService.On("GetJWKS").Return(&model.JWKS{})
+
+	var logs bytes.Buffer
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		TokenService: mockTokenService,
+		Logger:       logging.NewJSONLogger(&logs, logging.InfoLevel),
+	})
+
+	jwks := func(requestID string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		request.Header.Set(middleware.RequestIDHeader, requestID)
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Propagates the caller's ID", func(t *testing.T) {
+		logs.Reset()
+
+		rr := jwks("arequest-id.1")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "arequest-id.1", rr.Header().Get(middleware.RequestIDHeader))
+		assert.Contains(t, logs.String(), `"requestId":"arequest-id.1"`)
+	})
+
+	t.Run("Replaces an invalid ID", func(t *testing.T) {
+		logs.Reset()
+
+		rr := jwks("not\nan id")
+
+		requestID := rr.Header().Get(middleware.RequestIDHeader)
+		_, err := uuid.Parse(requestID)
+
+		assert.NoError(t, err)
+		assert.Contains(t, logs.String(), `"requestId":"`+requestID+`"`)
+	})
+}
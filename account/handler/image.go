@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// Image handler
+func (h *Handler) Image(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	// limit overly large request bodies
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxBodyBytes)
+
+	imageFileHeader, err := c.FormFile("imageFile")
+
+	// check for error before checking for non-nil header
+	if err != nil {
+		h.Logger.Debug(c.Request.Context(), "Unable to parse multipart/form-data", "err", err)
+
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			e := apperrors.NewPayloadTooLarge(h.MaxBodyBytes, c.Request.ContentLength)
+			c.JSON(e.Status(), gin.H{
+				"error": e,
+			})
+			return
+		}
+
+		e := apperrors.NewBadRequest("Unable to parse multipart/form-data")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	if imageFileHeader == nil {
+		e := apperrors.NewBadRequest("Must include an imageFile")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	// Don't trust the Content-Type sent by the client, check the bytes themselves
+	mimeType, err := detectImageType(imageFileHeader)
+
+	if err != nil {
+		h.Logger.Error(c.Request.Context(), "Unable to read imageFile", "err", err)
+		e := apperrors.NewBadRequest("Unable to read imageFile")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	if valid := isAllowedImageType(mimeType); !valid {
+		h.Logger.Debug(c.Request.Context(), "Image is not an allowable mime-type", "mimeType", mimeType)
+		e := apperrors.NewUnsupportedMediaType("imageFile must be 'image/jpeg' or 'image/png'")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	updatedUser, err := h.UserService.SetProfileImage(ctx, authUser.UID, imageFileHeader)
+
+	if err != nil {
+		h.logFailure(c, "Failed to set profile image for user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": updatedUser,
+	})
+}
+
+// DeleteImage handler
+func (h *Handler) DeleteImage(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	err := h.UserService.ClearProfileImage(ctx, authUser.UID)
+
+	if err != nil {
+		h.logFailure(c, "Failed to delete profile image", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		MaxBodyBytes: 4 * 1024,
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		body, contentType := multipartImage(t, "imageFile", "avatar.png", pngBytes(t, 8, 8))
+
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		updatedUser := &model.User{
+			UID:      uid,
+			ImageURL: "https://storage.googleapis.com/bucket/avatar",
+		}
+
+		mockUserService.
+			On("SetProfileImage", mock.Anything, uid, mock.AnythingOfType("*multipart.FileHeader")).
+			Return(updatedUser, nil).
+			Once()
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user": updatedUser,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Disallowed mime-type", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		// client claims png, but the bytes say otherwise
+		body, contentType := multipartImage(t, "imageFile", "avatar.png", []byte("definitely not an image"))
+
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("Body too large", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		body, contentType := multipartImage(t, "imageFile", "avatar.png", pngBytes(t, 512, 512))
+
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("No image file", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		body, contentType := multipartImage(t, "notImageFile", "avatar.png", pngBytes(t, 8, 8))
+
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Error from SetProfileImage", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		body, contentType := multipartImage(t, "imageFile", "avatar.png", pngBytes(t, 8, 8))
+
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", contentType)
+
+		mockError := apperrors.NewInternal()
+
+		mockUserService.
+			On("SetProfileImage", mock.Anything, uid, mock.AnythingOfType("*multipart.FileHeader")).
+			Return(nil, mockError).
+			Once()
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	mockUserService.AssertExpectations(t)
+}
+
+// pngBytes encodes a noisy (and therefore poorly compressible) png
+func pngBytes(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7919 % 251)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func multipartImage(t *testing.T, fieldName, fileName string, data []byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile(fieldName, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := part.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return body, writer.FormDataContentType()
+}
+
+func TestDeleteImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	t.Run("Clear profile image error", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockError := apperrors.NewInternal()
+		mockUserService.
+			On("ClearProfileImage", mock.Anything, uid).
+			Return(mockError).
+			Once()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockUserService.
+			On("ClearProfileImage", mock.Anything, uid).
+			Return(nil).
+			Once()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"message": "success",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	mockUserService.AssertExpectations(t)
+}
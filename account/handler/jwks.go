@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS handler publishes the public keys ID tokens are signed with,
+// so other services can verify ID tokens without sharing key files
+func (h *Handler) JWKS(c *gin.Context) {
+	jwks := h.TokenService.GetJWKS()
+
+	// allow clients to cache keys, but pick up rotations reasonably quickly
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
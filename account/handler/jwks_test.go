@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jwks := &model.JWKS{
+		Keys: []model.JWK{
+			{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "currentKID", N: "abc", E: "AQAB"},
+			{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "prevKID", N: "def", E: "AQAB"},
+		},
+	}
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockTokenService.On("GetJWKS").Return(jwks)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		TokenService: mockTokenService,
+	})
+
+	rr := httptest.NewRecorder()
+
+	request, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	router.ServeHTTP(rr, request)
+
+	respBody, _ := json.Marshal(jwks)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, respBody, rr.Body.Bytes())
+	assert.Equal(t, "public, max-age=300", rr.Header().Get("Cache-Control"))
+	mockTokenService.AssertExpectations(t)
+}
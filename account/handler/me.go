@@ -1,8 +1,8 @@
 package handler
 
 import (
-	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jacobsngoodwin/memrizr/account/model"
@@ -16,7 +16,7 @@ func (h *Handler) Me(c *gin.Context) {
 	user, exists := c.Get("user")
 
 	if !exists {
-		log.Printf("Unable to extract user from request context for unknown reason: %v\n", c)
+		h.Logger.Error(c.Request.Context(), "Unable to extract user from request context for unknown reason")
 		err := apperrors.NewInternal()
 		c.JSON(err.Status(), gin.H{
 			"error": err,
@@ -31,7 +31,7 @@ func (h *Handler) Me(c *gin.Context) {
 	u, err := h.UserService.Get(ctx, uid)
 
 	if err != nil {
-		log.Printf("Unable to find user: %v\n%v", uid, err)
+		h.logFailure(c, "Unable to find user", err, "uid", uid)
 		e := apperrors.NewNotFound("user", uid.String())
 
 		c.JSON(e.Status(), gin.H{
@@ -43,4 +43,39 @@ func (h *Handler) Me(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"user": u,
 	})
-}
\ No newline at end of file
+}
+
+type deleteMeReq struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeleteMe handler deletes the signed in user's account once they've
+// confirmed their password
+func (h *Handler) DeleteMe(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req deleteMeReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.DeleteAccount(ctx, authUser.UID, req.Password); err != nil {
+		h.logFailure(c, "Failed to delete account", err)
+
+		// set when too many wrong passwords lock out the user
+		if retryAfter := apperrors.RetryAfterSeconds(err); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully deleted account",
+	})
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -30,7 +31,7 @@ func TestMe(t *testing.T) {
 		}
 
 		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("Get", mock.AnythingOfType("*context.emptyCtx"), uid).Return(mockUserResp, nil)
+		mockUserService.On("Get", mock.Anything, uid).Return(mockUserResp, nil)
 
 		rr := httptest.NewRecorder()
 
@@ -120,4 +121,56 @@ func TestMe(t *testing.T) {
 		mockUserService.AssertExpectations(t)
 	})
 
-}
\ No newline at end of file
+}
+func TestDeleteMe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{UID: uid})
+	})
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	request := func(body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(body)
+		request, _ := http.NewRequest(http.MethodDelete, "/me", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Password required", func(t *testing.T) {
+		rr := request(gin.H{})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "DeleteAccount", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Incorrect password", func(t *testing.T) {
+		mockUserService.On("DeleteAccount", mock.Anything, uid, "wrongpassword").Return(apperrors.NewForbidden("Current password is incorrect"))
+
+		rr := request(gin.H{"password": "wrongpassword"})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService.On("DeleteAccount", mock.Anything, uid, "currentpassword").Return(nil)
+
+		rr := request(gin.H{"password": "currentpassword"})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "DeleteAccount", mock.Anything, uid, "currentpassword")
+	})
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// mfaChallenge responds to a signin with a challenge for the second factor
+func (h *Handler) mfaChallenge(c *gin.Context, u *model.User) {
+	challenge, err := h.TokenService.NewMFAChallenge(c.Request.Context(), u)
+
+	if err != nil {
+		h.logFailure(c, "Failed to create MFA challenge for user", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mfaRequired": true,
+		"mfaToken":    challenge,
+	})
+}
+
+type signinMFAReq struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// SigninMFA handler exchanges the MFA challenge from signin and a TOTP
+// or recovery code for tokens
+func (h *Handler) SigninMFA(c *gin.Context) {
+	var req signinMFAReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	uid, err := h.TokenService.ValidateMFAChallenge(c.Request.Context(), req.MFAToken)
+
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.VerifyMFA(ctx, uid, req.Code)
+
+	if err != nil {
+		h.logFailure(c, "Failed to verify second factor", err)
+
+		// set when too many wrong codes lock out the user
+		if retryAfter := apperrors.RetryAfterSeconds(err); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "")
+
+	if err != nil {
+		h.logFailure(c, "Failed to create tokens for user", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// EnrollTOTP handler returns a new TOTP secret and provisioning URI
+// for the user to add to their authenticator app
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	enrollment, err := h.UserService.EnrollTOTP(ctx, authUser.UID)
+
+	if err != nil {
+		h.logFailure(c, "Failed to enroll user in TOTP", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"totp": enrollment,
+	})
+}
+
+type confirmTOTPReq struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// ConfirmTOTP handler enables two-factor authentication with a first
+// code from the user's authenticator app, and returns recovery codes
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req confirmTOTPReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	codes, err := h.UserService.ConfirmTOTP(ctx, authUser.UID, req.Code)
+
+	if err != nil {
+		h.logFailure(c, "Failed to confirm TOTP", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recoveryCodes": codes,
+	})
+}
+
+type disableTOTPReq struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DisableTOTP handler turns off two-factor authentication
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req disableTOTPReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.DisableTOTP(ctx, authUser.UID, req.Password); err != nil {
+		h.logFailure(c, "Failed to disable TOTP", err)
+
+		// set when too many wrong passwords lock out the user
+		if retryAfter := apperrors.RetryAfterSeconds(err); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSigninMFA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{UID: uid, Email: "bob@bob.com", TOTPEnabled: true}
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	signinMFA := func(body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(body)
+		request, _ := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Missing code", func(t *testing.T) {
+		rr := signinMFA(gin.H{"mfaToken": "mfaChallenge"})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockTokenService.AssertNotCalled(t, "ValidateMFAChallenge", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid challenge", func(t *testing.T) {
+		mockError := apperrors.NewAuthorization("Two-factor authentication has expired, please sign in again")
+		mockTokenService.On("ValidateMFAChallenge", mock.Anything, "expiredChallenge").Return(nil, mockError)
+
+		rr := signinMFA(gin.H{"mfaToken": "expiredChallenge", "code": "123456"})
+
+		respBody, _ := json.Marshal(gin.H{"error": mockError})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertNotCalled(t, "VerifyMFA", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		mockError := apperrors.NewAuthorization("Invalid two-factor authentication code")
+		mockTokenService.On("ValidateMFAChallenge", mock.Anything, "mfaChallenge").Return(uid, nil)
+		mockUserService.On("VerifyMFA", mock.Anything, uid, "000000").Return(nil, mockError)
+
+		rr := signinMFA(gin.H{"mfaToken": "mfaChallenge", "code": "000000"})
+
+		respBody, _ := json.Marshal(gin.H{"error": mockError})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Locked out", func(t *testing.T) {
+		mockError := apperrors.NewTooManyRequests(90 * time.Second)
+		mockUserService.On("VerifyMFA", mock.Anything, uid, "111111").Return(nil, mockError)
+
+		rr := signinMFA(gin.H{"mfaToken": "mfaChallenge", "code": "111111"})
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SS: "idToken"},
+			RefreshToken: model.RefreshToken{SS: "refreshToken"},
+		}
+
+		mockUserService.On("VerifyMFA", mock.Anything, uid, "123456").Return(u, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, u, "").Return(mockTokenPair, nil)
+
+		rr := signinMFA(gin.H{"mfaToken": "mfaChallenge", "code": "123456"})
+
+		respBody, _ := json.Marshal(gin.H{"tokens": mockTokenPair})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{UID: uid})
+	})
+
+	mockUserService := new(mocks.MockUserService)
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	post := func(path string, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(body)
+		request, _ := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Enroll", func(t *testing.T) {
+		enrollment := &model.TOTPEnrollment{
+			Secret: "JBSWY3DPEHPK3PXP",
+			URI:    "otpauth://totp/Memrizr:bob@bob.com?secret=JBSWY3DPEHPK3PXP",
+		}
+		mockUserService.On("EnrollTOTP", mock.Anything, uid).Return(enrollment, nil)
+
+		rr := post("/mfa/totp", nil)
+
+		respBody, _ := json.Marshal(gin.H{"totp": enrollment})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Confirm with malformed code", func(t *testing.T) {
+		rr := post("/mfa/totp/confirm", gin.H{"code": "12ab56"})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "ConfirmTOTP", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		codes := []string{"abcd-efgh-ijkl-mnop", "qrst-uvwx-yz23-4567"}
+		mockUserService.On("ConfirmTOTP", mock.Anything, uid, "123456").Return(codes, nil)
+
+		rr := post("/mfa/totp/confirm", gin.H{"code": "123456"})
+
+		respBody, _ := json.Marshal(gin.H{"recoveryCodes": codes})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Disable with incorrect password", func(t *testing.T) {
+		mockError := apperrors.NewForbidden("Current password is incorrect")
+		mockUserService.On("DisableTOTP", mock.Anything, uid, "wrongpassword").Return(mockError)
+
+		rr := post("/mfa/totp/disable", gin.H{"password": "wrongpassword"})
+
+		respBody, _ := json.Marshal(gin.H{"error": mockError})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		mockUserService.On("DisableTOTP", mock.Anything, uid, "currentpassword").Return(nil)
+
+		rr := post("/mfa/totp/disable", gin.H{"password": "currentpassword"})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
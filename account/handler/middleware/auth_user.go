@@ -26,36 +26,18 @@ type invalidArgument struct {
 // It sets the user to the context if the user exists
 func AuthUser(s model.TokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		h := authHeader{}
-
-		// bind Authorization Header to h and check for validation errors
-		if err := c.ShouldBindHeader(&h); err != nil {
-			if errs, ok := err.(validator.ValidationErrors); ok {
-				// we used this type in bind_data to extract desired fields from errs
-				// you might consider extracting it
-				var invalidArgs []invalidArgument
-
-				for _, err := range errs {
-					invalidArgs = append(invalidArgs, invalidArgument{
-						err.Field(),
-						err.Value().(string),
-						err.Tag(),
-						err.Param(),
-					})
-				}
-
-				err := apperrors.NewBadRequest("Invalid request parameters. See invalidArgs")
-
-				c.JSON(err.Status(), gin.H{
-					"error":       err,
-					"invalidArgs": invalidArgs,
-				})
-				c.Abort()
-				return
-			}
+		token, ok := bearerToken(c)
+
+		if !ok {
+			return
+		}
 
-			// otherwise error type is unknown
-			err := apperrors.NewInternal()
+		// validate ID token here
+		user, err := s.ValidateIDToken(c.Request.Context(), token)
+
+		// handlers rely on there being a user
+		if err != nil || user == nil {
+			err := apperrors.NewAuthorization("Provided token is invalid")
 			c.JSON(err.Status(), gin.H{
 				"error": err,
 			})
@@ -63,22 +45,27 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 			return
 		}
 
-		idTokenHeader := strings.Split(h.IDToken, "Bearer ")
+		c.Set("user", user)
 
-		if len(idTokenHeader) < 2 {
-			err := apperrors.NewAuthorization("Must provide Authorization header with format `Bearer {token}`")
+		c.Next()
+	}
+}
 
-			c.JSON(err.Status(), gin.H{
-				"error": err,
-			})
-			c.Abort()
+// AuthOIDCAccess extracts an OpenID Connect client's access token from
+// the Authorization header, setting it to the context as "oidcAccess".
+// Access tokens are only good for the OpenID Connect endpoints using it
+func AuthOIDCAccess(s model.TokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+
+		if !ok {
 			return
 		}
 
-		// validate ID token here
-		user, err := s.ValidateIDToken(idTokenHeader[1])
+		access, err := s.ValidateOIDCAccessToken(c.Request.Context(), token)
 
 		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
 			err := apperrors.NewAuthorization("Provided token is invalid")
 			c.JSON(err.Status(), gin.H{
 				"error": err,
@@ -87,8 +74,63 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user", user)
+		c.Set("oidcAccess", access)
 
 		c.Next()
 	}
 }
+
+// bearerToken returns the token of an Authorization header of the form
+// "Bearer token". Without one, it responds with an error and aborts
+func bearerToken(c *gin.Context) (string, bool) {
+	h := authHeader{}
+
+	// bind Authorization Header to h and check for validation errors
+	if err := c.ShouldBindHeader(&h); err != nil {
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			// we used this type in bind_data to extract desired fields from errs
+			// you might consider extracting it
+			var invalidArgs []invalidArgument
+
+			for _, err := range errs {
+				invalidArgs = append(invalidArgs, invalidArgument{
+					err.Field(),
+					err.Value().(string),
+					err.Tag(),
+					err.Param(),
+				})
+			}
+
+			err := apperrors.NewBadRequest("Invalid request parameters. See invalidArgs")
+
+			c.JSON(err.Status(), gin.H{
+				"error":       err,
+				"invalidArgs": invalidArgs,
+			})
+			c.Abort()
+			return "", false
+		}
+
+		// otherwise error type is unknown
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		c.Abort()
+		return "", false
+	}
+
+	idTokenHeader := strings.Split(h.IDToken, "Bearer ")
+
+	if len(idTokenHeader) < 2 {
+		err := apperrors.NewAuthorization("Must provide Authorization header with format `Bearer {token}`")
+
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		c.Abort()
+		return "", false
+	}
+
+	return idTokenHeader[1], true
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// ClientInfo adds the client's IP and user agent to the request
+// context so that services can record where a request came from
+func ClientInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := model.ContextWithClientInfo(c.Request.Context(), model.ClientInfo{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// RateLimitAlgorithm selects how requests are counted
+type RateLimitAlgorithm string
+
+const (
+	// TokenBucket allows bursts of up to Limit requests, refilling
+	// steadily so that Limit requests are allowed per Window
+	TokenBucket RateLimitAlgorithm = "token_bucket"
+	// SlidingWindow allows at most Limit requests in any span of Window
+	SlidingWindow RateLimitAlgorithm = "sliding_window"
+)
+
+// RateLimitKeyFunc identifies who a request is counted against
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP counts requests per client IP
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser counts requests per authenticated user, so must run after
+// AuthUser. Unauthenticated requests are counted per client IP
+func KeyByUser(c *gin.Context) string {
+	if u, ok := c.Get("user"); ok {
+		if user, ok := u.(*model.User); ok {
+			return "uid:" + user.UID.String()
+		}
+	}
+
+	return KeyByIP(c)
+}
+
+// KeyByRoute counts all clients' requests to a route together
+func KeyByRoute(c *gin.Context) string {
+	return "route:" + c.Request.Method + ":" + c.FullPath()
+}
+
+// RateLimitConfig configures a single rate limit. Name namespaces the
+// limit's keys, so limits sharing a store don't count against each other
+type RateLimitConfig struct {
+	Name      string
+	Algorithm RateLimitAlgorithm
+	Limit     int64
+	Window    time.Duration
+	KeyFunc   RateLimitKeyFunc
+}
+
+// RateLimit rejects requests over the configured limit with a 429.
+// Responses carry RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers, and Retry-After once limited. If the store fails we let the
+// request through rather than take the API down with it
+func RateLimit(store model.RateLimitStore, cfg *RateLimitConfig, logger model.Logger) gin.HandlerFunc {
+	keyFunc := cfg.KeyFunc
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+
+	return func(c *gin.Context) {
+		key := fmt.Sprintf("%s:%s", cfg.Name, keyFunc(c))
+		ctx := c.Request.Context()
+		now := time.Now()
+
+		var res *model.RateLimitResult
+		var err error
+
+		if cfg.Algorithm == SlidingWindow {
+			res, err = store.SlidingWindow(ctx, key, cfg.Limit, cfg.Window, now)
+		} else {
+			res, err = store.TokenBucket(ctx, key, cfg.Limit, cfg.Window, now)
+		}
+
+		if err != nil {
+			logger.Warn(ctx, "Unable to check rate limit", "key", key, "err", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+		c.Header("RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+		if !res.Allowed {
+			err := apperrors.NewTooManyRequests(res.RetryAfter)
+
+			c.Header("Retry-After", strconv.Itoa(apperrors.RetryAfterSeconds(err)))
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
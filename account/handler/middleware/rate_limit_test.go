@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	setup := func(cfg *RateLimitConfig, res *model.RateLimitResult, err error) (*gin.Engine, *mocks.MockRateLimitStore) {
+		store := new(mocks.MockRateLimitStore)
+		store.On("TokenBucket", mock.Anything, mock.Anything, cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time")).Return(res, err)
+		store.On("SlidingWindow", mock.Anything, mock.Anything, cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time")).Return(res, err)
+
+		router := gin.New()
+		router.GET("/", RateLimit(store, cfg, logging.Nop()), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		return router, store
+	}
+
+	serve := func(router *gin.Engine) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/", nil)
+		request.RemoteAddr = "192.0.2.1:1234"
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		cfg := &RateLimitConfig{Name: "default", Limit: 5, Window: time.Minute}
+		router, store := setup(cfg, &model.RateLimitResult{
+			Allowed:    true,
+			Limit:      5,
+			Remaining:  4,
+			ResetAfter: 12*time.Second + time.Millisecond,
+		}, nil)
+
+		rr := serve(router)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "5", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "4", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "13", rr.Header().Get("RateLimit-Reset"))
+		assert.Empty(t, rr.Header().Get("Retry-After"))
+
+		// keyed by the limit's name and client IP
+		store.AssertCalled(t, "TokenBucket", mock.Anything, "default:ip:192.0.2.1", cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time"))
+	})
+
+	t.Run("Limited", func(t *testing.T) {
+		cfg := &RateLimitConfig{Name: "auth", Algorithm: SlidingWindow, Limit: 10, Window: time.Minute}
+		router, store := setup(cfg, &model.RateLimitResult{
+			Allowed:    false,
+			Limit:      10,
+			Remaining:  0,
+			ResetAfter: time.Minute,
+			RetryAfter: 2500 * time.Millisecond,
+		}, nil)
+
+		rr := serve(router)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "3", rr.Header().Get("Retry-After"))
+		assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+		assert.Contains(t, rr.Body.String(), `"type":"TOO_MANY_REQUESTS"`)
+
+		store.AssertCalled(t, "SlidingWindow", mock.Anything, "auth:ip:192.0.2.1", cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time"))
+		store.AssertNotCalled(t, "TokenBucket", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Store error lets request through", func(t *testing.T) {
+		cfg := &RateLimitConfig{Name: "default", Limit: 5, Window: time.Minute}
+		router, _ := setup(cfg, nil, errors.New("redis is down"))
+
+		rr := serve(router)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("Keyed by user", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		cfg := &RateLimitConfig{Name: "user", Limit: 5, Window: time.Minute, KeyFunc: KeyByUser}
+		store := new(mocks.MockRateLimitStore)
+		store.On("TokenBucket", mock.Anything, mock.Anything, cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time")).Return(&model.RateLimitResult{Allowed: true, Limit: 5}, nil)
+
+		router := gin.New()
+		router.GET("/me", func(c *gin.Context) {
+			c.Set("user", &model.User{UID: uid})
+		}, RateLimit(store, cfg, logging.Nop()), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		router.GET("/", RateLimit(store, cfg, logging.Nop()), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		for _, path := range []string{"/me", "/"} {
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, path, nil)
+			request.RemoteAddr = "192.0.2.1:1234"
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+
+		store.AssertCalled(t, "TokenBucket", mock.Anything, "user:uid:"+uid.String(), cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time"))
+		// without a user, requests are counted per client IP
+		store.AssertCalled(t, "TokenBucket", mock.Anything, "user:ip:192.0.2.1", cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time"))
+	})
+
+	t.Run("Keyed by route", func(t *testing.T) {
+		cfg := &RateLimitConfig{Name: "route", Limit: 5, Window: time.Minute, KeyFunc: KeyByRoute}
+		store := new(mocks.MockRateLimitStore)
+		store.On("TokenBucket", mock.Anything, mock.Anything, cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time")).Return(&model.RateLimitResult{Allowed: true, Limit: 5}, nil)
+
+		router := gin.New()
+		router.GET("/users/:id", RateLimit(store, cfg, logging.Nop()), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, "/users/"+ip, nil)
+			request.RemoteAddr = ip + ":1234"
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+
+		// every client and path parameter counts against the route
+		store.AssertNumberOfCalls(t, "TokenBucket", 2)
+		store.AssertCalled(t, "TokenBucket", mock.Anything, "route:route:GET:/users/:id", cfg.Limit, cfg.Window, mock.AnythingOfType("time.Time"))
+	})
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// RequestIDHeader carries a request's ID, both ways
+const RequestIDHeader = "X-Request-ID"
+
+// IDs from clients or proxies are only kept if they can't be used to
+// forge or garble log entries
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID adds an ID to the request context, so that every entry
+// logged while handling a request can be found by it. The ID is taken
+// from the X-Request-ID header when set, so requests can be traced
+// across services, and is returned in the same header
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+
+		c.Request = c.Request.WithContext(model.ContextWithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// RequireRole rejects users without role. It reads the user set by
+// AuthUser, so must run after it. As the role comes from the user's ID
+// token, a change of role applies once their tokens are refreshed
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, ok := c.Get("user")
+
+		if user, isUser := u.(*model.User); !ok || !isUser || user.Role != role {
+			err := apperrors.NewForbidden("You don't have permission to do that")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// RequireVerifiedEmail rejects users who haven't verified their email
+// address. It reads the user set by AuthUser, so must run after it.
+// As the user comes from their ID token, users who have just verified
+// need to refresh their tokens first
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, ok := c.Get("user")
+
+		if user, isUser := u.(*model.User); !ok || !isUser || !user.EmailVerified {
+			err := apperrors.NewForbidden("Email address must be verified. If you have verified it, refresh your tokens")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
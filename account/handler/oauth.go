@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// oauthStateCookie ties a signin to the browser which started it, so that
+// an attacker can't complete their own signin in a victim's browser. It
+// holds a hash of the state, which remains single use in the repository
+const oauthStateCookie = "oauth_state"
+
+func hashOAuthState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// setOAuthStateCookie sets the cookie for state, or clears it when state
+// is empty. The cookie is only sent to the OAuth routes, and is marked
+// Secure when the request came over HTTPS
+func (h *Handler) setOAuthStateCookie(c *gin.Context, state string) {
+	value, maxAge := hashOAuthState(state), 0
+	if state == "" {
+		value, maxAge = "", -1
+	}
+
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, value, maxAge, h.BaseURL+"/oauth", "", secure, true)
+}
+
+// OAuthAuthorize handler starts a signin with an identity provider,
+// returning the URL to send the user to
+func (h *Handler) OAuthAuthorize(c *gin.Context) {
+	ctx := c.Request.Context()
+	url, state, err := h.IdentityService.AuthCodeURL(ctx, c.Param("provider"))
+
+	if err != nil {
+		h.logFailure(c, "Failed to start signin with identity provider", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.setOAuthStateCookie(c, state)
+
+	c.JSON(http.StatusOK, gin.H{
+		"url": url,
+	})
+}
+
+type oauthCallbackReq struct {
+	State string `json:"state" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// OAuthCallback handler exchanges the state and code the identity
+// provider redirected the user back with for tokens. The state must be
+// the one set in this browser's cookie at /oauth/:provider/authorize.
+// As at /signin, users with two-factor authentication are given an MFA
+// challenge
+func (h *Handler) OAuthCallback(c *gin.Context) {
+	var req oauthCallbackReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// the cookie is only good for one attempt
+	cookie, _ := c.Cookie(oauthStateCookie)
+	h.setOAuthStateCookie(c, "")
+
+	if subtle.ConstantTimeCompare([]byte(cookie), []byte(hashOAuthState(req.State))) != 1 {
+		err := apperrors.NewAuthorization("Sign in was not started in this browser")
+		h.logFailure(c, "OAuth state does not match cookie", err)
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	u, err := h.IdentityService.Signin(ctx, req.State, req.Code)
+
+	if err != nil {
+		h.logFailure(c, "Failed to sign in with identity provider", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if u.TOTPEnabled {
+		h.mfaChallenge(c, u)
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "")
+
+	if err != nil {
+		h.logFailure(c, "Failed to create tokens for user", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
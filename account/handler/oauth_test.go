@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockIdentityService := new(mocks.MockIdentityService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:               router,
+		TokenService:    mockTokenService,
+		IdentityService: mockIdentityService,
+	})
+
+	request := func(path string, body interface{}, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(body)
+		request, _ := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	stateCookie := func(state string) *http.Cookie {
+		return &http.Cookie{Name: oauthStateCookie, Value: hashOAuthState(state)}
+	}
+
+	t.Run("Authorize", func(t *testing.T) {
+		mockIdentityService.On("AuthCodeURL", mock.Anything, "google").Return("https://accounts.google.test/authorize", "state", nil)
+
+		rr := request("/oauth/google/authorize", nil)
+
+		respBody, _ := json.Marshal(gin.H{"url": "https://accounts.google.test/authorize"})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+
+		// the browser is given a hash of the state, never the state itself
+		cookies := rr.Result().Cookies()
+		assert.Len(t, cookies, 1)
+		assert.Equal(t, oauthStateCookie, cookies[0].Name)
+		assert.Equal(t, hashOAuthState("state"), cookies[0].Value)
+		assert.Equal(t, "/oauth", cookies[0].Path)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+	})
+
+	t.Run("Authorize unknown provider", func(t *testing.T) {
+		mockError := apperrors.NewNotFound("provider", "myspace")
+		mockIdentityService.On("AuthCodeURL", mock.Anything, "myspace").Return("", "", mockError)
+
+		rr := request("/oauth/myspace/authorize", nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Callback without code", func(t *testing.T) {
+		rr := request("/oauth/callback", gin.H{"state": "state"})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockIdentityService.AssertNotCalled(t, "Signin", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Callback from another browser", func(t *testing.T) {
+		for _, cookies := range [][]*http.Cookie{nil, {stateCookie("other")}} {
+			rr := request("/oauth/callback", gin.H{"state": "attacker", "code": "code"}, cookies...)
+
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			mockIdentityService.AssertNotCalled(t, "Signin", mock.Anything, "attacker", mock.Anything)
+		}
+	})
+
+	t.Run("Callback with expired state", func(t *testing.T) {
+		mockError := apperrors.NewAuthorization("Sign in has expired or was already completed")
+		mockIdentityService.On("Signin", mock.Anything, "expired", "code").Return(nil, mockError)
+
+		rr := request("/oauth/callback", gin.H{"state": "expired", "code": "code"}, stateCookie("expired"))
+
+		respBody, _ := json.Marshal(gin.H{"error": mockError})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{UID: uid, Email: "bob@bob.com"}
+
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SS: "idToken"},
+			RefreshToken: model.RefreshToken{SS: "refreshToken"},
+		}
+
+		mockIdentityService.On("Signin", mock.Anything, "state", "code").Return(u, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, u, "").Return(mockTokenPair, nil)
+
+		rr := request("/oauth/callback", gin.H{"state": "state", "code": "code"}, stateCookie("state"))
+
+		respBody, _ := json.Marshal(gin.H{"tokens": mockTokenPair})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+
+		// the cookie is cleared
+		cookies := rr.Result().Cookies()
+		assert.Len(t, cookies, 1)
+		assert.Equal(t, oauthStateCookie, cookies[0].Name)
+		assert.True(t, cookies[0].MaxAge < 0)
+	})
+
+	t.Run("Callback with two-factor authentication", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{UID: uid, Email: "twofactor@bob.com", TOTPEnabled: true}
+
+		mockIdentityService.On("Signin", mock.Anything, "twofactor", "code").Return(u, nil)
+		mockTokenService.On("NewMFAChallenge", mock.Anything, u).Return("mfaChallenge", nil)
+
+		rr := request("/oauth/callback", gin.H{"state": "twofactor", "code": "code"}, stateCookie("twofactor"))
+
+		respBody, _ := json.Marshal(gin.H{
+			"mfaRequired": true,
+			"mfaToken":    "mfaChallenge",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, u, "")
+	})
+}
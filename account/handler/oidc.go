@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// OIDCDiscovery handler publishes the OpenID Connect discovery document,
+// so clients can use standard OpenID Connect libraries
+func (h *Handler) OIDCDiscovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.OIDCService.Discovery())
+}
+
+// the parameters of an authentication request, as sent by clients
+// to GET /oidc/authorize, and passed on to POST /oidc/authorize by the
+// login page
+type oidcAuthorizeReq struct {
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	ResponseType        string `form:"response_type" json:"response_type"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	Nonce               string `form:"nonce" json:"nonce"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+func (r *oidcAuthorizeReq) toModel() *model.OIDCAuthorizationRequest {
+	return &model.OIDCAuthorizationRequest{
+		ClientID:            r.ClientID,
+		RedirectURI:         r.RedirectURI,
+		ResponseType:        r.ResponseType,
+		Scope:               r.Scope,
+		State:               r.State,
+		Nonce:               r.Nonce,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+	}
+}
+
+// OIDCStartAuthorization handler is the authorization endpoint clients
+// send users to. It redirects them on to the web client's login page,
+// or back to the client if the request is invalid
+func (h *Handler) OIDCStartAuthorization(c *gin.Context) {
+	var req oidcAuthorizeReq
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		err := apperrors.NewBadRequest("client_id and redirect_uri are required")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	redirectURL, err := h.OIDCService.StartAuthorization(c.Request.Context(), req.toModel())
+
+	if err != nil {
+		h.logFailure(c, "Failed to start OIDC authorization", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// OIDCAuthorize handler is called by the login page once the user has
+// signed in and approved a client's request. It returns the URL to send
+// the user back to the client with
+func (h *Handler) OIDCAuthorize(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req oidcAuthorizeReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	redirectURL, err := h.OIDCService.Authorize(ctx, authUser.UID, req.toModel())
+
+	if err != nil {
+		h.logFailure(c, "Failed to authorize OIDC client", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"redirectUrl": redirectURL,
+	})
+}
+
+// OIDCToken handler is the token endpoint, where clients exchange an
+// authorization code or refresh token for tokens. As OAuth 2.0 requires,
+// it takes form parameters and responds with OAuth errors
+func (h *Handler) OIDCToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+
+	if ok {
+		// credentials are form encoded before being put in the header
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+
+	client, err := h.OIDCService.AuthenticateClient(clientID, clientSecret)
+
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var authorization *model.OIDCAuthorization
+	var prevTokenID string
+	var uid uuid.UUID
+
+	switch c.PostForm("grant_type") {
+	case "authorization_code":
+		authorization, err = h.OIDCService.ExchangeCode(ctx, client, c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"))
+
+		if err != nil {
+			oauthError(c, err)
+			return
+		}
+
+		uid = authorization.UID
+	case "refresh_token":
+		refreshToken, err := h.TokenService.ValidateRefreshToken(ctx, c.PostForm("refresh_token"))
+
+		if err != nil {
+			oauthError(c, err)
+			return
+		}
+
+		// the web client's and other clients' tokens are no good here
+		if refreshToken.ClientID != client.ID {
+			oauthError(c, &model.OAuthError{Code: "invalid_grant", Description: "The refresh token was not issued to the client"})
+			return
+		}
+
+		uid = refreshToken.UID
+		prevTokenID = refreshToken.ID.String()
+
+		// refreshed tokens keep the scope the user authorized
+		authorization = &model.OIDCAuthorization{
+			ClientID: refreshToken.ClientID,
+			UID:      refreshToken.UID,
+			Scope:    refreshToken.Scope,
+		}
+	default:
+		oauthError(c, &model.OAuthError{Code: "unsupported_grant_type", Description: "grant_type must be authorization_code or refresh_token"})
+		return
+	}
+
+	u, err := h.UserService.Get(ctx, uid)
+
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	tokens, err := h.TokenService.NewOIDCTokens(ctx, u, authorization, prevTokenID)
+
+	if err != nil {
+		h.logFailure(c, "Failed to create OIDC tokens for user", err)
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// oauthError responds with err as an OAuth error. Other errors, eg an
+// invalid refresh token, mean the grant is invalid, unless they're ours
+func oauthError(c *gin.Context, err error) {
+	var e *model.OAuthError
+
+	if !errors.As(err, &e) {
+		if status := apperrors.Status(err); status >= http.StatusInternalServerError {
+			c.JSON(status, &model.OAuthError{Code: "server_error"})
+			return
+		}
+
+		e = &model.OAuthError{Code: "invalid_grant", Description: err.Error()}
+	}
+
+	status := http.StatusBadRequest
+
+	if e.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="memrizr"`)
+	}
+
+	c.JSON(status, e)
+}
+
+// OIDCUserInfo handler returns claims about the user an access token
+// was issued to, as far as the token's scope allows
+func (h *Handler) OIDCUserInfo(c *gin.Context) {
+	access := c.MustGet("oidcAccess").(*model.OIDCAccessToken)
+
+	ctx := c.Request.Context()
+	info, err := h.OIDCService.UserInfo(ctx, access)
+
+	if err != nil {
+		h.logFailure(c, "Failed to get OIDC userinfo", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
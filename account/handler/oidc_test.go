@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOIDC(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	mockOIDCService := new(mocks.MockOIDCService)
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", u)
+		c.Set("oidcAccess", &model.OIDCAccessToken{UID: uid, ClientID: "deck", Scope: "openid email"})
+	})
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+		OIDCService:  mockOIDCService,
+	})
+
+	client := &model.OIDCClient{ID: "deck", Secret: "decksecret"}
+	mockOIDCService.On("AuthenticateClient", "deck", "decksecret").Return(client, nil)
+	mockOIDCService.On("AuthenticateClient", mock.Anything, mock.Anything).Return(nil, &model.OAuthError{Code: "invalid_client", Description: "Client authentication failed"})
+
+	mockUserService.On("Get", mock.Anything, uid).Return(u, nil)
+
+	token := func(form url.Values, basicAuth bool) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodPost, "/oidc/token", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		if basicAuth {
+			request.SetBasicAuth("deck", "decksecret")
+		}
+
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Discovery", func(t *testing.T) {
+		discovery := &model.OIDCDiscovery{Issuer: "https://memrizr.test/api/account"}
+		mockOIDCService.On("Discovery").Return(discovery)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(discovery)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Start authorization", func(t *testing.T) {
+		mockOIDCService.On("StartAuthorization", mock.Anything, &model.OIDCAuthorizationRequest{
+			ClientID:     "deck",
+			RedirectURI:  "https://deck.memrizr.test/callback",
+			ResponseType: "code",
+			Scope:        "openid",
+			State:        "state",
+		}).Return("https://memrizr.test/authorize?client_id=deck", nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/oidc/authorize?client_id=deck&redirect_uri=https%3A%2F%2Fdeck.memrizr.test%2Fcallback&response_type=code&scope=openid&state=state", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusFound, rr.Code)
+		assert.Equal(t, "https://memrizr.test/authorize?client_id=deck", rr.Header().Get("Location"))
+	})
+
+	t.Run("Start authorization without client", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/oidc/authorize?response_type=code", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockOIDCService.AssertNotCalled(t, "StartAuthorization", mock.Anything, &model.OIDCAuthorizationRequest{ResponseType: "code"})
+	})
+
+	t.Run("Authorize", func(t *testing.T) {
+		req := &model.OIDCAuthorizationRequest{
+			ClientID:     "deck",
+			RedirectURI:  "https://deck.memrizr.test/callback",
+			ResponseType: "code",
+			Scope:        "openid",
+		}
+		mockOIDCService.On("Authorize", mock.Anything, uid, req).Return("https://deck.memrizr.test/callback?code=code", nil)
+
+		rr := httptest.NewRecorder()
+		reqBody, _ := json.Marshal(gin.H{
+			"client_id":     "deck",
+			"redirect_uri":  "https://deck.memrizr.test/callback",
+			"response_type": "code",
+			"scope":         "openid",
+		})
+		request, _ := http.NewRequest(http.MethodPost, "/oidc/authorize", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{"redirectUrl": "https://deck.memrizr.test/callback?code=code"})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Token from authorization code", func(t *testing.T) {
+		authorization := &model.OIDCAuthorization{ClientID: "deck", UID: uid, Scope: "openid"}
+		tokens := &model.OIDCTokens{AccessToken: "accessToken", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refreshToken", IDToken: "idToken", Scope: "openid"}
+
+		mockOIDCService.On("ExchangeCode", mock.Anything, client, "code", "https://deck.memrizr.test/callback", "").Return(authorization, nil)
+		mockTokenService.On("NewOIDCTokens", mock.Anything, u, authorization, "").Return(tokens, nil)
+
+		rr := token(url.Values{
+			"grant_type":   {"authorization_code"},
+			"code":         {"code"},
+			"redirect_uri": {"https://deck.memrizr.test/callback"},
+		}, true)
+
+		respBody, _ := json.Marshal(tokens)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Token with invalid code", func(t *testing.T) {
+		mockOIDCService.On("ExchangeCode", mock.Anything, client, "expired", mock.Anything, mock.Anything).Return(nil, &model.OAuthError{Code: "invalid_grant", Description: "Invalid or expired authorization code"})
+
+		rr := token(url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {"expired"},
+			"client_id":     {"deck"},
+			"client_secret": {"decksecret"},
+		}, false)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"error":"invalid_grant"`)
+	})
+
+	t.Run("Token from refresh token", func(t *testing.T) {
+		tokenID, _ := uuid.NewRandom()
+		tokens := &model.OIDCTokens{AccessToken: "accessToken2", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refreshToken2"}
+
+		mockTokenService.On("ValidateRefreshToken", mock.Anything, "refreshToken").Return(&model.RefreshToken{ID: tokenID, UID: uid, SS: "refreshToken", ClientID: "deck", Scope: "openid email"}, nil)
+		mockTokenService.On("NewOIDCTokens", mock.Anything, u, &model.OIDCAuthorization{ClientID: "deck", UID: uid, Scope: "openid email"}, tokenID.String()).Return(tokens, nil)
+
+		rr := token(url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {"refreshToken"},
+		}, true)
+
+		respBody, _ := json.Marshal(tokens)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Token with another client's refresh token", func(t *testing.T) {
+		for ss, clientID := range map[string]string{"webRefreshToken": "", "otherRefreshToken": "other"} {
+			tokenID, _ := uuid.NewRandom()
+			mockTokenService.On("ValidateRefreshToken", mock.Anything, ss).Return(&model.RefreshToken{ID: tokenID, UID: uid, SS: ss, ClientID: clientID}, nil)
+
+			rr := token(url.Values{
+				"grant_type":    {"refresh_token"},
+				"refresh_token": {ss},
+			}, true)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), `"error":"invalid_grant"`)
+			mockTokenService.AssertNotCalled(t, "NewOIDCTokens", mock.Anything, mock.Anything, mock.Anything, tokenID.String())
+		}
+	})
+
+	t.Run("Token with invalid refresh token", func(t *testing.T) {
+		mockTokenService.On("ValidateRefreshToken", mock.Anything, "invalid").Return(nil, apperrors.NewAuthorization("Unable to verify user from refresh token"))
+
+		rr := token(url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {"invalid"},
+		}, true)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"error":"invalid_grant"`)
+	})
+
+	t.Run("Token with wrong client secret", func(t *testing.T) {
+		rr := token(url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {"code"},
+			"client_id":     {"deck"},
+			"client_secret": {"wrong"},
+		}, false)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"error":"invalid_client"`)
+	})
+
+	t.Run("Token with unsupported grant", func(t *testing.T) {
+		rr := token(url.Values{"grant_type": {"password"}}, true)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"error":"unsupported_grant_type"`)
+	})
+
+	t.Run("UserInfo", func(t *testing.T) {
+		info := &model.OIDCUserInfo{Sub: uid.String(), Email: "bob@bob.com"}
+		mockOIDCService.On("UserInfo", mock.Anything, &model.OIDCAccessToken{UID: uid, ClientID: "deck", Scope: "openid email"}).Return(info, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/oidc/userinfo", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(info)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type forgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword handler emails a password reset link. It responds the
+// same way whether or not an account exists for the email
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.ForgotPassword(ctx, req.Email); err != nil {
+		h.logFailure(c, "Failed to send password reset", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If an account exists for this email, a password reset link has been sent to it",
+	})
+}
+
+type resetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,gte=6,lte=30"`
+}
+
+// ResetPassword handler sets a new password with the token
+// from a password reset email
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req resetPasswordReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		h.logFailure(c, "Failed to reset password", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+type changePasswordReq struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,gte=6,lte=30"`
+	// NewTokens keeps the current device signed in with a new token pair
+	NewTokens bool `json:"newTokens"`
+}
+
+// ChangePassword handler sets a new password for the signed in user, which
+// signs them out of every device. If requested, new tokens are returned
+// for the current device
+func (h *Handler) ChangePassword(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req changePasswordReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.ChangePassword(ctx, authUser.UID, req.CurrentPassword, req.NewPassword)
+
+	if err != nil {
+		h.logFailure(c, "Failed to change password", err)
+
+		// set when too many wrong current passwords lock out the user
+		if retryAfter := apperrors.RetryAfterSeconds(err); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if !req.NewTokens {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "success",
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "")
+
+	if err != nil {
+		h.logFailure(c, "Failed to create tokens for user", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestForgotPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	t.Run("Invalid email", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{"email": "notanemail"})
+		request, _ := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "ForgotPassword")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockUserService.On("ForgotPassword", mock.Anything, "bob@bob.com").Return(nil)
+
+		reqBody, _ := json.Marshal(gin.H{"email": "bob@bob.com"})
+		request, _ := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "ForgotPassword", mock.Anything, "bob@bob.com")
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	t.Run("Password too short", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{"token": "aresettoken", "password": "short"})
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "ResetPassword")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockUserService.On("ResetPassword", mock.Anything, "aresettoken", "anewpassword").Return(nil)
+
+		reqBody, _ := json.Marshal(gin.H{"token": "aresettoken", "password": "anewpassword"})
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockError := apperrors.NewAuthorization("Password reset link is invalid or has expired")
+		mockUserService.On("ResetPassword", mock.Anything, "aninvalidtoken", "anewpassword").Return(mockError)
+
+		reqBody, _ := json.Marshal(gin.H{"token": "aninvalidtoken", "password": "anewpassword"})
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{"error": mockError})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{UID: uid})
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	changePassword := func(body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(body)
+		request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("New password too long", func(t *testing.T) {
+		rr := changePassword(gin.H{
+			"currentPassword": "currentpassword",
+			"newPassword":     "thisnewpasswordiswaytoolongtobeallowed",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "ChangePassword")
+	})
+
+	t.Run("Incorrect current password", func(t *testing.T) {
+		mockError := apperrors.NewForbidden("Current password is incorrect")
+		mockUserService.On("ChangePassword", mock.Anything, uid, "wrongpassword", "anewpassword").Return(nil, mockError)
+
+		rr := changePassword(gin.H{
+			"currentPassword": "wrongpassword",
+			"newPassword":     "anewpassword",
+		})
+
+		respBody, _ := json.Marshal(gin.H{"error": mockError})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Success without new tokens", func(t *testing.T) {
+		mockUserService.On("ChangePassword", mock.Anything, uid, "currentpassword", "anewpassword").Return(u, nil)
+
+		rr := changePassword(gin.H{
+			"currentPassword": "currentpassword",
+			"newPassword":     "anewpassword",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success with new tokens", func(t *testing.T) {
+		tokens := &model.TokenPair{
+			IDToken:      model.IDToken{SS: "idToken"},
+			RefreshToken: model.RefreshToken{SS: "refreshToken"},
+		}
+
+		mockUserService.On("ChangePassword", mock.Anything, uid, "currentpassword", "anotherpassword").Return(u, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, u, "").Return(tokens, nil)
+
+		rr := changePassword(gin.H{
+			"currentPassword": "currentpassword",
+			"newPassword":     "anotherpassword",
+			"newTokens":       true,
+		})
+
+		respBody, _ := json.Marshal(gin.H{"tokens": tokens})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// Sessions handler lists the devices a user is signed in on
+func (h *Handler) Sessions(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	sessions, err := h.TokenService.GetSessions(ctx, authUser.UID)
+
+	if err != nil {
+		h.logFailure(c, "Failed to get sessions for user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession handler signs a user out of a single device
+func (h *Handler) RevokeSession(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	if err := h.TokenService.RevokeSession(ctx, authUser.UID, c.Param("id")); err != nil {
+		h.logFailure(c, "Failed to revoke session for user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully revoked session",
+	})
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{
+			UID: uid,
+		})
+	})
+
+	mockTokenService := new(mocks.MockTokenService)
+
+	NewHandler(&Config{
+		R:            router,
+		TokenService: mockTokenService,
+	})
+
+	t.Run("List sessions", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		sessions := []*model.Session{
+			{
+				ID:              "a_sessionID",
+				CreatedAt:       time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+				LastRefreshedAt: time.Date(2021, 1, 3, 3, 4, 5, 0, time.UTC),
+				UserAgent:       "Mozilla/5.0",
+				IP:              "10.0.0.1",
+			},
+		}
+
+		mockTokenService.On("GetSessions", mock.Anything, uid).Return(sessions, nil)
+
+		request, _ := http.NewRequest(http.MethodGet, "/sessions", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"sessions": sessions,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Revoke session", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockTokenService.On("RevokeSession", mock.Anything, uid, "a_sessionID").Return(nil)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/sessions/a_sessionID", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertCalled(t, "RevokeSession", mock.Anything, uid, "a_sessionID")
+	})
+
+	t.Run("Revoke unknown session", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockError := apperrors.NewNotFound("session", "unknown")
+		mockTokenService.On("RevokeSession", mock.Anything, uid, "unknown").Return(mockError)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/sessions/unknown", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
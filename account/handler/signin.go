@@ -1,8 +1,8 @@
 package handler
 
 import (
-	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jacobsngoodwin/memrizr/account/model"
@@ -14,11 +14,12 @@ type signinReq struct {
 	Password string `json:"Password" binding:"required,gte=6,lte=30"`
 }
 
-// Signin handler
+// Signin handler. Users with two-factor authentication are given an MFA
+// challenge in place of tokens, which is exchanged at /signin/mfa
 func (h *Handler) Signin(c *gin.Context) {
 	var req signinReq
 
-	if ok := bindData(c, &req); !ok {
+	if ok := h.bindData(c, &req); !ok {
 		return
 	}
 	
@@ -31,17 +32,29 @@ func (h *Handler) Signin(c *gin.Context) {
 	err := h.UserService.Signin(ctx, u)
 
 	if err != nil {
-		log.Printf("Failed to sign in user: %v\n", err.Error())
+		h.logFailure(c, "Failed to sign in user", err)
+
+		// set when signins are locked out after too many failures
+		if retryAfter := apperrors.RetryAfterSeconds(err); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+
 		c.JSON(apperrors.Status(err), gin.H {
 			"error": err,
 		})
 		return
 	}
 
+	// tokens are only issued once the user's second factor is checked
+	if u.TOTPEnabled {
+		h.mfaChallenge(c, u)
+		return
+	}
+
 	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "")
 
 	if err != nil {
-		log.Printf("Failed to create tokens for user: %v\n", err.Error())
+		h.logFailure(c, "Failed to create tokens for user", err)
 
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jacobsngoodwin/memrizr/account/model"
@@ -58,7 +59,7 @@ func TestSignin(t *testing.T) {
 		password := "pwddoesnotmatch123"
 
 		mockUSArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
+			mock.Anything,
 			&model.User{Email: email, Password: password},
 		}
 
@@ -85,6 +86,45 @@ func TestSignin(t *testing.T) {
 		mockUserService.AssertCalled(t, "Signin", mockUSArgs...)
 		mockTokenService.AssertNotCalled(t, "NewTokenFromUser")
 		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Empty(t, rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Locked out", func(t *testing.T) {
+		email := "locked@bob.com"
+		password := "pwddoesnotmatch123"
+
+		mockUSArgs := mock.Arguments{
+			mock.Anything,
+			&model.User{Email: email, Password: password},
+		}
+
+		mockError := apperrors.NewTooManyRequests(1500 * time.Millisecond)
+
+		mockUserService.On("Signin", mockUSArgs...).Return(mockError)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email": email,
+			"password": password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockError,
+		})
+		assert.NoError(t, err)
+
+		mockUserService.AssertCalled(t, "Signin", mockUSArgs...)
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+		assert.Equal(t, respBody, rr.Body.Bytes())
 	})
 
 	t.Run("Successful Token Creation", func(t *testing.T) {
@@ -92,14 +132,14 @@ func TestSignin(t *testing.T) {
 		password := "pwworksgreat123"
 
 		mockUSArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
+			mock.Anything,
 			&model.User{Email: email, Password: password},
 		}
 
 		mockUserService.On("Signin", mockUSArgs...).Return(nil)
 
 		mockTSArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
+			mock.Anything,
 			&model.User{Email: email, Password: password},
 			"",
 		}
@@ -142,14 +182,14 @@ func TestSignin(t *testing.T) {
 		password := "cannotproducetoken"
 
 		mockUSArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
+			mock.Anything,
 			&model.User{Email: email, Password: password},
 		}
 
 		mockUserService.On("Signin", mockUSArgs...).Return(nil)
 
 		mockTSArgs := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"),
+			mock.Anything,
 			&model.User{Email: email, Password: password},
 			"",
 		}
@@ -182,4 +222,46 @@ func TestSignin(t *testing.T) {
 		mockUserService.AssertCalled(t, "Signin", mockUSArgs...)
 		mockTokenService.AssertCalled(t, "NewPairFromUser", mockTSArgs...)
 	})
+
+	t.Run("Two-factor authentication required", func(t *testing.T) {
+		email := "twofactor@bob.com"
+		password := "pwworksgreat123"
+
+		mockUSArgs := mock.Arguments{
+			mock.Anything,
+			&model.User{Email: email, Password: password},
+		}
+
+		// the signed in user has TOTP enabled
+		mockUserService.On("Signin", mockUSArgs...).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.User).TOTPEnabled = true
+		})
+
+		mockTokenService.On("NewMFAChallenge", mock.Anything, &model.User{Email: email, Password: password, TOTPEnabled: true}).Return("mfaChallenge", nil)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email": email,
+			"password": password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"mfaRequired": true,
+			"mfaToken": "mfaChallenge",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, &model.User{Email: email, Password: password, TOTPEnabled: true}, "")
+	})
 }
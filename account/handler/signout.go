@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -15,7 +14,7 @@ func (h *Handler) Signout(c *gin.Context) {
 
 	ctx := c.Request.Context()
 	if err := h.TokenService.Signout(ctx, user.(*model.User).UID); err != nil {
-		log.Printf("Failed to sign out user: %v\n", err.Error())
+		h.logFailure(c, "Failed to sign out user", err)
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
 		})
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -19,7 +18,7 @@ func (h *Handler) Signup(c *gin.Context) {
 
 	var req signupReq
 
-	if ok := bindData(c, &req); !ok {
+	if ok := h.bindData(c, &req); !ok {
 		return
 	}
 
@@ -32,7 +31,7 @@ func (h *Handler) Signup(c *gin.Context) {
 	err := h.UserService.Signup(ctx, u)
 
 	if err != nil {
-		log.Printf("Failed to sign up user: %v\n", err.Error())
+		h.logFailure(c, "Failed to sign up user", err)
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
 		})
@@ -42,7 +41,7 @@ func (h *Handler) Signup(c *gin.Context) {
 	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "")
 
 	if err != nil {
-		log.Printf("Failed to create tokens for user: %v\n", err.Error())
+		h.logFailure(c, "Failed to create tokens for user", err)
 
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
@@ -22,7 +22,7 @@ func TestSignup(t *testing.T) {
 	t.Run("Email and Password Required", func(t *testing.T) {
 
 		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).Return(nil)
+		mockUserService.On("Signup", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
 
 		rr := httptest.NewRecorder()
 
@@ -53,7 +53,7 @@ func TestSignup(t *testing.T) {
 	t.Run("Invalid email", func(t *testing.T) {
 
 		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).Return(nil)
+		mockUserService.On("Signup", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
 
 		rr := httptest.NewRecorder()
 
@@ -85,7 +85,7 @@ func TestSignup(t *testing.T) {
 	t.Run("Password too short", func(t *testing.T) {
 
 		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).Return(nil)
+		mockUserService.On("Signup", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
 
 		rr := httptest.NewRecorder()
 
@@ -121,7 +121,7 @@ func TestSignup(t *testing.T) {
 		}
 
 		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).Return(apperrors.NewConflict("User Already Exists", u.Email))
+		mockUserService.On("Signup", mock.Anything, mock.AnythingOfType("*model.User")).Return(apperrors.NewConflict("User Already Exists", u.Email))
 
 		rr := httptest.NewRecorder()
 
@@ -164,8 +164,8 @@ func TestSignup(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 		mockTockenService := new(mocks.MockTokenService)
 
-		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), u).Return(nil)
-		mockTockenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), u, "").Return(mockTokenResp, nil)
+		mockUserService.On("Signup", mock.Anything, u).Return(nil)
+		mockTockenService.On("NewPairFromUser", mock.Anything, u, "").Return(mockTokenResp, nil)
 
 		rr := httptest.NewRecorder()
 
@@ -212,8 +212,8 @@ func TestSignup(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 		mockTockenService := new(mocks.MockTokenService)
 
-		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), u).Return(nil)
-		mockTockenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), u, "").Return(nil, mockErrorResponse)
+		mockUserService.On("Signup", mock.Anything, u).Return(nil)
+		mockTockenService.On("NewPairFromUser", mock.Anything, u, "").Return(nil, mockErrorResponse)
 
 		rr := httptest.NewRecorder()
 
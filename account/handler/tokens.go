@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -17,13 +16,13 @@ func (h *Handler) Tokens(c *gin.Context) {
 	
 	var req tokensReq
 
-	if ok := bindData(c, &req); !ok {
+	if ok := h.bindData(c, &req); !ok {
 		return
 	}
 
 	ctx := c.Request.Context()
 
-	refreshToken, err := h.TokenService.ValidateRefreshToken(req.RefreshToken)
+	refreshToken, err := h.TokenService.ValidateRefreshToken(ctx, req.RefreshToken)
 
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{
@@ -32,18 +31,28 @@ func (h *Handler) Tokens(c *gin.Context) {
 		return
 	}
 
+	// OpenID Connect clients' refresh tokens are only for the token endpoint
+	if refreshToken.ClientID != "" {
+		err := apperrors.NewAuthorization("Unable to verify user from refresh token")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
 	u, err := h.UserService.Get(ctx, refreshToken.UID)
 
 	if err != nil {
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
 		})
+		return
 	}
 
 	tokens, err := h.TokenService.NewPairFromUser(ctx, u, refreshToken.ID.String())
 
 	if err != nil {
-		log.Printf("Failed to create tokens for user: %+v. Error %v\n", u, err.Error())
+		h.logFailure(c, "Failed to create tokens for user", err, "uid", u.UID)
 
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"mime/multipart"
+	"net/http"
+)
+
+var validImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+// isAllowedImageType determines if image is among types defined
+// in map of allowed images
+func isAllowedImageType(mimeType string) bool {
+	_, exists := validImageTypes[mimeType]
+
+	return exists
+}
+
+// detectImageType sniffs the mime-type from the first 512 bytes
+// of the uploaded file
+func detectImageType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := f.Read(buf)
+
+	if err != nil && n == 0 {
+		return "", err
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
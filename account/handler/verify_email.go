@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type verifyEmailReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail handler marks the user's email verified with the token
+// from a verification email. It doesn't require the user to be signed
+// in, as the link may well be opened on another device
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.VerifyEmail(ctx, req.Token)
+
+	if err != nil {
+		h.logFailure(c, "Failed to verify email", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": u,
+	})
+}
+
+// SendVerificationEmail handler sends the signed in user
+// another verification email
+func (h *Handler) SendVerificationEmail(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	if err := h.UserService.SendVerificationEmail(ctx, authUser.UID); err != nil {
+		h.logFailure(c, "Failed to send verification email", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodPost, "/verify-email", bytes.NewBufferString("{}"))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "VerifyEmail")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		uid, _ := uuid.NewRandom()
+		u := &model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true}
+
+		mockUserService.On("VerifyEmail", mock.Anything, "avalidtoken").Return(u, nil)
+
+		reqBody, _ := json.Marshal(gin.H{"token": "avalidtoken"})
+		request, _ := http.NewRequest(http.MethodPost, "/verify-email", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{"user": u})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockError := apperrors.NewAuthorization("Email verification link is invalid or has expired")
+		mockUserService.On("VerifyEmail", mock.Anything, "aninvalidtoken").Return(nil, mockError)
+
+		reqBody, _ := json.Marshal(gin.H{"token": "aninvalidtoken"})
+		request, _ := http.NewRequest(http.MethodPost, "/verify-email", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{"error": mockError})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
+
+func TestSendVerificationEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{UID: uid})
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockUserService.On("SendVerificationEmail", mock.Anything, uid).Return(nil)
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	rr := httptest.NewRecorder()
+
+	request, _ := http.NewRequest(http.MethodPost, "/verify-email/send", nil)
+	router.ServeHTTP(rr, request)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockUserService.AssertExpectations(t)
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{UID: uid}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockUserService.On("ClearProfileImage", mock.Anything, uid).Return(nil)
+
+	NewHandler(&Config{
+		R:                    router,
+		UserService:          mockUserService,
+		RequireVerifiedEmail: true,
+	})
+
+	t.Run("Unverified", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockUserService.AssertNotCalled(t, "ClearProfileImage", mock.Anything, uid)
+	})
+
+	t.Run("Verified", func(t *testing.T) {
+		ctxUser.EmailVerified = true
+
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "ClearProfileImage", mock.Anything, uid)
+	})
+}
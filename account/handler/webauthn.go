@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// BeginPasskeyRegistration handler returns the options to pass to
+// navigator.credentials.create to register a passkey
+func (h *Handler) BeginPasskeyRegistration(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	options, err := h.WebAuthnService.BeginRegistration(ctx, authUser.UID)
+
+	if err != nil {
+		h.logFailure(c, "Failed to begin passkey registration", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
+
+type finishPasskeyRegistrationReq struct {
+	Name       string                     `json:"name" binding:"omitempty,max=64"`
+	Credential *model.WebAuthnAttestation `json:"credential" binding:"required"`
+}
+
+// FinishPasskeyRegistration handler stores the passkey created by
+// navigator.credentials.create
+func (h *Handler) FinishPasskeyRegistration(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req finishPasskeyRegistrationReq
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	cred, err := h.WebAuthnService.FinishRegistration(ctx, authUser.UID, req.Name, req.Credential)
+
+	if err != nil {
+		h.logFailure(c, "Failed to finish passkey registration", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"passkey": cred,
+	})
+}
+
+// Passkeys handler lists the user's passkeys
+func (h *Handler) Passkeys(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	creds, err := h.WebAuthnService.GetCredentials(ctx, authUser.UID)
+
+	if err != nil {
+		h.logFailure(c, "Failed to get passkeys for user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"passkeys": creds,
+	})
+}
+
+// DeletePasskey handler removes one of the user's passkeys
+func (h *Handler) DeletePasskey(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(c.Param("id"))
+
+	if err != nil {
+		err := apperrors.NewBadRequest("Invalid passkey ID")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.WebAuthnService.DeleteCredential(ctx, authUser.UID, credentialID); err != nil {
+		h.logFailure(c, "Failed to delete passkey for user", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully deleted passkey",
+	})
+}
+
+// BeginPasskeySignin handler returns the options to pass to
+// navigator.credentials.get to sign in with a passkey
+func (h *Handler) BeginPasskeySignin(c *gin.Context) {
+	ctx := c.Request.Context()
+	options, err := h.WebAuthnService.BeginLogin(ctx)
+
+	if err != nil {
+		h.logFailure(c, "Failed to begin passkey signin", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
+
+// FinishPasskeySignin handler exchanges the assertion from
+// navigator.credentials.get for tokens. Passkeys verify the user
+// themselves, so no second factor is asked for
+func (h *Handler) FinishPasskeySignin(c *gin.Context) {
+	var req model.WebAuthnAssertion
+
+	if ok := h.bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.WebAuthnService.FinishLogin(ctx, &req)
+
+	if err != nil {
+		h.logFailure(c, "Failed to sign in with passkey", err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "")
+
+	if err != nil {
+		h.logFailure(c, "Failed to create tokens for user", err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
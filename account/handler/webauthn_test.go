@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPasskeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{UID: uid})
+	})
+
+	mockWebAuthnService := new(mocks.MockWebAuthnService)
+
+	NewHandler(&Config{
+		R:               router,
+		WebAuthnService: mockWebAuthnService,
+	})
+
+	request := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(body)
+		request, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Begin registration", func(t *testing.T) {
+		options := &model.WebAuthnCreationOptions{
+			Challenge: []byte("achallenge"),
+			RP:        model.WebAuthnRelyingParty{ID: "memrizr.test", Name: "Memrizr"},
+			User:      model.WebAuthnUser{ID: uid[:], Name: "bob@bob.com", DisplayName: "Bob"},
+		}
+		mockWebAuthnService.On("BeginRegistration", mock.Anything, uid).Return(options, nil)
+
+		rr := request(http.MethodPost, "/passkeys/register/begin", nil)
+
+		var resp struct {
+			PublicKey map[string]interface{} `json:"publicKey"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		// binary fields are base64url encoded, as the WebAuthn JSON API expects
+		assert.Equal(t, "YWNoYWxsZW5nZQ", resp.PublicKey["challenge"])
+	})
+
+	t.Run("Finish registration without credential", func(t *testing.T) {
+		rr := request(http.MethodPost, "/passkeys/register/finish", gin.H{"name": "My laptop"})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockWebAuthnService.AssertNotCalled(t, "FinishRegistration", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Finish registration", func(t *testing.T) {
+		cred := &model.WebAuthnCredential{ID: []byte("acredential"), UID: uid, Name: "My laptop"}
+
+		mockWebAuthnService.On("FinishRegistration", mock.Anything, uid, "My laptop", mock.MatchedBy(func(a *model.WebAuthnAttestation) bool {
+			return string(a.ID) == "acredential" && string(a.Response.ClientDataJSON) == "{}"
+		})).Return(cred, nil)
+
+		rr := request(http.MethodPost, "/passkeys/register/finish", gin.H{
+			"name": "My laptop",
+			"credential": gin.H{
+				"rawId": "YWNyZWRlbnRpYWw",
+				"type":  "public-key",
+				"response": gin.H{
+					"clientDataJSON":    "e30",
+					"attestationObject": "oA",
+				},
+			},
+		})
+
+		respBody, _ := json.Marshal(gin.H{"passkey": cred})
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Delete with malformed ID", func(t *testing.T) {
+		rr := request(http.MethodDelete, "/passkeys/not+base64url", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockWebAuthnService.AssertNotCalled(t, "DeleteCredential", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		mockWebAuthnService.On("DeleteCredential", mock.Anything, uid, []byte("acredential")).Return(nil)
+
+		rr := request(http.MethodDelete, "/passkeys/YWNyZWRlbnRpYWw", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestPasskeySignin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	mockWebAuthnService := new(mocks.MockWebAuthnService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:               router,
+		TokenService:    mockTokenService,
+		WebAuthnService: mockWebAuthnService,
+	})
+
+	finish := func(clientDataJSON string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"rawId": "YWNyZWRlbnRpYWw",
+			"type":  "public-key",
+			"response": gin.H{
+				"clientDataJSON":    clientDataJSON,
+				"authenticatorData": "YXV0aGRhdGE",
+				"signature":         "c2ln",
+			},
+		})
+		request, _ := http.NewRequest(http.MethodPost, "/signin/passkey/finish", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Invalid assertion", func(t *testing.T) {
+		mockError := apperrors.NewAuthorization("Unable to sign in with passkey")
+		mockWebAuthnService.On("FinishLogin", mock.Anything, mock.MatchedBy(func(a *model.WebAuthnAssertion) bool {
+			return string(a.Response.ClientDataJSON) == "bad"
+		})).Return(nil, mockError)
+
+		rr := finish("YmFk")
+
+		respBody, _ := json.Marshal(gin.H{"error": mockError})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SS: "idToken"},
+			RefreshToken: model.RefreshToken{SS: "refreshToken"},
+		}
+
+		mockWebAuthnService.On("FinishLogin", mock.Anything, mock.MatchedBy(func(a *model.WebAuthnAssertion) bool {
+			return string(a.Response.ClientDataJSON) == "{}"
+		})).Return(u, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, u, "").Return(mockTokenPair, nil)
+
+		rr := finish("e30")
+
+		respBody, _ := json.Marshal(gin.H{"tokens": mockTokenPair})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
@@ -0,0 +1,157 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"golang.org/x/oauth2"
+)
+
+// GitHubConfig configures GitHub as an identity provider. The URLs
+// default to github.com's, and only need setting for GitHub Enterprise
+// or for tests
+type GitHubConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	APIURL       string
+	HTTPClient   *http.Client
+}
+
+type gitHubProvider struct {
+	oauth      oauth2.Config
+	apiURL     string
+	httpClient *http.Client
+}
+
+// NewGitHubProvider initializes GitHub as an identity provider. GitHub
+// doesn't support OpenID Connect, so users are identified by their
+// GitHub user ID and primary verified email, read from its API
+func NewGitHubProvider(c *GitHubConfig) model.IdentityProvider {
+	authURL := c.AuthURL
+	if authURL == "" {
+		authURL = "https://github.com/login/oauth/authorize"
+	}
+
+	tokenURL := c.TokenURL
+	if tokenURL == "" {
+		tokenURL = "https://github.com/login/oauth/access_token"
+	}
+
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &gitHubProvider{
+		oauth: oauth2.Config{
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			RedirectURL:  c.RedirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   authURL,
+				TokenURL:  tokenURL,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+func (p *gitHubProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	return p.oauth.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	), nil
+}
+
+type gitHubUser struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+type gitHubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// Exchange redeems an authorization code, then looks up the user it
+// was issued for
+func (p *gitHubProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*model.ExternalIdentity, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
+	token, err := p.oauth.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not exchange github authorization code: %w", err)
+	}
+
+	client := p.oauth.Client(ctx, token)
+
+	u := &gitHubUser{}
+	if err := p.getJSON(ctx, client, "/user", u); err != nil {
+		return nil, err
+	}
+
+	emails := []gitHubEmail{}
+	if err := p.getJSON(ctx, client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &model.ExternalIdentity{
+		Provider: "github",
+		Subject:  strconv.FormatInt(u.ID, 10),
+		Name:     u.Name,
+	}
+
+	if identity.Name == "" {
+		identity.Name = u.Login
+	}
+
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *gitHubProvider) getJSON(ctx context.Context, client *http.Client, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not fetch github %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not fetch github %s: unexpected status: %s", path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,98 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitHubProvider(t *testing.T) {
+	emails := []gitHubEmail{}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		if r.PostForm.Get("code") != "code" || r.PostForm.Get("code_verifier") != "verifier" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(jsonObject{"error": "bad_verification_code"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jsonObject{
+			"access_token": "accessToken",
+			"token_type":   "bearer",
+		})
+	})
+
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer accessToken" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			h(w, r)
+		}
+	}
+
+	mux.HandleFunc("/api/user", authorized(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jsonObject{"id": 583231, "login": "octocat", "name": ""})
+	}))
+
+	mux.HandleFunc("/api/user/emails", authorized(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(emails)
+	}))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := NewGitHubProvider(&GitHubConfig{
+		ClientID:     "clientID",
+		ClientSecret: "clientSecret",
+		RedirectURL:  "https://memrizr.test/oauth/callback",
+		AuthURL:      server.URL + "/login/oauth/authorize",
+		TokenURL:     server.URL + "/login/oauth/access_token",
+		APIURL:       server.URL + "/api",
+	})
+
+	t.Run("Primary verified email", func(t *testing.T) {
+		emails = []gitHubEmail{
+			{Email: "other@bob.com", Verified: true},
+			{Email: "octocat@bob.com", Primary: true, Verified: true},
+		}
+
+		identity, err := p.Exchange(context.TODO(), "code", "verifier", "")
+		assert.NoError(t, err)
+		assert.Equal(t, &model.ExternalIdentity{
+			Provider:      "github",
+			Subject:       "583231",
+			Email:         "octocat@bob.com",
+			EmailVerified: true,
+			Name:          "octocat",
+		}, identity)
+	})
+
+	t.Run("Primary email unverified", func(t *testing.T) {
+		emails = []gitHubEmail{
+			{Email: "octocat@bob.com", Primary: true},
+		}
+
+		identity, err := p.Exchange(context.TODO(), "code", "verifier", "")
+		assert.NoError(t, err)
+		assert.False(t, identity.EmailVerified)
+	})
+
+	t.Run("Wrong code verifier", func(t *testing.T) {
+		_, err := p.Exchange(context.TODO(), "code", "otherVerifier", "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,258 @@
+package identity
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig configures an OpenID Connect provider. Its endpoints are
+// read from the issuer's discovery document
+type OIDCConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// defaults to openid, email and profile
+	Scopes     []string
+	HTTPClient *http.Client
+}
+
+type oidcProvider struct {
+	name       string
+	issuer     string
+	oauth      oauth2.Config
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+	keysAt    time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// keys are fetched again for an unknown kid, as the provider may have
+// rotated them, but no more often than this
+const jwksRefreshInterval = time.Minute
+
+// NewOIDCProvider initializes an OpenID Connect identity provider. Its
+// discovery document is fetched when first needed, so that a provider
+// being unavailable doesn't stop the service from starting
+func NewOIDCProvider(c *OIDCConfig) model.IdentityProvider {
+	scopes := c.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &oidcProvider{
+		name:   c.Name,
+		issuer: strings.TrimSuffix(c.Issuer, "/"),
+		oauth: oauth2.Config{
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			RedirectURL:  c.RedirectURL,
+			Scopes:       scopes,
+		},
+		httpClient: httpClient,
+	}
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// config returns the OAuth config with the provider's endpoints
+func (p *oidcProvider) config(ctx context.Context) (*oauth2.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery == nil {
+		d := &oidcDiscovery{}
+
+		if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", d); err != nil {
+			return nil, fmt.Errorf("could not fetch %s discovery document: %w", p.name, err)
+		}
+
+		if strings.TrimSuffix(d.Issuer, "/") != p.issuer {
+			return nil, fmt.Errorf("%s discovery document is for issuer: %s", p.name, d.Issuer)
+		}
+
+		p.discovery = d
+	}
+
+	cfg := p.oauth
+	cfg.Endpoint = oauth2.Endpoint{
+		AuthURL:   p.discovery.AuthorizationEndpoint,
+		TokenURL:  p.discovery.TokenEndpoint,
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+
+	return &cfg, nil
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	cfg, err := p.config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	), nil
+}
+
+type oidcClaims struct {
+	Email string `json:"email"`
+	// some providers send this as a string
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+	Nonce         string      `json:"nonce"`
+	jwt.StandardClaims
+}
+
+// Exchange redeems an authorization code and verifies the ID token
+// returned with the access token
+func (p *oidcProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*model.ExternalIdentity, error) {
+	cfg, err := p.config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
+	token, err := cfg.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not exchange %s authorization code: %w", p.name, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%s token response has no id_token", p.name)
+	}
+
+	claims := &oidcClaims{}
+
+	if _, err := jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	}); err != nil {
+		return nil, fmt.Errorf("invalid %s id_token: %w", p.name, err)
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("%s id_token is from issuer: %s", p.name, claims.Issuer)
+	}
+
+	if !claims.VerifyAudience(p.oauth.ClientID, true) {
+		return nil, fmt.Errorf("%s id_token is for audience: %s", p.name, claims.Audience)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%s id_token nonce does not match", p.name)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%s id_token has no subject", p.name)
+	}
+
+	return &model.ExternalIdentity{
+		Provider:      p.name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+// key returns the provider's signing key with the ID kid
+func (p *oidcProvider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(p.keysAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown %s signing key: %s", p.name, kid)
+	}
+
+	jwks := &model.JWKS{}
+
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, jwks); err != nil {
+		return nil, fmt.Errorf("could not fetch %s signing keys: %w", p.name, err)
+	}
+
+	p.keys = make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	p.keysAt = time.Now()
+
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+
+		if errN != nil || errE != nil {
+			continue
+		}
+
+		p.keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	key, ok := p.keys[kid]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown %s signing key: %s", p.name, kid)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,212 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonObject map[string]interface{}
+
+// fakeOIDCProvider is a local OpenID Connect provider, which issues an
+// ID token with its claims for the code it expects
+type fakeOIDCProvider struct {
+	*httptest.Server
+	key           *rsa.PrivateKey
+	code          string
+	codeChallenge string
+	claims        jwt.MapClaims
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	p := &fakeOIDCProvider{key: key}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jsonObject{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(model.JWKS{Keys: []model.JWK{{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: "test",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+
+		if r.PostForm.Get("code") != p.code || base64.RawURLEncoding.EncodeToString(sum[:]) != p.codeChallenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(jsonObject{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, p.claims)
+		token.Header["kid"] = "test"
+
+		idToken, err := token.SignedString(key)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jsonObject{
+			"access_token": "accessToken",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func TestOIDCProvider(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+
+	p := NewOIDCProvider(&OIDCConfig{
+		Name:         "google",
+		Issuer:       fake.URL,
+		ClientID:     "clientID",
+		ClientSecret: "clientSecret",
+		RedirectURL:  "https://memrizr.test/oauth/callback",
+	})
+
+	verifier := "verifier"
+	sum := sha256.Sum256([]byte(verifier))
+	fake.codeChallenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	fake.code = "code"
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            fake.URL,
+			"aud":            "clientID",
+			"sub":            "12345",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          "nonce",
+			"email":          "bob@bob.com",
+			"email_verified": true,
+			"name":           "Bob",
+		}
+	}
+
+	t.Run("AuthCodeURL", func(t *testing.T) {
+		authURL, err := p.AuthCodeURL(context.TODO(), "state", "nonce", fake.codeChallenge)
+		assert.NoError(t, err)
+
+		u, err := url.Parse(authURL)
+		assert.NoError(t, err)
+
+		q := u.Query()
+		assert.Equal(t, fake.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(t, "clientID", q.Get("client_id"))
+		assert.Equal(t, "code", q.Get("response_type"))
+		assert.Equal(t, "state", q.Get("state"))
+		assert.Equal(t, "nonce", q.Get("nonce"))
+		assert.Equal(t, fake.codeChallenge, q.Get("code_challenge"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.Equal(t, "openid email profile", q.Get("scope"))
+	})
+
+	t.Run("Exchange", func(t *testing.T) {
+		fake.claims = validClaims()
+
+		identity, err := p.Exchange(context.TODO(), "code", verifier, "nonce")
+		assert.NoError(t, err)
+		assert.Equal(t, &model.ExternalIdentity{
+			Provider:      "google",
+			Subject:       "12345",
+			Email:         "bob@bob.com",
+			EmailVerified: true,
+			Name:          "Bob",
+		}, identity)
+	})
+
+	t.Run("email_verified as a string", func(t *testing.T) {
+		fake.claims = validClaims()
+		fake.claims["email_verified"] = "true"
+
+		identity, err := p.Exchange(context.TODO(), "code", verifier, "nonce")
+		assert.NoError(t, err)
+		assert.True(t, identity.EmailVerified)
+	})
+
+	t.Run("Unverified email", func(t *testing.T) {
+		fake.claims = validClaims()
+		fake.claims["email_verified"] = false
+
+		identity, err := p.Exchange(context.TODO(), "code", verifier, "nonce")
+		assert.NoError(t, err)
+		assert.False(t, identity.EmailVerified)
+	})
+
+	t.Run("Wrong code verifier", func(t *testing.T) {
+		fake.claims = validClaims()
+
+		_, err := p.Exchange(context.TODO(), "code", "otherVerifier", "nonce")
+		assert.Error(t, err)
+	})
+
+	t.Run("Wrong nonce", func(t *testing.T) {
+		fake.claims = validClaims()
+
+		_, err := p.Exchange(context.TODO(), "code", verifier, "otherNonce")
+		assert.Error(t, err)
+	})
+
+	t.Run("Wrong audience", func(t *testing.T) {
+		fake.claims = validClaims()
+		fake.claims["aud"] = "otherClient"
+
+		_, err := p.Exchange(context.TODO(), "code", verifier, "nonce")
+		assert.Error(t, err)
+	})
+
+	t.Run("Wrong issuer", func(t *testing.T) {
+		fake.claims = validClaims()
+		fake.claims["iss"] = "https://evil.test"
+
+		_, err := p.Exchange(context.TODO(), "code", verifier, "nonce")
+		assert.Error(t, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		fake.claims = validClaims()
+		fake.claims["exp"] = time.Now().Add(-time.Minute).Unix()
+
+		_, err := p.Exchange(context.TODO(), "code", verifier, "nonce")
+		assert.Error(t, err)
+	})
+}
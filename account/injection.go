@@ -1,38 +1,258 @@
 package main
 
 import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/jacobsngoodwin/memrizr/account/events"
 	"github.com/jacobsngoodwin/memrizr/account/handler"
+	"github.com/jacobsngoodwin/memrizr/account/handler/middleware"
+	"github.com/jacobsngoodwin/memrizr/account/identity"
+	"github.com/jacobsngoodwin/memrizr/account/mailer"
+	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/jacobsngoodwin/memrizr/account/repository"
 	"github.com/jacobsngoodwin/memrizr/account/service"
 )
 
-func inject(d *dataSources) (*gin.Engine, error) {
-	log.Println("Injecting data sources")
+// supported values of IMAGE_STORE
+const (
+	imageStoreGC = "gc"
+	imageStoreFS = "fs"
+	imageStoreS3 = "s3"
+)
+
+// supported values of MAILER
+const (
+	mailerLog  = "log"
+	mailerFile = "file"
+)
+
+// supported values of EVENT_BROKER. Other brokers, such as NATS, can be
+// added with events.NewMessageBroker
+const (
+	eventBrokerLog = "log"
+)
+
+// path, relative to ACCOUNT_API_URL, from which filesystem images are served
+const fsImagePath = "/images"
+
+// inject wires up the app. Background workers run until ctx is cancelled,
+// and are added to workers so the caller can wait for them to stop
+func inject(ctx context.Context, workers *sync.WaitGroup, d *dataSources, logger model.Logger) (*gin.Engine, error) {
+	logger.Info(ctx, "Injecting data sources")
 
 	/*
 	* repotory layer
 	*/
-	userRepository := repository.NewUserRepository(d.DB)
-	tokenRepository := repository.NewTokenRepository(d.RedisClient)
+	userRepository := repository.NewUserRepository(d.DB, logger)
+	tokenRepository := repository.NewTokenRepository(d.RedisClient, logger)
+	signinAttemptRepository := repository.NewSigninAttemptRepository(d.RedisClient, logger)
+	passwordResetRepository := repository.NewPasswordResetRepository(d.RedisClient, logger)
+	webAuthnCredentialRepository := repository.NewWebAuthnCredentialRepository(d.DB, logger)
+	webAuthnChallengeRepository := repository.NewWebAuthnChallengeRepository(d.RedisClient, logger)
+	userIdentityRepository := repository.NewUserIdentityRepository(d.DB, logger)
+	oauthStateRepository := repository.NewOAuthStateRepository(d.RedisClient, logger)
+	outboxRepository := repository.NewOutboxRepository(d.DB, logger)
+	auditRepository := repository.NewAuditRepository(d.DB, logger)
+	transactor := repository.NewTransactor(d.DB, logger)
+
+	baseURL := os.Getenv("ACCOUNT_API_URL")
+
+	// images are kept in Google Cloud Storage unless configured otherwise
+	var imageRepository model.ImageRepository
+	var imageDir string
+
+	switch imageStore := os.Getenv("IMAGE_STORE"); imageStore {
+	case "", imageStoreGC:
+		bucketName := os.Getenv("GC_IMAGE_BUCKET")
+		imageRepository = repository.NewImageRepository(d.StorageClient, bucketName, logger)
+	case imageStoreFS:
+		imageDir = os.Getenv("FS_IMAGE_DIR")
+		if imageDir == "" {
+			return nil, fmt.Errorf("FS_IMAGE_DIR is required when IMAGE_STORE is %s", imageStoreFS)
+		}
+		imageRepository = repository.NewFSImageRepository(imageDir, baseURL+fsImagePath, logger)
+	case imageStoreS3:
+		imageRepository = repository.NewS3ImageRepository(&http.Client{Timeout: 30 * time.Second}, &repository.S3Config{
+			Endpoint: os.Getenv("S3_ENDPOINT"),
+			Region: os.Getenv("S3_REGION"),
+			Bucket: os.Getenv("S3_IMAGE_BUCKET"),
+			AccessKeyID: os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+			Logger: logger,
+		})
+	default:
+		return nil, fmt.Errorf("unknown IMAGE_STORE: %s", imageStore)
+	}
+
+	emailLockout, err := lockoutPolicyFromEnv("SIGNIN_EMAIL", defaultEmailLockout)
+	if err != nil {
+		return nil, err
+	}
 
-	bucketName := os.Getenv("GC_IMAGE_BUCKET")
-	imageRepository := repository.NewImageRepository(d.StorageClient, bucketName)
+	ipLockout, err := lockoutPolicyFromEnv("SIGNIN_IP", defaultIPLockout)
+	if err != nil {
+		return nil, err
+	}
+
+	// emails are only written locally for now, as we have no mail provider
+	var m model.Mailer
+	mailFrom := os.Getenv("MAIL_FROM")
+
+	switch mailerType := os.Getenv("MAILER"); mailerType {
+	case "", mailerLog:
+		m = mailer.NewLogMailer(mailFrom, logger)
+	case mailerFile:
+		mailDir := os.Getenv("MAIL_DIR")
+		if mailDir == "" {
+			return nil, fmt.Errorf("MAIL_DIR is required when MAILER is %s", mailerFile)
+		}
+		m = mailer.NewFileMailer(mailDir, mailFrom, logger)
+	default:
+		return nil, fmt.Errorf("unknown MAILER: %s", mailerType)
+	}
+
+	emailTokenSecret := os.Getenv("EMAIL_TOKEN_SECRET")
+	if emailTokenSecret == "" {
+		return nil, fmt.Errorf("EMAIL_TOKEN_SECRET is required")
+	}
+
+	verifyEmailURL := os.Getenv("VERIFY_EMAIL_URL")
+	if verifyEmailURL == "" {
+		return nil, fmt.Errorf("VERIFY_EMAIL_URL is required")
+	}
+
+	// optional, defaults to a day
+	var emailTokenExp int64 = 24 * 60 * 60
+	if v := os.Getenv("EMAIL_TOKEN_EXP"); v != "" {
+		emailTokenExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse EMAIL_TOKEN_EXP as int: %w", err)
+		}
+	}
+
+	resetPasswordURL := os.Getenv("RESET_PASSWORD_URL")
+	if resetPasswordURL == "" {
+		return nil, fmt.Errorf("RESET_PASSWORD_URL is required")
+	}
+
+	// optional, defaults to an hour
+	var passwordResetExp int64 = 60 * 60
+	if v := os.Getenv("PASSWORD_RESET_EXP"); v != "" {
+		passwordResetExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse PASSWORD_RESET_EXP as int: %w", err)
+		}
+	}
+
+	// optional, names the service in authenticator apps
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "Memrizr"
+	}
+
+	passwordParams, err := passwordParamsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// services add events to the outbox, in the same transactions as the
+	// changes they describe, and the relay sends them on to the broker
+	eventBroker := events.NewOutboxBroker(outboxRepository)
+
+	var relayBroker model.EventBroker
+
+	switch brokerType := os.Getenv("EVENT_BROKER"); brokerType {
+	case "", eventBrokerLog:
+		relayBroker = events.NewLogBroker(logger)
+	default:
+		return nil, fmt.Errorf("unknown EVENT_BROKER: %s", brokerType)
+	}
+
+	eventRelayInterval := time.Second
+	if v := os.Getenv("EVENT_RELAY_INTERVAL"); v != "" {
+		eventRelayInterval, err = time.ParseDuration(v)
+		if err != nil || eventRelayInterval <= 0 {
+			return nil, fmt.Errorf("could not parse EVENT_RELAY_INTERVAL as positive duration: %v", v)
+		}
+	}
+
+	relay := events.NewRelay(&events.RelayConfig{
+		OutboxRepository: outboxRepository,
+		Broker: relayBroker,
+		BatchSize: 100,
+		Interval: eventRelayInterval,
+		Logger: logger,
+	})
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		relay.Run(ctx)
+	}()
+
+	// optional, accounts are deleted straight away without a grace period
+	var deletionGracePeriod time.Duration
+	if v := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); v != "" {
+		deletionGracePeriod, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse ACCOUNT_DELETION_GRACE_PERIOD as duration: %w", err)
+		}
+	}
+
+	accountPurgeInterval := time.Hour
+	if v := os.Getenv("ACCOUNT_PURGE_INTERVAL"); v != "" {
+		accountPurgeInterval, err = time.ParseDuration(v)
+		if err != nil || accountPurgeInterval <= 0 {
+			return nil, fmt.Errorf("could not parse ACCOUNT_PURGE_INTERVAL as positive duration: %v", v)
+		}
+	}
 
 	//service layer
 	userService := service.NewUserService(&service.USConfig{
 		UserRepository: userRepository,
 		ImageRepository: imageRepository,
+		SigninAttemptRepository: signinAttemptRepository,
+		EmailLockout: emailLockout,
+		IPLockout: ipLockout,
+		Mailer: m,
+		EmailTokenSecret: emailTokenSecret,
+		EmailTokenExpirationSecs: emailTokenExp,
+		VerifyEmailURL: verifyEmailURL,
+		TokenRepository: tokenRepository,
+		PasswordResetRepository: passwordResetRepository,
+		PasswordResetExpirationSecs: passwordResetExp,
+		ResetPasswordURL: resetPasswordURL,
+		PasswordParams: passwordParams,
+		TOTPIssuer: totpIssuer,
+		EventBroker: eventBroker,
+		Transactor: transactor,
+		DeletionGracePeriod: deletionGracePeriod,
+		AuditRepository: auditRepository,
+		Logger: logger,
 	})
 
+	if deletionGracePeriod > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			purgeDeletedAccounts(ctx, userService, accountPurgeInterval, logger)
+		}()
+	}
+
 	privKeyFile := os.Getenv("PRIV_KEY_FILE")
 	priv, err := ioutil.ReadFile(privKeyFile)
 
@@ -59,6 +279,30 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not parse private key: %w", err)
 	}
 
+	// optional, comma separated public keys of retired signing keys.
+	// They verify ID tokens issued before a key rotation until those expire
+	var prevPubKeys []*rsa.PublicKey
+
+	for _, f := range strings.Split(os.Getenv("PREV_PUB_KEY_FILES"), ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+
+		prev, err := ioutil.ReadFile(f)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read previous public key pem file: %w", err)
+		}
+
+		prevPubKey, err := jwt.ParseRSAPublicKeyFromPEM(prev)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse previous public key: %w", err)
+		}
+
+		prevPubKeys = append(prevPubKeys, prevPubKey)
+	}
+
 	refreshSecret := os.Getenv("REFRESH_SECRET")
 
 	idTokenExp := os.Getenv("ID_TOKEN_EXP")
@@ -74,18 +318,171 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not parse REFRESH_TOKEN_EXP as int: %w", err)
 	}
 
+	// optional, defaults to only revoking the reused token's family
+	revokeAllOnReuse := os.Getenv("REFRESH_REUSE_REVOKE_ALL") == "true"
+
+	mfaChallengeSecret := os.Getenv("MFA_CHALLENGE_SECRET")
+	if mfaChallengeSecret == "" {
+		return nil, fmt.Errorf("MFA_CHALLENGE_SECRET is required")
+	}
+
+	if mfaChallengeSecret == refreshSecret {
+		return nil, fmt.Errorf("MFA_CHALLENGE_SECRET must differ from REFRESH_SECRET")
+	}
+
+	// optional, defaults to five minutes
+	var mfaChallengeExp int64 = 5 * 60
+	if v := os.Getenv("MFA_CHALLENGE_EXP"); v != "" {
+		mfaChallengeExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse MFA_CHALLENGE_EXP as int: %w", err)
+		}
+	}
+
+	// the public URL of this API, which identifies it to OpenID Connect clients
+	oidcIssuer := os.Getenv("OIDC_ISSUER")
+	if oidcIssuer == "" {
+		return nil, fmt.Errorf("OIDC_ISSUER is required")
+	}
+
 	tokenService := service.NewTokenService(&service.TSConfig{
 		TokenRepository: tokenRepository,
 		PrivKey: privKey,
 		PubKey: pubKey,
+		PrevPubKeys: prevPubKeys,
 		RefreshSecret: refreshSecret,
 		IDExpiratonSecs: idExp,
 		RefreshExpirationSecs: refreshExp,
+		RevokeAllOnReuse: revokeAllOnReuse,
+		MFAChallengeSecret: mfaChallengeSecret,
+		MFAChallengeExpirationSecs: mfaChallengeExp,
+		OIDCIssuer: oidcIssuer,
+		EventBroker: eventBroker,
+		AuditRepository: auditRepository,
+		Logger: logger,
 	})
 
-	router := gin.Default()
+	// passkeys are bound to WEBAUTHN_RP_ID, a domain, and may only be used
+	// from the comma separated WEBAUTHN_ORIGINS under it
+	webAuthnRPID := os.Getenv("WEBAUTHN_RP_ID")
+	if webAuthnRPID == "" {
+		return nil, fmt.Errorf("WEBAUTHN_RP_ID is required")
+	}
 
-	baseURL := os.Getenv("ACCOUNT_API_URL")
+	var webAuthnOrigins []string
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			webAuthnOrigins = append(webAuthnOrigins, origin)
+		}
+	}
+
+	if len(webAuthnOrigins) == 0 {
+		return nil, fmt.Errorf("WEBAUTHN_ORIGINS is required")
+	}
+
+	// optional, names the service when creating passkeys
+	webAuthnRPName := os.Getenv("WEBAUTHN_RP_NAME")
+	if webAuthnRPName == "" {
+		webAuthnRPName = "Memrizr"
+	}
+
+	// optional, defaults to five minutes
+	var webAuthnChallengeExp int64 = 5 * 60
+	if v := os.Getenv("WEBAUTHN_CHALLENGE_EXP"); v != "" {
+		webAuthnChallengeExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse WEBAUTHN_CHALLENGE_EXP as int: %w", err)
+		}
+	}
+
+	webAuthnService := service.NewWebAuthnService(&service.WSConfig{
+		UserRepository: userRepository,
+		CredentialRepository: webAuthnCredentialRepository,
+		ChallengeRepository: webAuthnChallengeRepository,
+		RPID: webAuthnRPID,
+		RPName: webAuthnRPName,
+		Origins: webAuthnOrigins,
+		ChallengeExpirationSecs: webAuthnChallengeExp,
+		AuditRepository: auditRepository,
+		Logger: logger,
+	})
+
+	identityProviders, err := identityProvidersFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// how long users have to sign in at an identity provider
+	var oauthStateExp int64 = 10 * 60
+	if v := os.Getenv("OAUTH_STATE_EXP"); v != "" {
+		oauthStateExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse OAUTH_STATE_EXP as int: %w", err)
+		}
+	}
+
+	identityService := service.NewIdentityService(&service.ISConfig{
+		UserRepository: userRepository,
+		IdentityRepository: userIdentityRepository,
+		StateRepository: oauthStateRepository,
+		Providers: identityProviders,
+		StateExpirationSecs: oauthStateExp,
+		EventBroker: eventBroker,
+		Transactor: transactor,
+		Logger: logger,
+	})
+
+	// the web client's page where users sign in and approve OpenID
+	// Connect clients
+	oidcLoginURL := os.Getenv("OIDC_LOGIN_URL")
+	if oidcLoginURL == "" {
+		return nil, fmt.Errorf("OIDC_LOGIN_URL is required")
+	}
+
+	oidcClients, err := oidcClientsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var oidcCodeExp int64 = 60
+	if v := os.Getenv("OIDC_CODE_EXP"); v != "" {
+		oidcCodeExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse OIDC_CODE_EXP as int: %w", err)
+		}
+	}
+
+	oidcService := service.NewOIDCService(&service.OSConfig{
+		UserRepository: userRepository,
+		CodeRepository: repository.NewOIDCCodeRepository(d.RedisClient, logger),
+		Clients: oidcClients,
+		Issuer: oidcIssuer,
+		LoginURL: oidcLoginURL,
+		CodeExpirationSecs: oidcCodeExp,
+		Logger: logger,
+	})
+
+	// requests are logged by the handler, with their request IDs
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	// client IPs key rate limits and lockouts, so X-Forwarded-For is only
+	// believed when sent by a listed proxy. By default, no proxy is trusted
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("could not parse TRUSTED_PROXIES: %w", err)
+	}
+
+	// filesystem images have no host of their own, so we serve them
+	if imageDir != "" {
+		router.Static(baseURL+fsImagePath, imageDir)
+	}
 
 	handlerTimeout := os.Getenv("HANDLER_TIMEOUT")
 	ht, err := strconv.ParseInt(handlerTimeout, 0, 64)
@@ -93,13 +490,268 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not parse HANDLER_TIMEOUT as int: %w", err)
 	}
 
+	maxBodyBytes := os.Getenv("MAX_BODY_BYTES")
+	mbb, err := strconv.ParseInt(maxBodyBytes, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MAX_BODY_BYTES as int: %w", err)
+	}
+
+	defaultRateLimit, err := rateLimitFromEnv("RATE_LIMIT_DEFAULT", "default", "300/1m")
+	if err != nil {
+		return nil, err
+	}
+
+	authRateLimit, err := rateLimitFromEnv("RATE_LIMIT_AUTH", "auth", "10/1m")
+	if err != nil {
+		return nil, err
+	}
+
+	userRateLimit, err := rateLimitFromEnv("RATE_LIMIT_USER", "user", "120/1m")
+	if err != nil {
+		return nil, err
+	}
+	userRateLimit.KeyFunc = middleware.KeyByUser
+
 	handler.NewHandler(&handler.Config{
 		R: router,
 		UserService: userService,
 		TokenService: tokenService,
+		WebAuthnService: webAuthnService,
+		IdentityService: identityService,
+		OIDCService: oidcService,
+		AdminService: service.NewAdminService(&service.ASConfig{
+			UserRepository: userRepository,
+			TokenRepository: tokenRepository,
+			AuditRepository: auditRepository,
+			Logger: logger,
+		}),
+		ExportService: service.NewExportService(&service.ESConfig{
+			UserRepository: userRepository,
+			TokenRepository: tokenRepository,
+			UserIdentityRepository: userIdentityRepository,
+			WebAuthnCredentialRepository: webAuthnCredentialRepository,
+			ImageRepository: imageRepository,
+			AuditRepository: auditRepository,
+			Logger: logger,
+		}),
 		BaseURL: baseURL,
 		TimeoutDuration: time.Duration(time.Duration(ht) * time.Second),
+		MaxBodyBytes: mbb,
+		RateLimitStore: repository.NewRateLimitStore(d.RedisClient, logger),
+		DefaultRateLimit: defaultRateLimit,
+		AuthRateLimit: authRateLimit,
+		UserRateLimit: userRateLimit,
+		RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+		Logger: logger,
 	})
 
 	return router, nil
-}
\ No newline at end of file
+}
+
+// signin lockout defaults. A single IP may be shared by many users (NAT,
+// offices) so it gets more slack than an individual email
+var (
+	defaultEmailLockout = service.LockoutPolicy{
+		FreeAttempts: 5,
+		BaseDelay:    time.Second,
+		MaxDelay:     15 * time.Minute,
+		Window:       time.Hour,
+	}
+	defaultIPLockout = service.LockoutPolicy{
+		FreeAttempts: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     15 * time.Minute,
+		Window:       time.Hour,
+	}
+)
+
+// lockoutPolicyFromEnv overrides fields of def with the optional
+// <prefix>_FREE_ATTEMPTS, <prefix>_BASE_DELAY, <prefix>_MAX_DELAY and
+// <prefix>_WINDOW env variables. Delays are Go durations, eg "30s"
+func lockoutPolicyFromEnv(prefix string, def service.LockoutPolicy) (service.LockoutPolicy, error) {
+	p := def
+
+	if v := os.Getenv(prefix + "_FREE_ATTEMPTS"); v != "" {
+		n, err := strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return p, fmt.Errorf("could not parse %s_FREE_ATTEMPTS as int: %w", prefix, err)
+		}
+		p.FreeAttempts = n
+	}
+
+	durations := map[string]*time.Duration{
+		"_BASE_DELAY": &p.BaseDelay,
+		"_MAX_DELAY":  &p.MaxDelay,
+		"_WINDOW":     &p.Window,
+	}
+
+	for suffix, d := range durations {
+		if v := os.Getenv(prefix + suffix); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return p, fmt.Errorf("could not parse %s%s as duration: %w", prefix, suffix, err)
+			}
+			*d = parsed
+		}
+	}
+
+	return p, nil
+}
+
+// passwordParamsFromEnv overrides fields of service.DefaultPasswordParams
+// with the optional PASSWORD_HASH_ALGORITHM, ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS, ARGON2_PARALLELISM, SCRYPT_N, SCRYPT_R and SCRYPT_P
+// env variables
+func passwordParamsFromEnv() (service.PasswordParams, error) {
+	p := service.DefaultPasswordParams
+
+	switch algorithm := os.Getenv("PASSWORD_HASH_ALGORITHM"); algorithm {
+	case "":
+	case service.PasswordArgon2id, service.PasswordScrypt:
+		p.Algorithm = algorithm
+	default:
+		return p, fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM: %s", algorithm)
+	}
+
+	ints := map[string]struct {
+		bits int
+		set  func(uint64)
+	}{
+		"ARGON2_MEMORY_KIB":  {32, func(n uint64) { p.Memory = uint32(n) }},
+		"ARGON2_ITERATIONS":  {32, func(n uint64) { p.Iterations = uint32(n) }},
+		"ARGON2_PARALLELISM": {8, func(n uint64) { p.Parallelism = uint8(n) }},
+		"SCRYPT_N":           {31, func(n uint64) { p.ScryptN = int(n) }},
+		"SCRYPT_R":           {31, func(n uint64) { p.ScryptR = int(n) }},
+		"SCRYPT_P":           {31, func(n uint64) { p.ScryptP = int(n) }},
+	}
+
+	for key, i := range ints {
+		if v := os.Getenv(key); v != "" {
+			n, err := strconv.ParseUint(v, 0, i.bits)
+			if err != nil || n == 0 {
+				return p, fmt.Errorf("could not parse %s as positive int: %s", key, v)
+			}
+			i.set(n)
+		}
+	}
+
+	if p.ScryptN&(p.ScryptN-1) != 0 {
+		return p, fmt.Errorf("SCRYPT_N must be a power of 2, got: %d", p.ScryptN)
+	}
+
+	return p, nil
+}
+
+// rateLimitFromEnv reads a rate limit of the form "<limit>/<window>", eg
+// "10/1m", from the env variable named key, falling back to def. The
+// algorithm is chosen by RATE_LIMIT_ALGORITHM, defaulting to token bucket
+func rateLimitFromEnv(key string, name string, def string) (*middleware.RateLimitConfig, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		v = def
+	}
+
+	parts := strings.SplitN(v, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%s must be of the form <limit>/<window>, got: %s", key, v)
+	}
+
+	limit, err := strconv.ParseInt(parts[0], 0, 64)
+	if err != nil || limit <= 0 {
+		return nil, fmt.Errorf("could not parse %s limit as positive int: %s", key, parts[0])
+	}
+
+	window, err := time.ParseDuration(parts[1])
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("could not parse %s window as positive duration: %s", key, parts[1])
+	}
+
+	algorithm := middleware.RateLimitAlgorithm(os.Getenv("RATE_LIMIT_ALGORITHM"))
+
+	switch algorithm {
+	case "":
+		algorithm = middleware.TokenBucket
+	case middleware.TokenBucket, middleware.SlidingWindow:
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_ALGORITHM: %s", algorithm)
+	}
+
+	return &middleware.RateLimitConfig{
+		Name:      name,
+		Algorithm: algorithm,
+		Limit:     limit,
+		Window:    window,
+		KeyFunc:   middleware.KeyByIP,
+	}, nil
+}
+
+// identityProvidersFromEnv returns the identity providers users can sign
+// in with. Each is enabled by setting its client ID and secret, and all
+// redirect users back to OAUTH_REDIRECT_URL, a page of the client app
+// which posts the state and code it is given to /oauth/callback.
+// GOOGLE_ISSUER may point Google signins at another OpenID Connect
+// provider, such as a local fake one
+func identityProvidersFromEnv() (map[string]model.IdentityProvider, error) {
+	providers := map[string]model.IdentityProvider{}
+	redirectURL := os.Getenv("OAUTH_REDIRECT_URL")
+
+	if id, secret := os.Getenv("GOOGLE_CLIENT_ID"), os.Getenv("GOOGLE_CLIENT_SECRET"); id != "" && secret != "" {
+		issuer := os.Getenv("GOOGLE_ISSUER")
+		if issuer == "" {
+			issuer = "https://accounts.google.com"
+		}
+
+		providers["google"] = identity.NewOIDCProvider(&identity.OIDCConfig{
+			Name: "google",
+			Issuer: issuer,
+			ClientID: id,
+			ClientSecret: secret,
+			RedirectURL: redirectURL,
+		})
+	}
+
+	if id, secret := os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET"); id != "" && secret != "" {
+		providers["github"] = identity.NewGitHubProvider(&identity.GitHubConfig{
+			ClientID: id,
+			ClientSecret: secret,
+			RedirectURL: redirectURL,
+		})
+	}
+
+	if len(providers) > 0 && redirectURL == "" {
+		return nil, fmt.Errorf("OAUTH_REDIRECT_URL is required when an identity provider is configured")
+	}
+
+	return providers, nil
+}
+
+// oidcClientsFromEnv reads the OpenID Connect clients from OIDC_CLIENTS, a
+// JSON array of clients like
+// {"id": "deck", "secret": "...", "name": "Decks", "redirectUris": ["https://..."]}.
+// Clients without a secret are public, and must use PKCE
+func oidcClientsFromEnv() ([]*model.OIDCClient, error) {
+	var clients []*model.OIDCClient
+
+	v := os.Getenv("OIDC_CLIENTS")
+	if v == "" {
+		return clients, nil
+	}
+
+	if err := json.Unmarshal([]byte(v), &clients); err != nil {
+		return nil, fmt.Errorf("could not parse OIDC_CLIENTS: %w", err)
+	}
+
+	for _, client := range clients {
+		if client.ID == "" || len(client.RedirectURIs) == 0 {
+			return nil, fmt.Errorf("OIDC_CLIENTS entries need an id and redirectUris")
+		}
+
+		for _, uri := range client.RedirectURIs {
+			if u, err := url.Parse(uri); err != nil || !u.IsAbs() || u.Fragment != "" {
+				return nil, fmt.Errorf("OIDC client %s has an invalid redirect URI: %s", client.ID, uri)
+			}
+		}
+	}
+
+	return clients, nil
+}
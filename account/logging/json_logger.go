@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+type jsonLogger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level Level
+	now   func() time.Time
+}
+
+// NewJSONLogger is a factory for initializing a logger which writes each
+// entry at or above level to w as a line of JSON
+func NewJSONLogger(w io.Writer, level Level) model.Logger {
+	return &jsonLogger{
+		w:     w,
+		level: level,
+		now:   time.Now,
+	}
+}
+
+var defaultLogger = NewJSONLogger(os.Stderr, InfoLevel)
+
+// Default returns a logger writing info and above to stderr, for
+// components which weren't given one
+func Default() model.Logger {
+	return defaultLogger
+}
+
+// OrDefault returns l, or the default logger if l is nil
+func OrDefault(l model.Logger) model.Logger {
+	if l == nil {
+		return defaultLogger
+	}
+
+	return l
+}
+
+// Nop returns a logger which discards every entry
+func Nop() model.Logger {
+	return NewJSONLogger(ioutil.Discard, ErrorLevel+1)
+}
+
+func (l *jsonLogger) Debug(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log(ctx, DebugLevel, msg, keysAndValues)
+}
+
+func (l *jsonLogger) Info(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log(ctx, InfoLevel, msg, keysAndValues)
+}
+
+func (l *jsonLogger) Warn(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log(ctx, WarnLevel, msg, keysAndValues)
+}
+
+func (l *jsonLogger) Error(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log(ctx, ErrorLevel, msg, keysAndValues)
+}
+
+// log writes an entry's fields in a fixed order, time, level, msg and
+// requestId first, followed by the caller's fields as given
+func (l *jsonLogger) log(ctx context.Context, level Level, msg string, keysAndValues []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	writeField(&buf, "time", l.now().UTC().Format(time.RFC3339Nano))
+	buf.WriteByte(',')
+	writeField(&buf, "level", level.String())
+	buf.WriteByte(',')
+	writeField(&buf, "msg", msg)
+
+	if ctx != nil {
+		if id := model.RequestIDFromContext(ctx); id != "" {
+			buf.WriteByte(',')
+			writeField(&buf, "requestId", id)
+		}
+	}
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+
+		var value interface{} = "!MISSING"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+
+		if sensitive(key) {
+			value = Redacted
+		}
+
+		buf.WriteByte(',')
+		writeField(&buf, key, value)
+	}
+
+	buf.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.w.Write(buf.Bytes())
+}
+
+func writeField(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(marshalValue(value))
+}
+
+// marshalValue marshals value with any sensitive fields nested in it
+// redacted. Values JSON can't represent are written as their type only,
+// as their fields couldn't be redacted
+func marshalValue(value interface{}) []byte {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case time.Duration:
+		value = v.String()
+	}
+
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("!UNSUPPORTED(%T)", value))
+		return data
+	}
+
+	if len(data) == 0 || (data[0] != '{' && data[0] != '[') {
+		return data
+	}
+
+	var decoded interface{}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	if err := d.Decode(&decoded); err != nil {
+		return data
+	}
+
+	redacted, err := json.Marshal(redact(decoded))
+
+	if err != nil {
+		return data
+	}
+
+	return redacted
+}
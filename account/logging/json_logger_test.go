@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLogger(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	setup := func(level Level) (*jsonLogger, *bytes.Buffer) {
+		var buf bytes.Buffer
+
+		l := NewJSONLogger(&buf, level).(*jsonLogger)
+		l.now = func() time.Time { return now }
+
+		return l, &buf
+	}
+
+	t.Run("Writes fields in order", func(t *testing.T) {
+		l, buf := setup(InfoLevel)
+
+		ctx := model.ContextWithRequestID(context.TODO(), "arequestid")
+
+		l.Info(ctx, "Signed in", "uid", "auid", "attempts", 2, "err", errors.New("oops"), "after", 3*time.Second)
+
+		assert.Equal(t, `{"time":"2021-03-04T05:06:07Z","level":"info","msg":"Signed in","requestId":"arequestid","uid":"auid","attempts":2,"err":"oops","after":"3s"}`+"\n", buf.String())
+	})
+
+	t.Run("Skips entries below level", func(t *testing.T) {
+		l, buf := setup(WarnLevel)
+
+		l.Debug(context.TODO(), "debug")
+		l.Info(context.TODO(), "info")
+		l.Warn(context.TODO(), "warn")
+		l.Error(context.TODO(), "error")
+
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		assert.Len(t, lines, 2)
+		assert.Contains(t, string(lines[0]), `"level":"warn"`)
+		assert.Contains(t, string(lines[1]), `"level":"error"`)
+	})
+
+	t.Run("Missing value", func(t *testing.T) {
+		l, buf := setup(InfoLevel)
+
+		l.Info(context.TODO(), "Odd fields", "uid")
+
+		assert.Contains(t, buf.String(), `"uid":"!MISSING"`)
+	})
+
+	t.Run("Unsupported value", func(t *testing.T) {
+		l, buf := setup(InfoLevel)
+
+		l.Info(context.TODO(), "Odd value", "done", make(chan struct{}))
+
+		assert.Contains(t, buf.String(), `"done":"!UNSUPPORTED(chan struct {})"`)
+	})
+
+	t.Run("Redacts sensitive fields", func(t *testing.T) {
+		l, buf := setup(InfoLevel)
+
+		type session struct {
+			ID           string `json:"id"`
+			RefreshToken string `json:"refreshToken"`
+		}
+
+		l.Error(context.TODO(), "Failed",
+			"password", "hunter2",
+			"refreshToken", "arefreshtoken",
+			"tokenID", "atokenid",
+			"session", &session{ID: "asession", RefreshToken: "arefreshtoken"},
+			"sessions", []map[string]interface{}{{"clientSecret": "asecret", "count": 1}},
+		)
+
+		var entry map[string]interface{}
+		err := json.Unmarshal(buf.Bytes(), &entry)
+		assert.NoError(t, err)
+
+		assert.Equal(t, Redacted, entry["password"])
+		assert.Equal(t, Redacted, entry["refreshToken"])
+		assert.Equal(t, "atokenid", entry["tokenID"])
+		assert.Equal(t, map[string]interface{}{"id": "asession", "refreshToken": Redacted}, entry["session"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"clientSecret": Redacted, "count": float64(1)}}, entry["sessions"])
+		assert.NotContains(t, buf.String(), "hunter2")
+		assert.NotContains(t, buf.String(), "arefreshtoken")
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, WarnLevel, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package logging
+
+import "strings"
+
+// Redacted replaces the values of sensitive fields
+const Redacted = "[REDACTED]"
+
+// sensitive reports whether a field named key may hold a secret, such
+// as a password, refresh token or client secret. Token IDs are safe to
+// log, as they can't be used in place of the token
+func sensitive(key string) bool {
+	k := strings.ToLower(key)
+
+	switch {
+	case strings.Contains(k, "password"),
+		strings.Contains(k, "secret"),
+		strings.HasSuffix(k, "token"),
+		strings.HasSuffix(k, "tokens"),
+		k == "authorization",
+		k == "cookie",
+		k == "code",
+		k == "recoverycodes":
+		return true
+	}
+
+	return false
+}
+
+// redact replaces the values of sensitive fields of decoded JSON, however
+// deeply nested
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if sensitive(key) {
+				v[key] = Redacted
+				continue
+			}
+
+			v[key] = redact(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redact(value)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// characters we don't allow in a file name
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+type fileMailer struct {
+	Dir    string
+	From   string
+	Logger model.Logger
+}
+
+// NewFileMailer is a factory for initializing a mailer which writes
+// each email to a .eml file in dir instead of sending it, for development
+func NewFileMailer(dir string, from string, logger model.Logger) model.Mailer {
+	return &fileMailer{
+		Dir:    dir,
+		From:   from,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+func (m *fileMailer) Send(ctx context.Context, email *model.Email) error {
+	now := time.Now().UTC()
+
+	// sorts by the time the email was sent
+	name := fmt.Sprintf("%s_%s.eml", now.Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(email.To, "_"))
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		m.From, email.To, email.Subject, now.Format(time.RFC1123Z), email.Body)
+
+	if err := ioutil.WriteFile(filepath.Join(m.Dir, name), []byte(msg), 0600); err != nil {
+		m.Logger.Error(ctx, "Unable to write email", "dir", m.Dir, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := NewFileMailer(dir, "noreply@memrizr.test", logging.Nop())
+
+	err := m.Send(context.TODO(), &model.Email{
+		To:      "bob/../@bob.com",
+		Subject: "Hello",
+		Body:    "Hi Bob",
+	})
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// the recipient can't be used to escape dir
+	assert.True(t, strings.HasSuffix(files[0], "_bob_.._@bob.com.eml"))
+
+	msg, err := ioutil.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(msg), "From: noreply@memrizr.test\r\n")
+	assert.Contains(t, string(msg), "To: bob/../@bob.com\r\n")
+	assert.Contains(t, string(msg), "Subject: Hello\r\n")
+	assert.True(t, strings.HasSuffix(string(msg), "\r\n\r\nHi Bob"))
+}
@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+type logMailer struct {
+	From   string
+	Logger model.Logger
+}
+
+// NewLogMailer is a factory for initializing a mailer which writes
+// emails to logger instead of sending them, for development. Their
+// bodies hold links with tokens, so it's not for production either
+func NewLogMailer(from string, logger model.Logger) model.Mailer {
+	return &logMailer{
+		From:   from,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+func (m *logMailer) Send(ctx context.Context, email *model.Email) error {
+	m.Logger.Info(ctx, "Email", "from", m.From, "to", email.To, "subject", email.Subject, "body", email.Body)
+
+	return nil
+}
@@ -2,27 +2,52 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
 )
 
 func main() {
-	log.Println("Starting server...")
+	// optional, one of debug, info, warn or error
+	level := logging.InfoLevel
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		parsed, err := logging.ParseLevel(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to parse LOG_LEVEL: %v\n", err)
+			os.Exit(1)
+		}
+		level = parsed
+	}
+
+	logger := logging.NewJSONLogger(os.Stderr, level)
+	ctx := context.Background()
 
-	ds, err := initDS()
+	logger.Info(ctx, "Starting server...")
+
+	ds, err := initDS(logger)
 
 	if err != nil {
-		log.Fatalf("Unable to initialize data sources: %v\n", err)
+		logger.Error(ctx, "Unable to initialize data sources", "err", err)
+		os.Exit(1)
 	}
 
-	router, err := inject(ds)
+	// stops the background workers started by inject
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+
+	var workers sync.WaitGroup
+
+	router, err := inject(workerCtx, &workers, ds, logger)
 
 	if err != nil {
-		log.Fatalf("Failure to inject data sources: %v\n", err)
+		logger.Error(ctx, "Failure to inject data sources", "err", err)
+		os.Exit(1)
 	}
 
 	srv := &http.Server{
@@ -33,14 +58,15 @@ func main() {
 	// Graceful server shutdown - https://github.com/gin-gonic/examples/blob/master/graceful-shutdown/graceful-shutdown/server.go
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to initialize server: %v\n", err)
+			logger.Error(ctx, "Failed to initialize server", "err", err)
+			os.Exit(1)
 		}
 	}()
 
-	log.Printf("Listening on port %v\n", srv.Addr)
+	logger.Info(ctx, "Listening", "addr", srv.Addr)
 
 	// Wait for kill signal of channel
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -49,16 +75,23 @@ func main() {
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	// workers must be done with the data sources before they're closed
+	logger.Info(ctx, "Stopping background workers...")
+	stopWorkers()
+	workers.Wait()
+
 	if err := ds.close(); err != nil {
-		log.Fatalf("A problem occured gracefully shutting down data sources: %v\n", err)
+		logger.Error(ctx, "A problem occured gracefully shutting down data sources", "err", err)
+		os.Exit(1)
 	}
 
 	// Shutdown server
-	log.Println("Shutting down server...")
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v\n", err)
+	logger.Info(ctx, "Shutting down server...")
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error(ctx, "Server forced to shutdown", "err", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider VARCHAR NOT NULL,
+    subject VARCHAR NOT NULL,
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    email VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_uid_idx ON user_identities (uid);
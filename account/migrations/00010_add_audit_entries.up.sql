@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id BIGSERIAL PRIMARY KEY,
+    uid uuid REFERENCES users (uid) ON DELETE CASCADE,
+    action VARCHAR NOT NULL,
+    outcome VARCHAR NOT NULL,
+    ip VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_entries_uid_created_at_idx ON audit_entries (uid, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_entries_created_at_idx ON audit_entries (created_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS audit_entries_target_uid_created_at_idx;
+ALTER TABLE audit_entries DROP COLUMN IF EXISTS target_uid;
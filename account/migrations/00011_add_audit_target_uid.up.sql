@@ -0,0 +1,2 @@
+ALTER TABLE audit_entries ADD COLUMN IF NOT EXISTS target_uid uuid;
+CREATE INDEX IF NOT EXISTS audit_entries_target_uid_created_at_idx ON audit_entries (target_uid, created_at DESC) WHERE target_uid IS NOT NULL;
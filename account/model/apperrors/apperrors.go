@@ -3,7 +3,9 @@ package apperrors
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 // Type holds a type string and integer code for the error
@@ -14,10 +16,12 @@ const (
 	Authorization   	Type = "AUTHORIZATION"   // Authentication Failures -
 	BadRequest      	Type = "BAD_REQUEST"      // Validation errors / BadInput
 	Conflict        	Type = "CONFLICT"        // Already exists (eg, create account with existent email) - 409
+	Forbidden       	Type = "FORBIDDEN"       // Authenticated, but not allowed (eg, unverified email) - 403
 	Internal        	Type = "INTERNAL"        // Server (500) and fallback errors
 	NotFound        	Type = "NOT_FOUND"        // For not finding resource
 	PayloadTooLarge 	Type = "PAYLOAD_TOO_LARGE" // for uploading tons of JSON, or an image over the limit - 413
 	ServiceUnavailable 	Type = "SERVICE_UNAVAILABLE"
+	TooManyRequests 	Type = "TOO_MANY_REQUESTS" // for throttled or locked out clients - 429
 	UnsupportedMediaType Type = "UNSUPPORTED_MEDIA_TYPE"
 )
 
@@ -27,6 +31,8 @@ const (
 type Error struct {
 	Type    Type   `json:"type"`
 	Message string `json:"message"`
+	// RetryAfter is how long a throttled client should wait (TooManyRequests)
+	RetryAfter time.Duration `json:"-"`
 }
 
 // Error satisfies standard error interface
@@ -47,6 +53,8 @@ func (e *Error) Status() int {
 		return http.StatusBadRequest
 	case Conflict:
 		return http.StatusConflict
+	case Forbidden:
+		return http.StatusForbidden
 	case Internal:
 		return http.StatusInternalServerError
 	case NotFound:
@@ -55,6 +63,8 @@ func (e *Error) Status() int {
 		return http.StatusRequestEntityTooLarge
 	case ServiceUnavailable:
 		return http.StatusServiceUnavailable
+	case TooManyRequests:
+		return http.StatusTooManyRequests
 	case UnsupportedMediaType:
 		return http.StatusUnsupportedMediaType
 	default:
@@ -73,6 +83,17 @@ func Status(err error) int {
 	return http.StatusInternalServerError
 }
 
+// RetryAfterSeconds returns the whole number of seconds a client should
+// wait before retrying, for use in a Retry-After header. It returns 0
+// if err doesn't carry a retry delay
+func RetryAfterSeconds(err error) int {
+	var e *Error
+	if errors.As(err, &e) && e.RetryAfter > 0 {
+		return int(math.Ceil(e.RetryAfter.Seconds()))
+	}
+	return 0
+}
+
 /*
 * Error "Factories"
  */
@@ -101,6 +122,14 @@ func NewConflict(name string, value string) *Error {
 	}
 }
 
+// NewForbidden to create an error for 403
+func NewForbidden(reason string) *Error {
+	return &Error{
+		Type:    Forbidden,
+		Message: reason,
+	}
+}
+
 // NewInternal for 500 errors and unknown errors
 func NewInternal() *Error {
 	return &Error{
@@ -137,4 +166,13 @@ func NewUnsupportedMediaType(reason string) *Error {
 		Type: UnsupportedMediaType,
 		Message: reason,
 	}
-}
\ No newline at end of file
+}
+
+// NewTooManyRequests to create an error for 429
+func NewTooManyRequests(retryAfter time.Duration) *Error {
+	return &Error{
+		Type:       TooManyRequests,
+		Message:    fmt.Sprintf("Too many requests. Try again in %v", retryAfter.Round(time.Second)),
+		RetryAfter: retryAfter,
+	}
+}
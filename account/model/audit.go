@@ -0,0 +1,85 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Security relevant actions which are audited
+const (
+	AuditSignin         = "signin"
+	AuditSessionStart   = "session.start"
+	AuditTokenRefresh   = "token.refresh"
+	AuditSignout        = "signout"
+	AuditDetailsUpdate  = "details.update"
+	AuditPasswordChange = "password.change"
+	AuditPasswordReset  = "password.reset"
+	AuditTOTPEnable     = "totp.enable"
+	AuditTOTPDisable    = "totp.disable"
+	AuditPasskeyAdd     = "passkey.add"
+	AuditPasskeyRemove  = "passkey.remove"
+	AuditAccountDelete  = "account.delete"
+	// taken by an admin on another user's account
+	AuditAdminDisable = "admin.disable"
+	AuditAdminEnable  = "admin.enable"
+	AuditAdminDelete  = "admin.delete"
+	AuditAdminSignout = "admin.signout"
+)
+
+// Outcomes of audited actions
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry records an attempt at a security relevant action. UID is
+// nil when the actor isn't known, eg a signin with an unknown email.
+// TargetUID is the user an admin's action was taken on
+type AuditEntry struct {
+	ID        int64      `db:"id" json:"id"`
+	UID       *uuid.UUID `db:"uid" json:"uid,omitempty"`
+	Action    string     `db:"action" json:"action"`
+	Outcome   string     `db:"outcome" json:"outcome"`
+	TargetUID *uuid.UUID `db:"target_uid" json:"targetUid,omitempty"`
+	IP        string     `db:"ip" json:"ip"`
+	UserAgent string     `db:"user_agent" json:"userAgent"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// AuditQuery filters and pages through audit entries, newest first.
+// Empty fields match any entry, and a Limit of 0 returns every entry
+type AuditQuery struct {
+	UID       *uuid.UUID
+	TargetUID *uuid.UUID
+	Action    string
+	Outcome   string
+	IP        string
+	Limit     int
+	Offset    int
+}
+
+// AuditPage is a page of audit entries
+type AuditPage struct {
+	Entries []*AuditEntry `json:"entries"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
+
+type adminKey struct{}
+
+// ContextWithAdmin returns a copy of ctx recording that the request is an
+// admin's, acting on another user's account
+func ContextWithAdmin(ctx context.Context, uid uuid.UUID) context.Context {
+	return context.WithValue(ctx, adminKey{}, uid)
+}
+
+// AdminFromContext returns the UID of the admin stored in ctx, if any
+func AdminFromContext(ctx context.Context) *uuid.UUID {
+	uid, ok := ctx.Value(adminKey{}).(uuid.UUID)
+	if !ok {
+		return nil
+	}
+	return &uid
+}
@@ -0,0 +1,8 @@
+package model
+
+// Email is a plain text email sent to a user
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of Event
+const (
+	EventUserCreated      = "user.created"
+	EventUserUpdated      = "user.updated"
+	EventUserImageChanged = "user.image_changed"
+	EventUserSignedOut    = "user.signed_out"
+	// EventUserDeleted is published once a user's account is gone for
+	// good, so other services should erase their data about the user too
+	EventUserDeleted = "user.deleted"
+)
+
+// Event is something which happened to a user that other services
+// may need to act on. Events are delivered at least once, so consumers
+// should ignore an ID they've already seen
+type Event struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	UID        uuid.UUID `json:"uid"`
+	OccurredAt time.Time `json:"occurredAt"`
+	// the user as they are after the event, when they still exist
+	User *User `json:"user,omitempty"`
+}
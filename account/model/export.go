@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// UserExport is everything the account service holds about a user,
+// for answering their requests to access their data
+type UserExport struct {
+	ExportedAt   time.Time             `json:"exportedAt"`
+	User         *User                 `json:"user"`
+	Sessions     []*Session            `json:"sessions"`
+	Identities   []*UserIdentity       `json:"identities"`
+	Passkeys     []*WebAuthnCredential `json:"passkeys"`
+	Activity     []*AuditEntry         `json:"activity"`
+	ProfileImage *ExportedImage        `json:"profileImage,omitempty"`
+}
+
+// ExportedImage is an image stored for a user. Data is base64
+// encoded in JSON, and left out when the image is exported as a file
+type ExportedImage struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data,omitempty"`
+}
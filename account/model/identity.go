@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExternalIdentity is a user's account at an identity provider,
+// as the provider describes it after signin
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// UserIdentity links a user to their account at an identity provider,
+// identified by the provider's subject ID
+type UserIdentity struct {
+	Provider  string    `db:"provider" json:"provider"`
+	Subject   string    `db:"subject" json:"subject"`
+	UID       uuid.UUID `db:"uid" json:"-"`
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// OAuthState is kept between sending a user to an identity provider and
+// their return, and is looked up by the state parameter of the request
+type OAuthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+}
@@ -14,13 +14,74 @@ type UserService interface {
 	Signin(ctx context.Context, u *User) error
 	UpdateDetails(ctx context.Context, u *User) error
 	SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*User, error)
+	ClearProfileImage(ctx context.Context, uid uuid.UUID) error
+	SendVerificationEmail(ctx context.Context, uid uuid.UUID) error
+	VerifyEmail(ctx context.Context, token string) (*User, error)
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+	ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) (*User, error)
+	EnrollTOTP(ctx context.Context, uid uuid.UUID) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, uid uuid.UUID, password string) error
+	VerifyMFA(ctx context.Context, uid uuid.UUID, code string) (*User, error)
+	Delete(ctx context.Context, uid uuid.UUID) error
+	DeleteAccount(ctx context.Context, uid uuid.UUID, password string) error
+	PurgeDeletedAccounts(ctx context.Context) (int, error)
+	GetActivity(ctx context.Context, uid uuid.UUID, limit int, offset int) (*AuditPage, error)
 }
 
 type TokenService interface {
 	NewPairFromUser(ctx context.Context, u *User, prevTokenID string)(*TokenPair, error)
 	Signout(ctx context.Context, uid uuid.UUID) error
-	ValidateIDToken(tokenString string) (*User, error)
-	ValidateRefreshToken(refreshTokenString string) (*RefreshToken, error)
+	GetSessions(ctx context.Context, uid uuid.UUID) ([]*Session, error)
+	RevokeSession(ctx context.Context, uid uuid.UUID, sessionID string) error
+	GetJWKS() *JWKS
+	NewOIDCTokens(ctx context.Context, u *User, authorization *OIDCAuthorization, prevTokenID string) (*OIDCTokens, error)
+	NewMFAChallenge(ctx context.Context, u *User) (string, error)
+	ValidateMFAChallenge(ctx context.Context, tokenString string) (uuid.UUID, error)
+	ValidateIDToken(ctx context.Context, tokenString string) (*User, error)
+	ValidateRefreshToken(ctx context.Context, refreshTokenString string) (*RefreshToken, error)
+	ValidateOIDCAccessToken(ctx context.Context, tokenString string) (*OIDCAccessToken, error)
+}
+
+// WebAuthnService registers passkeys and signs users in with them
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, uid uuid.UUID) (*WebAuthnCreationOptions, error)
+	FinishRegistration(ctx context.Context, uid uuid.UUID, name string, attestation *WebAuthnAttestation) (*WebAuthnCredential, error)
+	BeginLogin(ctx context.Context) (*WebAuthnRequestOptions, error)
+	FinishLogin(ctx context.Context, assertion *WebAuthnAssertion) (*User, error)
+	GetCredentials(ctx context.Context, uid uuid.UUID) ([]*WebAuthnCredential, error)
+	DeleteCredential(ctx context.Context, uid uuid.UUID, credentialID []byte) error
+}
+
+// IdentityService signs users in with their accounts at external
+// identity providers, such as Google or GitHub
+type IdentityService interface {
+	AuthCodeURL(ctx context.Context, provider string) (string, string, error)
+	Signin(ctx context.Context, state string, code string) (*User, error)
+}
+
+// AdminService is used by support staff to manage users' accounts
+type AdminService interface {
+	ListUsers(ctx context.Context, query *UserQuery) (*UserPage, error)
+	SetDisabled(ctx context.Context, uid uuid.UUID, disabled bool) (*User, error)
+	AuditLog(ctx context.Context, query *AuditQuery) (*AuditPage, error)
+}
+
+// ExportService gathers a user's data for them to download
+type ExportService interface {
+	Export(ctx context.Context, uid uuid.UUID) (*UserExport, error)
+}
+
+// OIDCService lets registered clients sign users in with their memrizr
+// accounts, as an OpenID Connect provider
+type OIDCService interface {
+	Discovery() *OIDCDiscovery
+	StartAuthorization(ctx context.Context, req *OIDCAuthorizationRequest) (string, error)
+	Authorize(ctx context.Context, uid uuid.UUID, req *OIDCAuthorizationRequest) (string, error)
+	AuthenticateClient(clientID string, clientSecret string) (*OIDCClient, error)
+	ExchangeCode(ctx context.Context, client *OIDCClient, code string, redirectURI string, codeVerifier string) (*OIDCAuthorization, error)
+	UserInfo(ctx context.Context, access *OIDCAccessToken) (*OIDCUserInfo, error)
 }
 
 type UserRepository interface {
@@ -28,17 +89,139 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	Create(ctx context.Context, u *User) error
 	Update(ctx context.Context, u *User) error
-	UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*User, error)
+	UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string, thumbnails ImageThumbnails) (*User, error)
+	SetEmailVerified(ctx context.Context, uid uuid.UUID, email string) (*User, error)
+	UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error
+	UpdateTOTP(ctx context.Context, uid uuid.UUID, secret string, enabled bool, recoveryCodes RecoveryCodes) error
+	UseTOTPStep(ctx context.Context, uid uuid.UUID, step int64) error
+	UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) error
+	List(ctx context.Context, query *UserQuery) ([]*User, int, error)
+	SetDisabled(ctx context.Context, uid uuid.UUID, disabled bool) (*User, error)
+	Delete(ctx context.Context, uid uuid.UUID) error
+	SoftDelete(ctx context.Context, uid uuid.UUID, deletedAt time.Time) error
+	FindDeleted(ctx context.Context, before time.Time, limit int) ([]*User, error)
 }
 
+// TokenRepository stores refresh tokens. Every refresh token belongs to
+// a session (its family), which starts at signin and is carried through
+// each rotation
 type TokenRepository interface {
-	SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error
-	DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error
+	SetRefreshToken(ctx context.Context, userID string, tokenID string, session *Session, expiresIn time.Duration) error
+	DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) (*Session, error)
+	FindRotatedRefreshToken(ctx context.Context, userID string, tokenID string) (string, error)
+	GetSessions(ctx context.Context, userID string) ([]*Session, error)
+	DeleteSession(ctx context.Context, userID string, sessionID string) error
 	DeleateUserRefreshTokens(ctx context.Context, userID string) error
 }
 
+// SigninAttemptRepository tracks failed signins per key (an email or
+// client IP) so password guessing can be slowed down and locked out
+type SigninAttemptRepository interface {
+	IncrementFailures(ctx context.Context, key string, window time.Duration) (int64, error)
+	ResetFailures(ctx context.Context, key string) error
+	SetLockout(ctx context.Context, key string, d time.Duration) error
+	GetLockout(ctx context.Context, key string) (time.Duration, error)
+}
+
+// RateLimitStore counts requests against a key. Each call both checks
+// and records a request, atomically, so limits hold across instances.
+// limit requests are allowed per window; for the token bucket that is
+// the bucket's capacity and the time taken to refill it completely
+type RateLimitStore interface {
+	TokenBucket(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (*RateLimitResult, error)
+	SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (*RateLimitResult, error)
+}
+
+// PasswordResetRepository stores single use password reset tokens,
+// keyed by a hash of the token
+type PasswordResetRepository interface {
+	SetResetToken(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error
+	ConsumeResetToken(ctx context.Context, tokenHash string) (string, error)
+}
+
+// WebAuthnCredentialRepository stores users' passkeys
+type WebAuthnCredentialRepository interface {
+	Create(ctx context.Context, c *WebAuthnCredential) error
+	FindByID(ctx context.Context, id []byte) (*WebAuthnCredential, error)
+	FindByUser(ctx context.Context, uid uuid.UUID) ([]*WebAuthnCredential, error)
+	UpdateSignCount(ctx context.Context, id []byte, signCount uint32) error
+	Delete(ctx context.Context, uid uuid.UUID, id []byte) error
+}
+
+// WebAuthnChallengeRepository stores the challenges of WebAuthn
+// ceremonies which are in progress. Each can only be consumed once
+type WebAuthnChallengeRepository interface {
+	SetChallenge(ctx context.Context, key string, challenge string, expiresIn time.Duration) error
+	ConsumeChallenge(ctx context.Context, key string) (string, error)
+}
+
+// IdentityProvider is an external identity provider users can sign in
+// with, using the OAuth 2.0 authorization code flow with PKCE. The nonce
+// is checked by OpenID Connect providers, and ignored by others
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*ExternalIdentity, error)
+}
+
+// UserIdentityRepository stores the links between users and their
+// accounts at identity providers
+type UserIdentityRepository interface {
+	Create(ctx context.Context, i *UserIdentity) error
+	FindByProviderSubject(ctx context.Context, provider string, subject string) (*UserIdentity, error)
+	FindByUser(ctx context.Context, uid uuid.UUID) ([]*UserIdentity, error)
+}
+
+// OAuthStateRepository stores the state of signins at identity
+// providers which are in progress. Each can only be consumed once
+type OAuthStateRepository interface {
+	SetState(ctx context.Context, state string, s *OAuthState, expiresIn time.Duration) error
+	ConsumeState(ctx context.Context, state string) (*OAuthState, error)
+}
+
+// OIDCCodeRepository stores the authorizations of OpenID Connect
+// clients by the hash of their authorization code, until the code is
+// redeemed. Each can only be consumed once
+type OIDCCodeRepository interface {
+	SetAuthorization(ctx context.Context, codeHash string, a *OIDCAuthorization, expiresIn time.Duration) error
+	ConsumeAuthorization(ctx context.Context, codeHash string) (*OIDCAuthorization, error)
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(ctx context.Context, email *Email) error
+}
+
+// EventBroker tells other services about changes to users
+type EventBroker interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// AuditRepository keeps a log of security relevant actions
+type AuditRepository interface {
+	Record(ctx context.Context, entry *AuditEntry) error
+	Find(ctx context.Context, query *AuditQuery) ([]*AuditEntry, error)
+}
+
+// OutboxRepository keeps events in the same transactions as the changes
+// they describe, until they are relayed to an EventBroker. Relay passes
+// up to limit of the oldest events to publish, stopping at the first
+// which fails, and returns how many were published
+type OutboxRepository interface {
+	Add(ctx context.Context, event *Event) error
+	Relay(ctx context.Context, limit int, publish func(ctx context.Context, event *Event) error) (int, error)
+}
+
+// Transactor runs fn in a database transaction, which repositories
+// called with the context fn is given take part in. Transactions
+// started within fn join the outer one
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 // ImageRepository defines methods it expects a repository
 // it interacts with to implement
 type ImageRepository interface {
 	UpdateProfile(ctx context.Context, objName string, imgFile multipart.File) (string, error)
+	GetProfile(ctx context.Context, objName string) ([]byte, error)
+	DeleteProfile(ctx context.Context, objName string) error
 }
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// TOTPEnrollment is a TOTP secret which is yet to be confirmed, along with
+// the otpauth:// URI authenticator apps read from a QR code
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes are the hashes of a user's unused one time recovery codes
+type RecoveryCodes []string
+
+// Value stores recovery codes as a JSON array
+func (r RecoveryCodes) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+
+	b, err := json.Marshal(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan reads recovery codes from a JSON array
+func (r *RecoveryCodes) Scan(src interface{}) error {
+	var data []byte
+
+	switch v := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into RecoveryCodes", src)
+	}
+
+	return json.Unmarshal(data, r)
+}
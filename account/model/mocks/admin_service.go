@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAdminService struct {
+	mock.Mock
+}
+
+func (m *MockAdminService) ListUsers(ctx context.Context, query *model.UserQuery) (*model.UserPage, error) {
+	ret := m.Called(ctx, query)
+
+	var r0 *model.UserPage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.UserPage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockAdminService) SetDisabled(ctx context.Context, uid uuid.UUID, disabled bool) (*model.User, error) {
+	ret := m.Called(ctx, uid, disabled)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockAdminService) AuditLog(ctx context.Context, query *model.AuditQuery) (*model.AuditPage, error) {
+	ret := m.Called(ctx, query)
+
+	var r0 *model.AuditPage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.AuditPage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,39 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Record(ctx context.Context, entry *model.AuditEntry) error {
+	ret := m.Called(ctx, entry)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockAuditRepository) Find(ctx context.Context, query *model.AuditQuery) ([]*model.AuditEntry, error) {
+	ret := m.Called(ctx, query)
+
+	var r0 []*model.AuditEntry
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.AuditEntry)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
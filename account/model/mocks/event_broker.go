@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEventBroker struct {
+	mock.Mock
+}
+
+func (m *MockEventBroker) Publish(ctx context.Context, event *model.Event) error {
+	ret := m.Called(ctx, event)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
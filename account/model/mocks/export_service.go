@@ -0,0 +1,29 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockExportService struct {
+	mock.Mock
+}
+
+func (m *MockExportService) Export(ctx context.Context, uid uuid.UUID) (*model.UserExport, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.UserExport
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.UserExport)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdentityProvider struct {
+	mock.Mock
+}
+
+func (m *MockIdentityProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	ret := m.Called(ctx, state, nonce, codeChallenge)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockIdentityProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*model.ExternalIdentity, error) {
+	ret := m.Called(ctx, code, codeVerifier, nonce)
+
+	var r0 *model.ExternalIdentity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ExternalIdentity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdentityService struct {
+	mock.Mock
+}
+
+func (m *MockIdentityService) AuthCodeURL(ctx context.Context, provider string) (string, string, error) {
+	ret := m.Called(ctx, provider)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 string
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+func (m *MockIdentityService) Signin(ctx context.Context, state string, code string) (*model.User, error) {
+	ret := m.Called(ctx, state, code)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -25,4 +25,31 @@ func (m *MockImageRepository) UpdateProfile(ctx context.Context, objName string,
 	}
 
 	return r0, r1
+}
+
+func (m *MockImageRepository) GetProfile(ctx context.Context, objName string) ([]byte, error) {
+	ret := m.Called(ctx, objName)
+
+	var r0 []byte
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]byte)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	ret := m.Called(ctx, objName)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
 }
\ No newline at end of file
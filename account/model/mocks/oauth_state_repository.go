@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOAuthStateRepository struct {
+	mock.Mock
+}
+
+func (m *MockOAuthStateRepository) SetState(ctx context.Context, state string, s *model.OAuthState, expiresIn time.Duration) error {
+	ret := m.Called(ctx, state, s, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockOAuthStateRepository) ConsumeState(ctx context.Context, state string) (*model.OAuthState, error) {
+	ret := m.Called(ctx, state)
+
+	var r0 *model.OAuthState
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OAuthState)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOIDCCodeRepository struct {
+	mock.Mock
+}
+
+func (m *MockOIDCCodeRepository) SetAuthorization(ctx context.Context, codeHash string, a *model.OIDCAuthorization, expiresIn time.Duration) error {
+	ret := m.Called(ctx, codeHash, a, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockOIDCCodeRepository) ConsumeAuthorization(ctx context.Context, codeHash string) (*model.OIDCAuthorization, error) {
+	ret := m.Called(ctx, codeHash)
+
+	var r0 *model.OIDCAuthorization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OIDCAuthorization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,104 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) Discovery() *model.OIDCDiscovery {
+	ret := m.Called()
+
+	var r0 *model.OIDCDiscovery
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OIDCDiscovery)
+	}
+
+	return r0
+}
+
+func (m *MockOIDCService) StartAuthorization(ctx context.Context, req *model.OIDCAuthorizationRequest) (string, error) {
+	ret := m.Called(ctx, req)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockOIDCService) Authorize(ctx context.Context, uid uuid.UUID, req *model.OIDCAuthorizationRequest) (string, error) {
+	ret := m.Called(ctx, uid, req)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockOIDCService) AuthenticateClient(clientID string, clientSecret string) (*model.OIDCClient, error) {
+	ret := m.Called(clientID, clientSecret)
+
+	var r0 *model.OIDCClient
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OIDCClient)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockOIDCService) ExchangeCode(ctx context.Context, client *model.OIDCClient, code string, redirectURI string, codeVerifier string) (*model.OIDCAuthorization, error) {
+	ret := m.Called(ctx, client, code, redirectURI, codeVerifier)
+
+	var r0 *model.OIDCAuthorization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OIDCAuthorization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockOIDCService) UserInfo(ctx context.Context, access *model.OIDCAccessToken) (*model.OIDCUserInfo, error) {
+	ret := m.Called(ctx, access)
+
+	var r0 *model.OIDCUserInfo
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OIDCUserInfo)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) Add(ctx context.Context, event *model.Event) error {
+	ret := m.Called(ctx, event)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockOutboxRepository) Relay(ctx context.Context, limit int, publish func(ctx context.Context, event *model.Event) error) (int, error) {
+	ret := m.Called(ctx, limit, publish)
+
+	r0 := ret.Int(0)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
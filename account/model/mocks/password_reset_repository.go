@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) SetResetToken(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, tokenHash, userID, expiresIn)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockPasswordResetRepository) ConsumeResetToken(ctx context.Context, tokenHash string) (string, error) {
+	ret := m.Called(ctx, tokenHash)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
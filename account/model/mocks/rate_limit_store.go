@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRateLimitStore struct {
+	mock.Mock
+}
+
+func (m *MockRateLimitStore) TokenBucket(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (*model.RateLimitResult, error) {
+	ret := m.Called(ctx, key, limit, window, now)
+
+	var r0 *model.RateLimitResult
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.RateLimitResult)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockRateLimitStore) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (*model.RateLimitResult, error) {
+	ret := m.Called(ctx, key, limit, window, now)
+
+	var r0 *model.RateLimitResult
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.RateLimitResult)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
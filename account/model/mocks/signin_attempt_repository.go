@@ -0,0 +1,70 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSigninAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockSigninAttemptRepository) IncrementFailures(ctx context.Context, key string, window time.Duration) (int64, error) {
+	ret := m.Called(ctx, key, window)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockSigninAttemptRepository) ResetFailures(ctx context.Context, key string) error {
+	ret := m.Called(ctx, key)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockSigninAttemptRepository) SetLockout(ctx context.Context, key string, d time.Duration) error {
+	ret := m.Called(ctx, key, d)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockSigninAttemptRepository) GetLockout(ctx context.Context, key string) (time.Duration, error) {
+	ret := m.Called(ctx, key)
+
+	var r0 time.Duration
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
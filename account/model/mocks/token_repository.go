@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -11,8 +12,8 @@ type MockTokenRepository struct {
 	mock.Mock
 }
 
-func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
-	ret := m.Called(ctx, userID, tokenID, expiresIn)
+func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, session *model.Session, expiresIn time.Duration) error {
+	ret := m.Called(ctx, userID, tokenID, session, expiresIn)
 
 	var r0 error
 
@@ -23,9 +24,63 @@ func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string
 	return r0
 }
 
-func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error {
+func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) (*model.Session, error) {
 	ret := m.Called(ctx, userID, prevTokenID)
 
+	var r0 *model.Session
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+//FindRotatedRefreshToken mocks concrete FindRotatedRefreshToken
+func (m *MockTokenRepository) FindRotatedRefreshToken(ctx context.Context, userID string, tokenID string) (string, error) {
+	ret := m.Called(ctx, userID, tokenID)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+//GetSessions mocks concrete GetSessions
+func (m *MockTokenRepository) GetSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.Session
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+//DeleteSession mocks concrete DeleteSession
+func (m *MockTokenRepository) DeleteSession(ctx context.Context, userID string, sessionID string) error {
+	ret := m.Called(ctx, userID, sessionID)
+
 	var r0 error
 
 	if ret.Get(0) != nil {
@@ -46,4 +101,4 @@ func (m *MockTokenRepository) DeleateUserRefreshTokens(ctx context.Context, user
 	}
 
 	return r0
-}
\ No newline at end of file
+}
@@ -43,9 +43,52 @@ func (m *MockTokenService) Signout(ctx context.Context, uid uuid.UUID) error {
 	return r0
 }
 
+//GetSessions mocks concrete GetSessions
+func (m *MockTokenService) GetSessions(ctx context.Context, uid uuid.UUID) ([]*model.Session, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Session
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+//RevokeSession mocks concrete RevokeSession
+func (m *MockTokenService) RevokeSession(ctx context.Context, uid uuid.UUID, sessionID string) error {
+	ret := m.Called(ctx, uid, sessionID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+//GetJWKS mocks concrete GetJWKS
+func (m *MockTokenService) GetJWKS() *model.JWKS {
+	ret := m.Called()
+
+	var r0 *model.JWKS
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.JWKS)
+	}
+
+	return r0
+}
+
 //ValidateIDToken mocks concrete ValidateIDToken
-func (m *MockTokenService) ValidateIDToken(tokenString string) (*model.User, error) {
-	ret := m.Called(tokenString)
+func (m *MockTokenService) ValidateIDToken(ctx context.Context, tokenString string) (*model.User, error) {
+	ret := m.Called(ctx, tokenString)
 
 	var r0 *model.User
 	if ret.Get(0) != nil {
@@ -61,8 +104,9 @@ func (m *MockTokenService) ValidateIDToken(tokenString string) (*model.User, err
 	return r0, r1
 }
 
-func(m *MockTokenService) ValiidateRefreshToken(refreshTokenString string) (*model.RefreshToken, error) {
-	ret := m.Called(refreshTokenString)
+//ValidateRefreshToken mocks concrete ValidateRefreshToken
+func (m *MockTokenService) ValidateRefreshToken(ctx context.Context, refreshTokenString string) (*model.RefreshToken, error) {
+	ret := m.Called(ctx, refreshTokenString)
 
 	var r0 *model.RefreshToken
 	if ret.Get(0) != nil {
@@ -77,3 +121,71 @@ func(m *MockTokenService) ValiidateRefreshToken(refreshTokenString string) (*mod
 
 	return r0, r1
 }
+
+//ValidateOIDCAccessToken mocks concrete ValidateOIDCAccessToken
+func (m *MockTokenService) ValidateOIDCAccessToken(ctx context.Context, tokenString string) (*model.OIDCAccessToken, error) {
+	ret := m.Called(ctx, tokenString)
+
+	var r0 *model.OIDCAccessToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OIDCAccessToken)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+//NewOIDCTokens mocks concrete NewOIDCTokens
+func (m *MockTokenService) NewOIDCTokens(ctx context.Context, u *model.User, authorization *model.OIDCAuthorization, prevTokenID string) (*model.OIDCTokens, error) {
+	ret := m.Called(ctx, u, authorization, prevTokenID)
+
+	var r0 *model.OIDCTokens
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OIDCTokens)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+//NewMFAChallenge mocks concrete NewMFAChallenge
+func (m *MockTokenService) NewMFAChallenge(ctx context.Context, u *model.User) (string, error) {
+	ret := m.Called(ctx, u)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+//ValidateMFAChallenge mocks concrete ValidateMFAChallenge
+func (m *MockTokenService) ValidateMFAChallenge(ctx context.Context, tokenString string) (uuid.UUID, error) {
+	ret := m.Called(ctx, tokenString)
+
+	var r0 uuid.UUID
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(uuid.UUID)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
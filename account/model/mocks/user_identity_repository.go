@@ -0,0 +1,56 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserIdentityRepository) Create(ctx context.Context, i *model.UserIdentity) error {
+	ret := m.Called(ctx, i)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserIdentityRepository) FindByProviderSubject(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	ret := m.Called(ctx, provider, subject)
+
+	var r0 *model.UserIdentity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.UserIdentity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserIdentityRepository) FindByUser(ctx context.Context, uid uuid.UUID) ([]*model.UserIdentity, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.UserIdentity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.UserIdentity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
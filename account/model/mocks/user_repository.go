@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jacobsngoodwin/memrizr/account/model"
@@ -69,11 +70,12 @@ func (m *MockUserRepository) Update(ctx context.Context, u *model.User) error {
 }
 
 func (m *MockUserRepository) UpdateImage(
-	ctx.context.Context,
+	ctx context.Context,
 	uid uuid.UUID,
 	imageURL string,
+	thumbnails model.ImageThumbnails,
 ) (*model.User, error) {
-	ret := m.Called(ctx, uid, imageURL)
+	ret := m.Called(ctx, uid, imageURL, thumbnails)
 
 	var r0 *model.User
 	if ret.Get(0) != nil {
@@ -88,3 +90,141 @@ func (m *MockUserRepository) UpdateImage(
 
 	return r0, r1
 }
+
+func (m *MockUserRepository) SetEmailVerified(ctx context.Context, uid uuid.UUID, email string) (*model.User, error) {
+	ret := m.Called(ctx, uid, email)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, uid, password)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserRepository) UpdateTOTP(ctx context.Context, uid uuid.UUID, secret string, enabled bool, recoveryCodes model.RecoveryCodes) error {
+	ret := m.Called(ctx, uid, secret, enabled, recoveryCodes)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, uid uuid.UUID, step int64) error {
+	ret := m.Called(ctx, uid, step)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) error {
+	ret := m.Called(ctx, uid, codeHash)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserRepository) List(ctx context.Context, query *model.UserQuery) ([]*model.User, int, error) {
+	ret := m.Called(ctx, query)
+
+	var r0 []*model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	r1 := ret.Int(1)
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+func (m *MockUserRepository) SetDisabled(ctx context.Context, uid uuid.UUID, disabled bool) (*model.User, error) {
+	ret := m.Called(ctx, uid, disabled)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserRepository) SoftDelete(ctx context.Context, uid uuid.UUID, deletedAt time.Time) error {
+	ret := m.Called(ctx, uid, deletedAt)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserRepository) FindDeleted(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	ret := m.Called(ctx, before, limit)
+
+	var r0 []*model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"mime/multipart"
 
 	"github.com/google/uuid"
 	"github.com/jacobsngoodwin/memrizr/account/model"
@@ -64,7 +65,7 @@ func (m *MockUserService) UpdateDetails(ctx context.Context, u *model.User) erro
 	return r0
 }
 
-func (m *MockUserService) SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader model.ImageFileHeader) (*model.User, error) {
+func (m *MockUserService) SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*model.User, error) {
 	ret := m.Called(ctx, uid, imageFileHeader)
 
 	var r0 *model.User
@@ -78,4 +79,190 @@ func (m *MockUserService) SetProfileImage(ctx context.Context, uid uuid.UUID, im
 	}
 
 	return r0, r1
-}	
\ No newline at end of file
+}
+
+func (m *MockUserService) ClearProfileImage(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) SendVerificationEmail(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) ForgotPassword(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, token string, password string) error {
+	ret := m.Called(ctx, token, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) (*model.User, error) {
+	ret := m.Called(ctx, uid, currentPassword, newPassword)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) EnrollTOTP(ctx context.Context, uid uuid.UUID) (*model.TOTPEnrollment, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.TOTPEnrollment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TOTPEnrollment)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) ([]string, error) {
+	ret := m.Called(ctx, uid, code)
+
+	var r0 []string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) DisableTOTP(ctx context.Context, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, uid, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) VerifyMFA(ctx context.Context, uid uuid.UUID, code string) (*model.User, error) {
+	ret := m.Called(ctx, uid, code)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) Delete(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) DeleteAccount(ctx context.Context, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, uid, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	ret := m.Called(ctx)
+
+	r0 := ret.Int(0)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) GetActivity(ctx context.Context, uid uuid.UUID, limit int, offset int) (*model.AuditPage, error) {
+	ret := m.Called(ctx, uid, limit, offset)
+
+	var r0 *model.AuditPage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.AuditPage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
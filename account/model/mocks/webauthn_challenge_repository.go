@@ -0,0 +1,39 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebAuthnChallengeRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebAuthnChallengeRepository) SetChallenge(ctx context.Context, key string, challenge string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, key, challenge, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockWebAuthnChallengeRepository) ConsumeChallenge(ctx context.Context, key string) (string, error) {
+	ret := m.Called(ctx, key)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,78 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebAuthnCredentialRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebAuthnCredentialRepository) Create(ctx context.Context, c *model.WebAuthnCredential) error {
+	ret := m.Called(ctx, c)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockWebAuthnCredentialRepository) FindByID(ctx context.Context, id []byte) (*model.WebAuthnCredential, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *model.WebAuthnCredential
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.WebAuthnCredential)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockWebAuthnCredentialRepository) FindByUser(ctx context.Context, uid uuid.UUID) ([]*model.WebAuthnCredential, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.WebAuthnCredential
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.WebAuthnCredential)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockWebAuthnCredentialRepository) UpdateSignCount(ctx context.Context, id []byte, signCount uint32) error {
+	ret := m.Called(ctx, id, signCount)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockWebAuthnCredentialRepository) Delete(ctx context.Context, uid uuid.UUID, id []byte) error {
+	ret := m.Called(ctx, uid, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
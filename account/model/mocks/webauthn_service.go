@@ -0,0 +1,104 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebAuthnService struct {
+	mock.Mock
+}
+
+func (m *MockWebAuthnService) BeginRegistration(ctx context.Context, uid uuid.UUID) (*model.WebAuthnCreationOptions, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.WebAuthnCreationOptions
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.WebAuthnCreationOptions)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockWebAuthnService) FinishRegistration(ctx context.Context, uid uuid.UUID, name string, attestation *model.WebAuthnAttestation) (*model.WebAuthnCredential, error) {
+	ret := m.Called(ctx, uid, name, attestation)
+
+	var r0 *model.WebAuthnCredential
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.WebAuthnCredential)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockWebAuthnService) BeginLogin(ctx context.Context) (*model.WebAuthnRequestOptions, error) {
+	ret := m.Called(ctx)
+
+	var r0 *model.WebAuthnRequestOptions
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.WebAuthnRequestOptions)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockWebAuthnService) FinishLogin(ctx context.Context, assertion *model.WebAuthnAssertion) (*model.User, error) {
+	ret := m.Called(ctx, assertion)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockWebAuthnService) GetCredentials(ctx context.Context, uid uuid.UUID) ([]*model.WebAuthnCredential, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.WebAuthnCredential
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.WebAuthnCredential)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockWebAuthnService) DeleteCredential(ctx context.Context, uid uuid.UUID, credentialID []byte) error {
+	ret := m.Called(ctx, uid, credentialID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// OIDCClient is an app which signs users in with the account service,
+// such as another memrizr service. Clients without a secret are public,
+// eg single page apps, and must use PKCE
+type OIDCClient struct {
+	ID           string   `json:"id"`
+	Secret       string   `json:"secret"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirectUris"`
+}
+
+// OIDCAuthorizationRequest is a client's request for a user's
+// authorization, with the parameters of an OpenID Connect
+// authentication request
+type OIDCAuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OIDCAuthorization is what a user authorized a client to do, kept
+// until the client redeems its authorization code
+type OIDCAuthorization struct {
+	ClientID      string    `json:"clientId"`
+	RedirectURI   string    `json:"redirectUri"`
+	UID           uuid.UUID `json:"uid"`
+	Scope         string    `json:"scope"`
+	Nonce         string    `json:"nonce"`
+	CodeChallenge string    `json:"codeChallenge"`
+	AuthTime      int64     `json:"authTime"`
+}
+
+// OIDCTokens is a token endpoint response. The access token is only
+// accepted by the userinfo endpoint, with the scope the user authorized
+type OIDCTokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OIDCAccessToken is a validated access token of an OpenID Connect client
+type OIDCAccessToken struct {
+	UID      uuid.UUID
+	ClientID string
+	Scope    string
+}
+
+// OIDCUserInfo is the userinfo endpoint response, with standard claims
+type OIDCUserInfo struct {
+	Sub           string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Website       string `json:"website,omitempty"`
+}
+
+// OIDCDiscovery is the OpenID Connect discovery document
+type OIDCDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// OAuthError is an error response defined by OAuth 2.0 (RFC 6749), which
+// clients of the OpenID Connect endpoints expect in place of apperrors
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
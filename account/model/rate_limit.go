@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// RateLimitResult is a rate limiter's decision on a single request
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// ResetAfter is how long until the limit is fully replenished
+	ResetAfter time.Duration
+	// RetryAfter is how long a denied client should wait before retrying
+	RetryAfter time.Duration
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// Session is a signed in device. It starts when a user signs in and
+// lives through each refresh token rotation (a refresh token family)
+// until it is revoked or its last refresh token expires. Sessions of
+// OpenID Connect clients record the client and the scope the user
+// authorized it, and their refresh tokens only work for that client
+type Session struct {
+	ID              string    `json:"id"`
+	CreatedAt       time.Time `json:"createdAt"`
+	LastRefreshedAt time.Time `json:"lastRefreshedAt"`
+	UserAgent       string    `json:"userAgent"`
+	IP              string    `json:"ip"`
+	ClientID        string    `json:"clientId,omitempty"`
+	Scope           string    `json:"scope,omitempty"`
+}
+
+// ClientInfo describes the client making the current request
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type clientInfoKey struct{}
+
+// ContextWithClientInfo returns a copy of ctx carrying ci
+func ContextWithClientInfo(ctx context.Context, ci ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, ci)
+}
+
+// ClientInfoFromContext returns the ClientInfo stored in ctx, if any
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	ci, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return ci
+}
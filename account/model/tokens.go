@@ -2,10 +2,14 @@ package model
 
 import "github.com/google/uuid"
 
+// RefreshToken is a validated refresh token. Those issued to OpenID
+// Connect clients carry the client's ID and the scope it was authorized
 type RefreshToken struct {
 	ID 	uuid.UUID 	`json:"-"`
 	UID uuid.UUID 	`json:"-"`
 	SS 	string 		`json:"refreshToken"`
+	ClientID string `json:"-"`
+	Scope 	string 	`json:"-"`
 }
 
 type IDToken struct {
@@ -16,3 +20,18 @@ type TokenPair struct {
 	IDToken
 	RefreshToken
 }
+
+// JWK is an RSA public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is the set of keys ID tokens may be verified with
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
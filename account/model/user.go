@@ -1,14 +1,90 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type User struct {
 	UID			uuid.UUID 	`db:"uid" json:"uid"`
 	Email 		string 		`db:"email" json:"emil"`
+	EmailVerified bool 		`db:"email_verified" json:"emailVerified"`
 	Password	string		`db:"password" json:"-"`
 	Name		string		`db:"name" json:"name"`
 	ImageURL	string 		`db:"image_url" json:"imageUrl"`
+	Thumbnails	ImageThumbnails `db:"image_thumbnails" json:"thumbnails"`
 	Website		string		`db:"website" json:"website"`
+	TOTPSecret	string		`db:"totp_secret" json:"-"`
+	TOTPEnabled	bool		`db:"totp_enabled" json:"totpEnabled"`
+	TOTPLastStep int64		`db:"totp_last_step" json:"-"`
+	RecoveryCodes RecoveryCodes `db:"recovery_codes" json:"-"`
+	Role		string		`db:"role" json:"role"`
+	Disabled	bool		`db:"disabled" json:"disabled"`
+	// set while an account waits out its deletion grace period
+	DeletedAt	*time.Time	`db:"deleted_at" json:"deletedAt,omitempty"`
+}
+
+// Roles a user can have. The role is included in ID tokens, so a
+// change applies once the user's tokens are refreshed
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// UserQuery filters and pages through users. Search matches part of
+// a user's email or name, ignoring case
+type UserQuery struct {
+	Search string
+	Limit  int
+	Offset int
+}
+
+// UserPage is a page of users, along with how many match in all
+type UserPage struct {
+	Users  []*User `json:"users"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// ImageThumbnails maps a thumbnail's edge length in pixels
+// (eg, "128") to the URL it is served from
+type ImageThumbnails map[string]string
+
+// Value stores thumbnails as a JSON object
+func (t ImageThumbnails) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan reads thumbnails from a JSON object
+func (t *ImageThumbnails) Scan(src interface{}) error {
+	var data []byte
+
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ImageThumbnails", src)
+	}
+
+	return json.Unmarshal(data, t)
 }
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Base64URL is binary data which WebAuthn clients exchange as
+// unpadded base64url strings
+type Base64URL []byte
+
+// MarshalJSON encodes b as an unpadded base64url string
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes a base64url string, padded or not
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+	return nil
+}
+
+// String is the base64url encoding of b
+func (b Base64URL) String() string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// WebAuthnCredential is a passkey registered to a user. PublicKey
+// is the COSE encoded key assertions are verified with
+type WebAuthnCredential struct {
+	ID         Base64URL  `db:"id" json:"id"`
+	UID        uuid.UUID  `db:"uid" json:"-"`
+	PublicKey  []byte     `db:"public_key" json:"-"`
+	SignCount  uint32     `db:"sign_count" json:"-"`
+	Name       string     `db:"name" json:"name"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"lastUsedAt"`
+}
+
+// WebAuthnRelyingParty identifies this service to authenticators
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// WebAuthnUser identifies the user a credential is created for.
+// ID is the user handle returned with assertions
+type WebAuthnUser struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+// WebAuthnCredentialParam is a public key algorithm this service accepts
+type WebAuthnCredentialParam struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// WebAuthnCredentialDescriptor refers to an existing credential
+type WebAuthnCredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+}
+
+// WebAuthnAuthenticatorSelection states which authenticators may be used
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// WebAuthnCreationOptions are passed to navigator.credentials.create
+// to register a passkey
+type WebAuthnCreationOptions struct {
+	Challenge              Base64URL                      `json:"challenge"`
+	RP                     WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUser                   `json:"user"`
+	PubKeyCredParams       []WebAuthnCredentialParam      `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	Attestation            string                         `json:"attestation"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+// WebAuthnRequestOptions are passed to navigator.credentials.get
+// to sign in with a passkey
+type WebAuthnRequestOptions struct {
+	Challenge        Base64URL                      `json:"challenge"`
+	RPID             string                         `json:"rpId"`
+	Timeout          int64                          `json:"timeout"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+// WebAuthnAttestation is the credential returned by
+// navigator.credentials.create
+type WebAuthnAttestation struct {
+	ID       Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AttestationObject Base64URL `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// WebAuthnAssertion is the credential returned by
+// navigator.credentials.get
+type WebAuthnAssertion struct {
+	ID       Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AuthenticatorData Base64URL `json:"authenticatorData"`
+		Signature         Base64URL `json:"signature"`
+		UserHandle        Base64URL `json:"userHandle"`
+	} `json:"response"`
+}
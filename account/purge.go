@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// purgeDeletedAccounts removes accounts whose deletion grace period is
+// over every interval, until ctx is cancelled
+func purgeDeletedAccounts(ctx context.Context, userService model.UserService, interval time.Duration, logger model.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, interval)
+		purged, err := userService.PurgeDeletedAccounts(runCtx)
+		cancel()
+
+		if err != nil {
+			logger.Error(ctx, "Failed to purge deleted accounts", "err", err)
+			continue
+		}
+
+		if purged > 0 {
+			logger.Info(ctx, "Purged deleted accounts", "purged", purged)
+		}
+	}
+}
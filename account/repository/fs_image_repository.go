@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// fsImageRepository stores images on the local filesystem. It is meant
+// for development and tests, where no cloud storage credentials exist.
+// Files written to Dir are expected to be served from URLPrefix
+type fsImageRepository struct {
+	Dir       string
+	URLPrefix string
+	Logger    model.Logger
+}
+
+// NewFSImageRepository is a factory for initializing an image repository
+// which writes objects under dir
+func NewFSImageRepository(dir string, urlPrefix string, logger model.Logger) model.ImageRepository {
+	return &fsImageRepository{
+		Dir:       dir,
+		URLPrefix: urlPrefix,
+		Logger:    logging.OrDefault(logger),
+	}
+}
+
+func (r *fsImageRepository) UpdateProfile(
+	ctx context.Context,
+	objName string,
+	imgFile multipart.File,
+) (string, error) {
+	objPath, err := r.objPath(ctx, objName)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		r.Logger.Error(ctx, "Unable to create image directory", "dir", r.Dir, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	f, err := os.Create(objPath)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to create image file", "objName", objName, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	defer f.Close()
+
+	if _, err := io.Copy(f, imgFile); err != nil {
+		r.Logger.Error(ctx, "Unable to write image file", "objName", objName, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	imageURL := fmt.Sprintf("%s/%s", r.URLPrefix, objName)
+
+	return imageURL, nil
+}
+
+func (r *fsImageRepository) GetProfile(ctx context.Context, objName string) ([]byte, error) {
+	objPath, err := r.objPath(ctx, objName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(objPath)
+
+	if os.IsNotExist(err) {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to read image file", "path", objPath, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return data, nil
+}
+
+func (r *fsImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	objPath, err := r.objPath(ctx, objName)
+
+	if err != nil {
+		return err
+	}
+
+	// an already missing file is as good as a deleted one
+	if err := os.Remove(objPath); err != nil && !os.IsNotExist(err) {
+		r.Logger.Error(ctx, "Unable to delete image file", "path", objPath, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// objPath makes sure objName cannot escape the image directory
+func (r *fsImageRepository) objPath(ctx context.Context, objName string) (string, error) {
+	if objName == "" || objName != filepath.Base(objName) || objName == "." || objName == ".." {
+		r.Logger.Warn(ctx, "Invalid image object name", "objName", objName)
+		return "", apperrors.NewBadRequest("invalid image name")
+	}
+
+	return filepath.Join(r.Dir, objName), nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFSImageRepository(t *testing.T) {
+	dir := t.TempDir()
+	r := NewFSImageRepository(dir, "/api/account/images", logging.Nop())
+
+	ctx := context.TODO()
+
+	t.Run("Update and delete profile", func(t *testing.T) {
+		src, err := os.CreateTemp(t.TempDir(), "upload")
+		assert.NoError(t, err)
+		_, err = src.WriteString("imagebytes")
+		assert.NoError(t, err)
+		_, err = src.Seek(0, 0)
+		assert.NoError(t, err)
+		defer src.Close()
+
+		imageURL, err := r.UpdateProfile(ctx, "someobject", src)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/api/account/images/someobject", imageURL)
+
+		written, err := os.ReadFile(filepath.Join(dir, "someobject"))
+		assert.NoError(t, err)
+		assert.Equal(t, "imagebytes", string(written))
+
+		read, err := r.GetProfile(ctx, "someobject")
+		assert.NoError(t, err)
+		assert.Equal(t, "imagebytes", string(read))
+
+		err = r.DeleteProfile(ctx, "someobject")
+		assert.NoError(t, err)
+
+		_, err = os.Stat(filepath.Join(dir, "someobject"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Get missing object", func(t *testing.T) {
+		_, err := r.GetProfile(ctx, "doesnotexist")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+
+	t.Run("Delete missing object", func(t *testing.T) {
+		err := r.DeleteProfile(ctx, "doesnotexist")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Object name outside of directory", func(t *testing.T) {
+		err := r.DeleteProfile(ctx, "../escape")
+		assert.Error(t, err)
+	})
+}
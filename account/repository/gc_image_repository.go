@@ -4,10 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"io/ioutil"
 	"mime/multipart"
 
 	"cloud.google.com/go/storage"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
 	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
 )
@@ -15,12 +16,14 @@ import (
 type gcImageRepository struct {
 	Storage *storage.Client
 	BucketName string
+	Logger model.Logger
 }
 
-func NewImageRepository(gcClient * storage.Client, bucketName string) model.ImageRepository {
+func NewImageRepository(gcClient * storage.Client, bucketName string, logger model.Logger) model.ImageRepository {
 	return &gcImageRepository{
 		Storage: gcClient,
 		BucketName: bucketName,
+		Logger: logging.OrDefault(logger),
 	}
 }
 
@@ -37,7 +40,7 @@ func (r *gcImageRepository) UpdateProfile(
 	wc.ObjectAttrs.CacheControl = "Cache-Control:no-cache,max-age=0"
 
 	if _, err := io.Copy(wc, imgFile); err != nil {
-		log.Printf("Unable to write file to Google Cloud Storage: %v\n", err)
+		r.Logger.Error(ctx, "Unable to write file to Google Cloud Storage", "objName", objName, "err", err)
 		return "", apperrors.NewInternal()
 	}
 
@@ -52,4 +55,44 @@ func (r *gcImageRepository) UpdateProfile(
 	)
 
 	return imageURL, nil
+}
+
+func (r *gcImageRepository) GetProfile(ctx context.Context, objName string) ([]byte, error) {
+	bckt := r.Storage.Bucket(r.BucketName)
+
+	rc, err := bckt.Object(objName).NewReader(ctx)
+
+	if err == storage.ErrObjectNotExist {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to read image object from Google Cloud Storage", "objName", objName, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to read image object from Google Cloud Storage", "objName", objName, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return data, nil
+}
+
+func (r *gcImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	bckt := r.Storage.Bucket(r.BucketName)
+
+	object := bckt.Object(objName)
+
+	// an already missing object is as good as a deleted one
+	if err := object.Delete(ctx); err != nil && err != storage.ErrObjectNotExist {
+		r.Logger.Error(ctx, "Unable to delete image object from Google Cloud Storage", "objName", objName, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// how often expired keys are swept from memory
+const memoryRateLimitSweepInterval = time.Minute
+
+type tokenBucketState struct {
+	Tokens    float64
+	UpdatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type slidingWindowState struct {
+	Requests  []time.Time
+	ExpiresAt time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucketState
+	windows   map[string]*slidingWindowState
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore is a factory for initializing a rate limit
+// store held in process memory. Limits are not shared between instances,
+// so it's intended for tests and single instance development
+func NewMemoryRateLimitStore() model.RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*tokenBucketState),
+		windows: make(map[string]*slidingWindowState),
+	}
+}
+
+func (s *memoryRateLimitStore) TokenBucket(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (*model.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucketState{Tokens: float64(limit), UpdatedAt: now}
+		s.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit), b.Tokens+float64(elapsed)*float64(limit)/float64(window))
+		b.UpdatedAt = now
+	}
+
+	allowed := b.Tokens >= 1
+	if allowed {
+		b.Tokens--
+	}
+
+	b.ExpiresAt = now.Add(window)
+
+	return tokenBucketResult(allowed, b.Tokens, limit, window), nil
+}
+
+func (s *memoryRateLimitStore) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (*model.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &slidingWindowState{}
+		s.windows[key] = w
+	}
+
+	// drop requests which have left the window
+	start := now.Add(-window)
+	i := 0
+	for i < len(w.Requests) && !w.Requests[i].After(start) {
+		i++
+	}
+	w.Requests = w.Requests[i:]
+
+	allowed := int64(len(w.Requests)) < limit
+	if allowed {
+		w.Requests = append(w.Requests, now)
+	}
+
+	w.ExpiresAt = now.Add(window)
+
+	oldest := now
+	if len(w.Requests) > 0 {
+		oldest = w.Requests[0]
+	}
+
+	return slidingWindowResult(allowed, int64(len(w.Requests)), oldest, limit, window, now), nil
+}
+
+// sweep removes keys which haven't been used for their whole window,
+// so memory isn't held for every client ever seen
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memoryRateLimitSweepInterval {
+		return
+	}
+
+	for k, b := range s.buckets {
+		if now.After(b.ExpiresAt) {
+			delete(s.buckets, k)
+		}
+	}
+
+	for k, w := range s.windows {
+		if now.After(w.ExpiresAt) {
+			delete(s.windows, k)
+		}
+	}
+
+	s.lastSweep = now
+}
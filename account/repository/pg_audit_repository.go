@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jmoiron/sqlx"
+)
+
+type pgAuditRepository struct {
+	DB     *sqlx.DB
+	Logger model.Logger
+}
+
+// NewAuditRepository is a factory for initializing a repository which
+// keeps the audit log in Postgres
+func NewAuditRepository(db *sqlx.DB, logger model.Logger) model.AuditRepository {
+	return &pgAuditRepository{
+		DB:     db,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+// Record stores entry, setting its ID and, unless given, its time
+func (r *pgAuditRepository) Record(ctx context.Context, entry *model.AuditEntry) error {
+	query := `
+		INSERT INTO audit_entries (uid, action, outcome, target_uid, ip, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, now())) RETURNING *
+	`
+
+	var createdAt sql.NullTime
+	if !entry.CreatedAt.IsZero() {
+		createdAt = sql.NullTime{Time: entry.CreatedAt, Valid: true}
+	}
+
+	if err := r.DB.GetContext(ctx, entry, query, entry.UID, entry.Action, entry.Outcome, entry.TargetUID, entry.IP, entry.UserAgent, createdAt); err != nil {
+		r.Logger.Error(ctx, "Could not record audit entry", "action", entry.Action, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgAuditRepository) Find(ctx context.Context, query *model.AuditQuery) ([]*model.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+
+	where := func(column string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if query.UID != nil {
+		where("uid", *query.UID)
+	}
+
+	if query.TargetUID != nil {
+		where("target_uid", *query.TargetUID)
+	}
+
+	if query.Action != "" {
+		where("action", query.Action)
+	}
+
+	if query.Outcome != "" {
+		where("outcome", query.Outcome)
+	}
+
+	if query.IP != "" {
+		where("ip", query.IP)
+	}
+
+	findQuery := "SELECT * FROM audit_entries"
+
+	if len(conditions) > 0 {
+		findQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// a NULL limit is no limit
+	limit := sql.NullInt64{Int64: int64(query.Limit), Valid: query.Limit > 0}
+	args = append(args, limit, query.Offset)
+	findQuery += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	entries := []*model.AuditEntry{}
+
+	if err := r.DB.SelectContext(ctx, &entries, findQuery, args...); err != nil {
+		r.Logger.Error(ctx, "Error finding audit entries in database", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jmoiron/sqlx"
+)
+
+type pgOutboxRepository struct {
+	DB     *sqlx.DB
+	Logger model.Logger
+}
+
+// NewOutboxRepository is a factory for initializing a repository which
+// keeps events in the Postgres outbox_events table until relayed
+func NewOutboxRepository(db *sqlx.DB, logger model.Logger) model.OutboxRepository {
+	return &pgOutboxRepository{
+		DB:     db,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+type outboxRow struct {
+	ID      uuid.UUID `db:"id"`
+	Payload []byte    `db:"payload"`
+}
+
+// Add stores event in the transaction of ctx, if any
+func (r *pgOutboxRepository) Add(ctx context.Context, event *model.Event) error {
+	payload, err := json.Marshal(event)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to marshal event", "type", event.Type, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	query := "INSERT INTO outbox_events (id, type, payload, created_at) VALUES ($1, $2, $3, $4)"
+
+	if _, err := dbFromContext(ctx, r.DB).ExecContext(ctx, query, event.ID, event.Type, payload, event.OccurredAt); err != nil {
+		r.Logger.Error(ctx, "Could not add event to outbox", "type", event.Type, "uid", event.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Relay locks the events it reads, skipping those already locked, so
+// several instances can relay at once without sending an event twice.
+// Published events are removed from the outbox
+func (r *pgOutboxRepository) Relay(ctx context.Context, limit int, publish func(ctx context.Context, event *model.Event) error) (int, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to begin outbox transaction", "err", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	defer tx.Rollback()
+
+	query := "SELECT id, payload FROM outbox_events ORDER BY created_at LIMIT $1 FOR UPDATE SKIP LOCKED"
+
+	rows := []outboxRow{}
+
+	if err := tx.SelectContext(ctx, &rows, query, limit); err != nil {
+		r.Logger.Error(ctx, "Error reading outbox events from database", "err", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	relayed := 0
+	var publishErr error
+
+	for _, row := range rows {
+		event := &model.Event{}
+
+		// a payload which can't be read can never be published, so it's dropped
+		if err := json.Unmarshal(row.Payload, event); err != nil {
+			r.Logger.Error(ctx, "Dropping unreadable outbox event", "id", row.ID, "err", err)
+		} else if publishErr = publish(ctx, event); publishErr != nil {
+			break
+		} else {
+			relayed++
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM outbox_events WHERE id = $1", row.ID); err != nil {
+			r.Logger.Error(ctx, "Error removing relayed outbox event", "id", row.ID, "err", err)
+			return 0, apperrors.NewInternal()
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.Logger.Error(ctx, "Unable to commit outbox transaction", "err", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return relayed, publishErr
+}
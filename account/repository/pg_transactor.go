@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jmoiron/sqlx"
+)
+
+type txKey struct{}
+
+// dbtx is the part of sqlx used by the repositories, which both
+// *sqlx.DB and *sqlx.Tx provide
+type dbtx interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
+}
+
+// dbFromContext returns the transaction ctx was given by a Transactor,
+// if any, or else db
+func dbFromContext(ctx context.Context, db *sqlx.DB) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+type pgTransactor struct {
+	DB     *sqlx.DB
+	Logger model.Logger
+}
+
+// NewTransactor is a factory for initializing a Transactor for
+// the Postgres repositories sharing db
+func NewTransactor(db *sqlx.DB, logger model.Logger) model.Transactor {
+	return &pgTransactor{
+		DB:     db,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+func (t *pgTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.DB.BeginTxx(ctx, nil)
+
+	if err != nil {
+		t.Logger.Error(ctx, "Unable to begin transaction", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if err := tx.Rollback(); err != nil {
+			t.Logger.Error(ctx, "Unable to roll back transaction", "err", err)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Logger.Error(ctx, "Unable to commit transaction", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
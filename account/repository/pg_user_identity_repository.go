@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type pgUserIdentityRepository struct {
+	DB     *sqlx.DB
+	Logger model.Logger
+}
+
+// NewUserIdentityRepository is a factory for initializing a repository
+// which keeps users' links to identity providers in Postgres
+func NewUserIdentityRepository(db *sqlx.DB, logger model.Logger) model.UserIdentityRepository {
+	return &pgUserIdentityRepository{
+		DB:     db,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+func (r *pgUserIdentityRepository) Create(ctx context.Context, i *model.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (provider, subject, uid, email)
+		VALUES ($1, $2, $3, $4) RETURNING *
+	`
+
+	if err := r.DB.GetContext(ctx, i, query, i.Provider, i.Subject, i.UID, i.Email); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			r.Logger.Info(ctx, "Could not link identity", "provider", i.Provider, "uid", i.UID, "reason", err.Code.Name())
+			return apperrors.NewConflict("identity", i.Provider)
+		}
+
+		r.Logger.Error(ctx, "Could not link identity", "provider", i.Provider, "uid", i.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgUserIdentityRepository) FindByProviderSubject(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	i := &model.UserIdentity{}
+
+	query := "SELECT * FROM user_identities WHERE provider=$1 AND subject=$2"
+
+	if err := r.DB.GetContext(ctx, i, query, provider, subject); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("identity", provider)
+		}
+
+		r.Logger.Error(ctx, "Error finding user identity in database", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return i, nil
+}
+
+// FindByUser returns the identities linked to a user, oldest first
+func (r *pgUserIdentityRepository) FindByUser(ctx context.Context, uid uuid.UUID) ([]*model.UserIdentity, error) {
+	identities := []*model.UserIdentity{}
+
+	query := "SELECT * FROM user_identities WHERE uid=$1 ORDER BY created_at"
+
+	if err := r.DB.SelectContext(ctx, &identities, query, uid); err != nil {
+		r.Logger.Error(ctx, "Error finding user identities in database", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return identities, nil
+}
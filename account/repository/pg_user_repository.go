@@ -2,9 +2,13 @@ package repository
 
 import (
 	"context"
-	"log"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
 	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
 	"github.com/jmoiron/sqlx"
@@ -12,25 +16,32 @@ import (
 )
 
 type pgUserRepository struct {
-	DB *sqlx.DB
+	DB     *sqlx.DB
+	Logger model.Logger
 }
 
-func NewUserRepository(db *sqlx.DB) model.UserRepository {
+func NewUserRepository(db *sqlx.DB, logger model.Logger) model.UserRepository {
 	return &pgUserRepository {
-		DB: db,
+		DB:     db,
+		Logger: logging.OrDefault(logger),
 	}
 }
 
+// db takes part in the transaction of ctx, if any
+func (r *pgUserRepository) db(ctx context.Context) dbtx {
+	return dbFromContext(ctx, r.DB)
+}
+
 func (r *pgUserRepository) Create(ctx context.Context, u *model.User) error {
 	query := "INSERT INTO users (email, password) VALUES ($1, $2) RETURNING *"
 
-	if err := r.DB.GetContext(ctx, u, query, u.Email, u.Password); err != nil {
+	if err := r.db(ctx).GetContext(ctx, u, query, u.Email, u.Password); err != nil {
 		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
-			log.Printf("could not create a user with email: %v. Reason: %v\n", u.Email, err.Code.Name())
+			r.Logger.Info(ctx, "Could not create a user", "email", u.Email, "reason", err.Code.Name())
 			return apperrors.NewConflict("email", u.Email)
 		}
 
-		log.Printf("Could not create a user with email: %v. Reason: %v\n", u.Email, err)
+		r.Logger.Error(ctx, "Could not create a user", "email", u.Email, "err", err)
 		return apperrors.NewInternal()
 	}
 	return nil
@@ -41,7 +52,7 @@ func (r *pgUserRepository) FindByID(ctx context.Context, uid uuid.UUID) (*model.
 
 	query := "SELECT * FROM users WHERE uid=$1"
 
-	if err := r.DB.GetContext(ctx, user, query, uid); err != nil {
+	if err := r.db(ctx).GetContext(ctx, user, query, uid); err != nil {
 		return user, apperrors.NewNotFound("uid", uid.String())
 	}
 
@@ -53,29 +64,264 @@ func (r *pgUserRepository) FindByEmail(ctx context.Context, email string) (*mode
 
 	query := "SELECT * FROM users WHERE email=$1"
 
-	if err := r.DB.GetContext(ctx, user, query, email); err != nil {
+	if err := r.db(ctx).GetContext(ctx, user, query, email); err != nil {
 		return user, apperrors.NewNotFound("email", email)
 	}
 
 	return user, nil
 }
 
-func (r *pgUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*model.User, error) {
+func (r *pgUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string, thumbnails model.ImageThumbnails) (*model.User, error) {
 	query := `
 		UPDATE users
-		SET image_url = $2
+		SET image_url = $2, image_thumbnails = $3
 		WHERE uid = $1
 		RETURNING *;
 	`
 
 	u := &model.User{}
 
-	err := r.DB.GetContext(ctx, u, query, uid, imageURL)
+	err := r.db(ctx).GetContext(ctx, u, query, uid, imageURL, thumbnails)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Error updating image_url in database", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return u, nil
+}
+
+// Update sets a user's details. Changing the email address
+// means the new address has to be verified again
+func (r *pgUserRepository) Update(ctx context.Context, u *model.User) error {
+	query := `
+		UPDATE users
+		SET name=:name, email=:email, website=:website,
+			email_verified = email_verified AND email = :email
+		WHERE uid=:uid
+		RETURNING *;
+	`
+
+	nstmt, err := r.db(ctx).PrepareNamedContext(ctx, query)
 
 	if err != nil {
-		log.Printf("Error updating image_url in database: %v\n", err)
+		r.Logger.Error(ctx, "Unable to prepare user update query", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	defer nstmt.Close()
+
+	if err := nstmt.GetContext(ctx, u, u); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			r.Logger.Info(ctx, "Could not update details for user", "uid", u.UID, "email", u.Email, "reason", err.Code.Name())
+			return apperrors.NewConflict("email", u.Email)
+		}
+
+		r.Logger.Error(ctx, "Could not update details for user", "uid", u.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// SetEmailVerified marks a user's email verified, provided it is
+// still the email which was sent the verification
+func (r *pgUserRepository) SetEmailVerified(ctx context.Context, uid uuid.UUID, email string) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET email_verified = TRUE
+		WHERE uid = $1 AND email = $2
+		RETURNING *;
+	`
+
+	u := &model.User{}
+
+	if err := r.db(ctx).GetContext(ctx, u, query, uid, email); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("email", email)
+		}
+
+		r.Logger.Error(ctx, "Error setting email_verified in database", "uid", uid, "err", err)
 		return nil, apperrors.NewInternal()
 	}
 
 	return u, nil
 }
+
+// UpdatePassword sets a user's (already hashed) password
+func (r *pgUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	query := "UPDATE users SET password = $2 WHERE uid = $1"
+
+	res, err := r.db(ctx).ExecContext(ctx, query, uid, password)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Error updating password in database", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// UpdateTOTP sets a user's TOTP secret, whether two-factor authentication
+// is enabled, and the hashes of their recovery codes
+func (r *pgUserRepository) UpdateTOTP(ctx context.Context, uid uuid.UUID, secret string, enabled bool, recoveryCodes model.RecoveryCodes) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $2, totp_enabled = $3, recovery_codes = $4
+		WHERE uid = $1
+	`
+
+	res, err := r.db(ctx).ExecContext(ctx, query, uid, secret, enabled, recoveryCodes)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Error updating totp in database", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// UseTOTPStep records the time step of a user's last accepted TOTP code.
+// Steps only move forward, so each code can be used just once
+func (r *pgUserRepository) UseTOTPStep(ctx context.Context, uid uuid.UUID, step int64) error {
+	query := "UPDATE users SET totp_last_step = $2 WHERE uid = $1 AND totp_last_step < $2"
+
+	res, err := r.db(ctx).ExecContext(ctx, query, uid, step)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Error updating totp_last_step in database", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("totp_step", strconv.FormatInt(step, 10))
+	}
+
+	return nil
+}
+
+// UseRecoveryCode removes a recovery code from a user's unused codes
+func (r *pgUserRepository) UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) error {
+	query := `
+		UPDATE users
+		SET recovery_codes = recovery_codes - $2::text
+		WHERE uid = $1 AND recovery_codes @> jsonb_build_array($2::text)
+	`
+
+	res, err := r.db(ctx).ExecContext(ctx, query, uid, codeHash)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Error updating recovery_codes in database", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("recovery_code", codeHash)
+	}
+
+	return nil
+}
+
+// List returns a page of the users matching query, ordered by email,
+// along with how many match in all
+func (r *pgUserRepository) List(ctx context.Context, query *model.UserQuery) ([]*model.User, int, error) {
+	// match the search literally, rather than as a LIKE pattern
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query.Search) + "%"
+
+	where := "WHERE email ILIKE $1 OR name ILIKE $1"
+
+	var total int
+
+	if err := r.db(ctx).GetContext(ctx, &total, "SELECT count(*) FROM users "+where, pattern); err != nil {
+		r.Logger.Error(ctx, "Error counting users in database", "err", err)
+		return nil, 0, apperrors.NewInternal()
+	}
+
+	users := []*model.User{}
+
+	listQuery := "SELECT * FROM users " + where + " ORDER BY email, uid LIMIT $2 OFFSET $3"
+
+	if err := r.db(ctx).SelectContext(ctx, &users, listQuery, pattern, query.Limit, query.Offset); err != nil {
+		r.Logger.Error(ctx, "Error listing users in database", "err", err)
+		return nil, 0, apperrors.NewInternal()
+	}
+
+	return users, total, nil
+}
+
+// SetDisabled disables or enables a user's account
+func (r *pgUserRepository) SetDisabled(ctx context.Context, uid uuid.UUID, disabled bool) (*model.User, error) {
+	query := "UPDATE users SET disabled = $2 WHERE uid = $1 RETURNING *"
+
+	u := &model.User{}
+
+	if err := r.db(ctx).GetContext(ctx, u, query, uid, disabled); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("uid", uid.String())
+		}
+
+		r.Logger.Error(ctx, "Error updating disabled in database", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return u, nil
+}
+
+// Delete removes a user. Their passkeys and linked identities are
+// removed with them
+func (r *pgUserRepository) Delete(ctx context.Context, uid uuid.UUID) error {
+	res, err := r.db(ctx).ExecContext(ctx, "DELETE FROM users WHERE uid = $1", uid)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Error deleting user from database", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// SoftDelete marks a user's account as deleted, to be removed
+// for good once its grace period is over
+func (r *pgUserRepository) SoftDelete(ctx context.Context, uid uuid.UUID, deletedAt time.Time) error {
+	query := "UPDATE users SET deleted_at = $2 WHERE uid = $1 AND deleted_at IS NULL"
+
+	res, err := r.db(ctx).ExecContext(ctx, query, uid, deletedAt)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Error marking user deleted in database", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// FindDeleted returns up to limit users marked deleted before the given
+// time, longest deleted first
+func (r *pgUserRepository) FindDeleted(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	query := "SELECT * FROM users WHERE deleted_at <= $1 ORDER BY deleted_at LIMIT $2"
+
+	users := []*model.User{}
+
+	if err := r.db(ctx).SelectContext(ctx, &users, query, before, limit); err != nil {
+		r.Logger.Error(ctx, "Error finding deleted users in database", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
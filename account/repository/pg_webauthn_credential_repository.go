@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type pgWebAuthnCredentialRepository struct {
+	DB     *sqlx.DB
+	Logger model.Logger
+}
+
+// NewWebAuthnCredentialRepository is a factory for initializing a
+// repository which keeps passkeys in Postgres
+func NewWebAuthnCredentialRepository(db *sqlx.DB, logger model.Logger) model.WebAuthnCredentialRepository {
+	return &pgWebAuthnCredentialRepository{
+		DB:     db,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+func (r *pgWebAuthnCredentialRepository) Create(ctx context.Context, c *model.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials (id, uid, public_key, sign_count, name, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	if _, err := r.DB.ExecContext(ctx, query, []byte(c.ID), c.UID, c.PublicKey, c.SignCount, c.Name, c.CreatedAt); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			r.Logger.Info(ctx, "Could not create a webauthn credential", "uid", c.UID, "reason", err.Code.Name())
+			return apperrors.NewConflict("credential", c.ID.String())
+		}
+
+		r.Logger.Error(ctx, "Could not create a webauthn credential", "uid", c.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgWebAuthnCredentialRepository) FindByID(ctx context.Context, id []byte) (*model.WebAuthnCredential, error) {
+	c := &model.WebAuthnCredential{}
+
+	query := "SELECT * FROM webauthn_credentials WHERE id=$1"
+
+	if err := r.DB.GetContext(ctx, c, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("credential", base64.RawURLEncoding.EncodeToString(id))
+		}
+
+		r.Logger.Error(ctx, "Error finding webauthn credential in database", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return c, nil
+}
+
+func (r *pgWebAuthnCredentialRepository) FindByUser(ctx context.Context, uid uuid.UUID) ([]*model.WebAuthnCredential, error) {
+	creds := []*model.WebAuthnCredential{}
+
+	query := "SELECT * FROM webauthn_credentials WHERE uid=$1 ORDER BY created_at"
+
+	if err := r.DB.SelectContext(ctx, &creds, query, uid); err != nil {
+		r.Logger.Error(ctx, "Error finding webauthn credentials in database", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return creds, nil
+}
+
+// UpdateSignCount records a credential's latest signature counter
+// along with when it was used
+func (r *pgWebAuthnCredentialRepository) UpdateSignCount(ctx context.Context, id []byte, signCount uint32) error {
+	query := "UPDATE webauthn_credentials SET sign_count = $2, last_used_at = now() WHERE id = $1"
+
+	if _, err := r.DB.ExecContext(ctx, query, id, signCount); err != nil {
+		r.Logger.Error(ctx, "Error updating webauthn credential sign_count in database", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Delete removes a credential, provided it belongs to uid
+func (r *pgWebAuthnCredentialRepository) Delete(ctx context.Context, uid uuid.UUID, id []byte) error {
+	query := "DELETE FROM webauthn_credentials WHERE uid = $1 AND id = $2"
+
+	res, err := r.DB.ExecContext(ctx, query, uid, id)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Error deleting webauthn credential from database", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NewNotFound("credential", base64.RawURLEncoding.EncodeToString(id))
+	}
+
+	return nil
+}
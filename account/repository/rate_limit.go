@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"math"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// tokenBucketResult describes a bucket left holding tokens after a
+// request, which was allowed if a token could be taken
+func tokenBucketResult(allowed bool, tokens float64, limit int64, window time.Duration) *model.RateLimitResult {
+	perToken := float64(window) / float64(limit)
+
+	res := &model.RateLimitResult{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  int64(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(limit) - tokens) * perToken),
+	}
+
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+
+	return res
+}
+
+// slidingWindowResult describes a window holding count requests after a
+// request, the oldest of which was made at oldest
+func slidingWindowResult(allowed bool, count int64, oldest time.Time, limit int64, window time.Duration, now time.Time) *model.RateLimitResult {
+	// the oldest request leaving the window frees up a slot
+	untilFree := oldest.Add(window).Sub(now)
+
+	res := &model.RateLimitResult{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  limit - count,
+		ResetAfter: untilFree,
+	}
+
+	if !allowed {
+		res.RetryAfter = untilFree
+	}
+
+	return res
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitStores(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	stores := map[string]model.RateLimitStore{
+		"memory": NewMemoryRateLimitStore(),
+		"redis":  NewRateLimitStore(rdb, logging.Nop()),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testRateLimitStore(t, store)
+		})
+	}
+}
+
+func testRateLimitStore(t *testing.T, s model.RateLimitStore) {
+	ctx := context.TODO()
+	start := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Token bucket", func(t *testing.T) {
+		// 3 requests per 3 seconds, so a token is added every second
+		for i := int64(0); i < 3; i++ {
+			res, err := s.TokenBucket(ctx, "tb", 3, 3*time.Second, start)
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, int64(3), res.Limit)
+			assert.Equal(t, 2-i, res.Remaining)
+		}
+
+		res, err := s.TokenBucket(ctx, "tb", 3, 3*time.Second, start.Add(500*time.Millisecond))
+		assert.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, int64(0), res.Remaining)
+		assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+		assert.Equal(t, 2500*time.Millisecond, res.ResetAfter)
+
+		res, err = s.TokenBucket(ctx, "tb", 3, 3*time.Second, start.Add(time.Second))
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, int64(0), res.Remaining)
+
+		// other keys have their own bucket
+		res, err = s.TokenBucket(ctx, "tb_other", 3, 3*time.Second, start.Add(time.Second))
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, int64(2), res.Remaining)
+	})
+
+	t.Run("Sliding window", func(t *testing.T) {
+		for i := int64(0); i < 2; i++ {
+			res, err := s.SlidingWindow(ctx, "sw", 2, 10*time.Second, start.Add(time.Duration(i)*time.Second))
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, 1-i, res.Remaining)
+		}
+
+		res, err := s.SlidingWindow(ctx, "sw", 2, 10*time.Second, start.Add(5*time.Second))
+		assert.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, int64(0), res.Remaining)
+		assert.Equal(t, 5*time.Second, res.RetryAfter)
+
+		// the first request has left the window
+		res, err = s.SlidingWindow(ctx, "sw", 2, 10*time.Second, start.Add(10*time.Second))
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, int64(0), res.Remaining)
+		assert.Equal(t, time.Second, res.ResetAfter)
+	})
+}
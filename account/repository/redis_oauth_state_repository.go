@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type redisOAuthStateRepository struct {
+	Redis  *redis.Client
+	Logger model.Logger
+}
+
+// NewOAuthStateRepository is a factory for initializing a repository
+// which keeps the state of identity provider signins in Redis
+func NewOAuthStateRepository(redisClient *redis.Client, logger model.Logger) model.OAuthStateRepository {
+	return &redisOAuthStateRepository{
+		Redis:  redisClient,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+//   oauth_state:state -> JSON encoded model.OAuthState
+func oauthStateKey(state string) string {
+	return fmt.Sprintf("oauth_state:%s", state)
+}
+
+func (r *redisOAuthStateRepository) SetState(ctx context.Context, state string, s *model.OAuthState, expiresIn time.Duration) error {
+	value, err := json.Marshal(s)
+	if err != nil {
+		r.Logger.Error(ctx, "Could not encode oauth state", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := r.Redis.Set(ctx, oauthStateKey(state), value, expiresIn).Err(); err != nil {
+		r.Logger.Error(ctx, "Could not SET oauth state to redis", "provider", s.Provider, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ConsumeState returns a signin's state and deletes it, in one
+// step, so that each state can only be used once
+func (r *redisOAuthStateRepository) ConsumeState(ctx context.Context, state string) (*model.OAuthState, error) {
+	value, err := r.Redis.GetDel(ctx, oauthStateKey(state)).Bytes()
+
+	if err == redis.Nil {
+		return nil, apperrors.NewNotFound("oauth state", state)
+	}
+
+	if err != nil {
+		r.Logger.Error(ctx, "Could not GETDEL oauth state from redis", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	s := &model.OAuthState{}
+
+	if err := json.Unmarshal(value, s); err != nil {
+		r.Logger.Error(ctx, "Could not decode oauth state", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return s, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisOAuthStateRepository(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	r := NewOAuthStateRepository(rdb, logging.Nop())
+	ctx := context.TODO()
+
+	t.Run("State can only be consumed once", func(t *testing.T) {
+		s := &model.OAuthState{
+			Provider:     "google",
+			Nonce:        "nonce",
+			CodeVerifier: "verifier",
+		}
+
+		assert.NoError(t, r.SetState(ctx, "abc", s, time.Minute))
+
+		got, err := r.ConsumeState(ctx, "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, s, got)
+
+		_, err = r.ConsumeState(ctx, "abc")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+
+	t.Run("State expires", func(t *testing.T) {
+		assert.NoError(t, r.SetState(ctx, "def", &model.OAuthState{Provider: "github"}, time.Minute))
+
+		mr.FastForward(time.Minute)
+
+		_, err := r.ConsumeState(ctx, "def")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+}
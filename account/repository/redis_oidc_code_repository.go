@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type redisOIDCCodeRepository struct {
+	Redis  *redis.Client
+	Logger model.Logger
+}
+
+// NewOIDCCodeRepository is a factory for initializing a repository
+// which keeps OpenID Connect authorization codes in Redis
+func NewOIDCCodeRepository(redisClient *redis.Client, logger model.Logger) model.OIDCCodeRepository {
+	return &redisOIDCCodeRepository{
+		Redis:  redisClient,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+//   oidc_code:codeHash -> JSON encoded model.OIDCAuthorization
+func oidcCodeKey(codeHash string) string {
+	return fmt.Sprintf("oidc_code:%s", codeHash)
+}
+
+func (r *redisOIDCCodeRepository) SetAuthorization(ctx context.Context, codeHash string, a *model.OIDCAuthorization, expiresIn time.Duration) error {
+	value, err := json.Marshal(a)
+	if err != nil {
+		r.Logger.Error(ctx, "Could not encode oidc authorization", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := r.Redis.Set(ctx, oidcCodeKey(codeHash), value, expiresIn).Err(); err != nil {
+		r.Logger.Error(ctx, "Could not SET oidc authorization to redis", "clientID", a.ClientID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ConsumeAuthorization returns the authorization of a code and deletes
+// it, in one step, so that a code can only be redeemed once
+func (r *redisOIDCCodeRepository) ConsumeAuthorization(ctx context.Context, codeHash string) (*model.OIDCAuthorization, error) {
+	value, err := r.Redis.GetDel(ctx, oidcCodeKey(codeHash)).Bytes()
+
+	if err == redis.Nil {
+		return nil, apperrors.NewNotFound("authorization code", codeHash)
+	}
+
+	if err != nil {
+		r.Logger.Error(ctx, "Could not GETDEL oidc authorization from redis", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	a := &model.OIDCAuthorization{}
+
+	if err := json.Unmarshal(value, a); err != nil {
+		r.Logger.Error(ctx, "Could not decode oidc authorization", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return a, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisOIDCCodeRepository(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	r := NewOIDCCodeRepository(rdb, logging.Nop())
+	ctx := context.TODO()
+
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Code can only be redeemed once", func(t *testing.T) {
+		a := &model.OIDCAuthorization{
+			ClientID:    "deck",
+			RedirectURI: "https://deck.memrizr.test/callback",
+			UID:         uid,
+			Scope:       "openid email",
+			Nonce:       "nonce",
+			AuthTime:    time.Now().Unix(),
+		}
+
+		assert.NoError(t, r.SetAuthorization(ctx, "abc", a, time.Minute))
+
+		got, err := r.ConsumeAuthorization(ctx, "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, a, got)
+
+		_, err = r.ConsumeAuthorization(ctx, "abc")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+
+	t.Run("Code expires", func(t *testing.T) {
+		assert.NoError(t, r.SetAuthorization(ctx, "def", &model.OIDCAuthorization{ClientID: "deck", UID: uid}, time.Minute))
+
+		mr.FastForward(time.Minute)
+
+		_, err := r.ConsumeAuthorization(ctx, "def")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+}
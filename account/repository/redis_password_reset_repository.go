@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type redisPasswordResetRepository struct {
+	Redis  *redis.Client
+	Logger model.Logger
+}
+
+// NewPasswordResetRepository is a factory for initializing a
+// repository which keeps password reset tokens in Redis
+func NewPasswordResetRepository(redisClient *redis.Client, logger model.Logger) model.PasswordResetRepository {
+	return &redisPasswordResetRepository{
+		Redis:  redisClient,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+//   password_reset:tokenHash -> userID the token resets the password of
+func passwordResetKey(tokenHash string) string {
+	return fmt.Sprintf("password_reset:%s", tokenHash)
+}
+
+func (r *redisPasswordResetRepository) SetResetToken(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error {
+	if err := r.Redis.Set(ctx, passwordResetKey(tokenHash), userID, expiresIn).Err(); err != nil {
+		r.Logger.Error(ctx, "Could not SET password reset token to redis", "userID", userID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ConsumeResetToken returns the userID of a reset token and deletes it,
+// in one step, so that a token can only be used once
+func (r *redisPasswordResetRepository) ConsumeResetToken(ctx context.Context, tokenHash string) (string, error) {
+	userID, err := r.Redis.GetDel(ctx, passwordResetKey(tokenHash)).Result()
+
+	if err == redis.Nil {
+		return "", apperrors.NewNotFound("password reset token", tokenHash)
+	}
+
+	if err != nil {
+		r.Logger.Error(ctx, "Could not GETDEL password reset token from redis", "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisPasswordResetRepository(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	r := NewPasswordResetRepository(rdb, logging.Nop())
+	ctx := context.TODO()
+
+	t.Run("Token can only be used once", func(t *testing.T) {
+		assert.NoError(t, r.SetResetToken(ctx, "hash1", "a_user", time.Hour))
+
+		userID, err := r.ConsumeResetToken(ctx, "hash1")
+		assert.NoError(t, err)
+		assert.Equal(t, "a_user", userID)
+
+		_, err = r.ConsumeResetToken(ctx, "hash1")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+
+	t.Run("Token expires", func(t *testing.T) {
+		assert.NoError(t, r.SetResetToken(ctx, "hash2", "a_user", time.Hour))
+
+		mr.FastForward(time.Hour)
+
+		_, err := r.ConsumeResetToken(ctx, "hash2")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// tokenBucketScript refills a bucket for the time elapsed since it was
+// last used and takes a token if one is available. Times are in ms.
+// Tokens are returned as a string, as Redis truncates Lua numbers
+var tokenBucketScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+
+if tokens == nil or ts == nil then
+	tokens = limit
+	ts = now
+end
+
+tokens = math.min(limit, tokens + math.max(0, now - ts) * limit / window)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], window)
+
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindowScript keeps a sorted set of request times, dropping
+// those which have left the window and adding this request if there
+// is room. Times are in ms
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+
+redis.call("PEXPIRE", KEYS[1], window)
+
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local oldestTs = now
+if oldest[2] then
+	oldestTs = tonumber(oldest[2])
+end
+
+return {allowed, count, oldestTs}
+`)
+
+type redisRateLimitStore struct {
+	Redis  *redis.Client
+	Logger model.Logger
+}
+
+// NewRateLimitStore is a factory for initializing a rate limit store
+// shared between instances through Redis
+func NewRateLimitStore(redisClient *redis.Client, logger model.Logger) model.RateLimitStore {
+	return &redisRateLimitStore{
+		Redis:  redisClient,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+//   ratelimit:tb:key -> hash of a token bucket's tokens and last update
+//   ratelimit:sw:key -> sorted set of request times within the window
+func tokenBucketKey(key string) string {
+	return fmt.Sprintf("ratelimit:tb:%s", key)
+}
+
+func slidingWindowKey(key string) string {
+	return fmt.Sprintf("ratelimit:sw:%s", key)
+}
+
+func (s *redisRateLimitStore) TokenBucket(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (*model.RateLimitResult, error) {
+	res, err := tokenBucketScript.Run(ctx, s.Redis, []string{tokenBucketKey(key)},
+		limit, window.Milliseconds(), now.UnixNano()/int64(time.Millisecond),
+	).Slice()
+
+	if err != nil {
+		s.Logger.Error(ctx, "Could not run token bucket", "key", key, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Could not parse token bucket tokens", "key", key, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return tokenBucketResult(allowed == 1, tokens, limit, window), nil
+}
+
+func (s *redisRateLimitStore) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (*model.RateLimitResult, error) {
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+
+	// members must be unique, or requests in the same ms would count once
+	res, err := slidingWindowScript.Run(ctx, s.Redis, []string{slidingWindowKey(key)},
+		limit, window.Milliseconds(), nowMs, uuid.New().String(),
+	).Slice()
+
+	if err != nil {
+		s.Logger.Error(ctx, "Could not run sliding window", "key", key, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	allowed, _ := res[0].(int64)
+	count, _ := res[1].(int64)
+	oldestMs, _ := res[2].(int64)
+
+	// compare in ms, the resolution the window is kept in
+	oldest := now.Add(time.Duration(oldestMs-nowMs) * time.Millisecond)
+
+	return slidingWindowResult(allowed == 1, count, oldest, limit, window, now), nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type redisSigninAttemptRepository struct {
+	Redis  *redis.Client
+	Logger model.Logger
+}
+
+// NewSigninAttemptRepository is a factory for initializing a
+// repository which tracks failed signins in Redis
+func NewSigninAttemptRepository(redisClient *redis.Client, logger model.Logger) model.SigninAttemptRepository {
+	return &redisSigninAttemptRepository{
+		Redis:  redisClient,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+//   signin:failures:key -> count of recent failed signins
+//   signin:lockout:key  -> present while signins for key are locked out
+func signinFailuresKey(key string) string {
+	return fmt.Sprintf("signin:failures:%s", key)
+}
+
+func signinLockoutKey(key string) string {
+	return fmt.Sprintf("signin:lockout:%s", key)
+}
+
+// IncrementFailures counts a failed signin and returns the number of
+// failures. Failures are forgotten once none have occurred for window
+func (r *redisSigninAttemptRepository) IncrementFailures(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := r.Redis.TxPipeline()
+	incr := pipe.Incr(ctx, signinFailuresKey(key))
+	pipe.Expire(ctx, signinFailuresKey(key), window)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.Logger.Error(ctx, "Could not increment signin failures", "key", key, "err", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return incr.Val(), nil
+}
+
+// ResetFailures clears failed signins and any lockout for key
+func (r *redisSigninAttemptRepository) ResetFailures(ctx context.Context, key string) error {
+	if err := r.Redis.Del(ctx, signinFailuresKey(key), signinLockoutKey(key)).Err(); err != nil {
+		r.Logger.Error(ctx, "Could not reset signin failures", "key", key, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// SetLockout blocks signins for key for the duration d
+func (r *redisSigninAttemptRepository) SetLockout(ctx context.Context, key string, d time.Duration) error {
+	if err := r.Redis.Set(ctx, signinLockoutKey(key), 1, d).Err(); err != nil {
+		r.Logger.Error(ctx, "Could not set signin lockout", "key", key, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// GetLockout returns how much longer signins for key are locked out,
+// or 0 if they aren't
+func (r *redisSigninAttemptRepository) GetLockout(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.Redis.PTTL(ctx, signinLockoutKey(key)).Result()
+
+	if err != nil {
+		r.Logger.Error(ctx, "Could not get signin lockout", "key", key, "err", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	// negative values mean there is no key (or, unexpectedly, no expiry)
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
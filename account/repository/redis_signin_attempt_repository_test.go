@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisSigninAttemptRepository(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	r := NewSigninAttemptRepository(rdb, logging.Nop())
+	ctx := context.TODO()
+
+	t.Run("Counts failures within window", func(t *testing.T) {
+		for i := int64(1); i <= 3; i++ {
+			n, err := r.IncrementFailures(ctx, "email:bob@bob.com", time.Hour)
+			assert.NoError(t, err)
+			assert.Equal(t, i, n)
+		}
+
+		// failures are forgotten after a quiet window
+		mr.FastForward(time.Hour + time.Second)
+
+		n, err := r.IncrementFailures(ctx, "email:bob@bob.com", time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("Lockout expires", func(t *testing.T) {
+		d, err := r.GetLockout(ctx, "ip:10.0.0.1")
+		assert.NoError(t, err)
+		assert.Zero(t, d)
+
+		assert.NoError(t, r.SetLockout(ctx, "ip:10.0.0.1", time.Minute))
+
+		d, err = r.GetLockout(ctx, "ip:10.0.0.1")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Minute, d)
+
+		mr.FastForward(time.Minute)
+
+		d, err = r.GetLockout(ctx, "ip:10.0.0.1")
+		assert.NoError(t, err)
+		assert.Zero(t, d)
+	})
+
+	t.Run("Reset clears failures and lockout", func(t *testing.T) {
+		_, err := r.IncrementFailures(ctx, "email:alice@alice.com", time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, r.SetLockout(ctx, "email:alice@alice.com", time.Minute))
+
+		assert.NoError(t, r.ResetFailures(ctx, "email:alice@alice.com"))
+
+		d, err := r.GetLockout(ctx, "email:alice@alice.com")
+		assert.NoError(t, err)
+		assert.Zero(t, d)
+
+		n, err := r.IncrementFailures(ctx, "email:alice@alice.com", time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+}
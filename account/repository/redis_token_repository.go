@@ -3,48 +3,189 @@ package repository
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
 	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
 )
 
 type redisTokenRepository struct {
-	Redis *redis.Client
+	Redis  *redis.Client
+	Logger model.Logger
 }
 
-func NewTokenRepository(redisClient *redis.Client) model.TokenRepository {
+func NewTokenRepository(redisClient *redis.Client, logger model.Logger) model.TokenRepository {
 	return &redisTokenRepository{
-		Redis: redisClient,
+		Redis:  redisClient,
+		Logger: logging.OrDefault(logger),
 	}
 }
 
-func (r *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
-	key := fmt.Sprintf("%s:%s", userID, tokenID)
-	if err := r.Redis.Set(ctx, key, 0, expiresIn).Err(); err != nil {
-		log.Printf("Could not SET refresh token to redis for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
+// All of a user's keys are prefixed with userID so that
+// DeleateUserRefreshTokens can find them with a single pattern
+//   userID:tokenID            -> sessionID of an active refresh token
+//   userID:session:sessionID  -> hash of session metadata and its active tokenID
+//   userID:rotated:tokenID    -> sessionID of an already rotated refresh token
+func refreshTokenKey(userID string, tokenID string) string {
+	return fmt.Sprintf("%s:%s", userID, tokenID)
+}
+
+func sessionKey(userID string, sessionID string) string {
+	return fmt.Sprintf("%s:session:%s", userID, sessionID)
+}
+
+func rotatedTokenKey(userID string, tokenID string) string {
+	return fmt.Sprintf("%s:rotated:%s", userID, tokenID)
+}
+
+// rotateRefreshTokenScript deletes a refresh token and marks it rotated
+// for the rest of its lifetime, returning its sessionID, or nil if it
+// doesn't exist. Doing both in one step means a token can only ever be
+// rotated once, and is never missed by reuse detection
+var rotateRefreshTokenScript = redis.NewScript(`
+local sessionID = redis.call("GET", KEYS[1])
+if not sessionID then
+	return false
+end
+
+local ttl = redis.call("PTTL", KEYS[1])
+redis.call("DEL", KEYS[1])
+
+if ttl > 0 then
+	redis.call("SET", KEYS[2], sessionID, "PX", ttl)
+end
+
+return sessionID
+`)
+
+// deleteSessionScript deletes a session along with its active refresh
+// token, which is prefixed with ARGV[1]. Returns 0 if the session doesn't
+// exist. Reading the token ID in the same step means a rotation can't
+// slip a new token in before the session is deleted
+var deleteSessionScript = redis.NewScript(`
+local tokenID = redis.call("HGET", KEYS[1], ARGV[2])
+if not tokenID then
+	return 0
+end
+
+redis.call("DEL", ARGV[1] .. tokenID, KEYS[1])
+
+return 1
+`)
+
+// fields of the session hash
+const (
+	sessionTokenField           = "token"
+	sessionCreatedAtField       = "createdAt"
+	sessionLastRefreshedAtField = "lastRefreshedAt"
+	sessionUserAgentField       = "userAgent"
+	sessionIPField              = "ip"
+	sessionClientIDField        = "clientId"
+	sessionScopeField           = "scope"
+)
+
+func (r *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, s *model.Session, expiresIn time.Duration) error {
+	key := sessionKey(userID, s.ID)
+
+	pipe := r.Redis.TxPipeline()
+	pipe.Set(ctx, refreshTokenKey(userID, tokenID), s.ID, expiresIn)
+	pipe.HSet(ctx, key,
+		sessionTokenField, tokenID,
+		sessionCreatedAtField, s.CreatedAt.Format(time.RFC3339),
+		sessionLastRefreshedAtField, s.LastRefreshedAt.Format(time.RFC3339),
+		sessionUserAgentField, s.UserAgent,
+		sessionIPField, s.IP,
+		sessionClientIDField, s.ClientID,
+		sessionScopeField, s.Scope,
+	)
+	pipe.Expire(ctx, key, expiresIn)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.Logger.Error(ctx, "Could not SET refresh token to redis", "userID", userID, "tokenID", tokenID, "err", err)
 		return apperrors.NewInternal()
 	}
 	return nil
 }
 
-func (r *redisTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, tokenID string) error {
-	key := fmt.Sprintf("%s:%s", userID, tokenID)
+func (r *redisTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, tokenID string) (*model.Session, error) {
+	sessionID, err := rotateRefreshTokenScript.Run(ctx, r.Redis,
+		[]string{refreshTokenKey(userID, tokenID), rotatedTokenKey(userID, tokenID)},
+	).Text()
+
+	// If no key was deleated, the refresh token is invalid
+	if err == redis.Nil {
+		r.Logger.Info(ctx, "Refresh token does not exist in redis", "userID", userID, "tokenID", tokenID)
+		return nil, apperrors.NewAuthorization("Invalid refresh token")
+	}
+
+	if err != nil {
+		r.Logger.Error(ctx, "Could not delete refresh token from redis", "userID", userID, "tokenID", tokenID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	// tokens issued before sessions were tracked have no session, so nil is returned
+	return r.getSession(ctx, userID, sessionID)
+}
+
+func (r *redisTokenRepository) FindRotatedRefreshToken(ctx context.Context, userID string, tokenID string) (string, error) {
+	sessionID, err := r.Redis.Get(ctx, rotatedTokenKey(userID, tokenID)).Result()
 
-	result := r.Redis.Del(ctx, key)
+	if err == redis.Nil {
+		return "", nil
+	}
 
-	if err := result.Err(); err != nil {
-		log.Printf("Could not delete refresh token to redis for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
+	if err != nil {
+		r.Logger.Error(ctx, "Could not GET rotated refresh token from redis", "userID", userID, "tokenID", tokenID, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return sessionID, nil
+}
+
+func (r *redisTokenRepository) GetSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	iter := r.Redis.Scan(ctx, 0, sessionKey(userID, "*"), 10).Iterator()
+
+	sessions := []*model.Session{}
+	prefixLen := len(sessionKey(userID, ""))
+
+	for iter.Next(ctx) {
+		s, err := r.getSession(ctx, userID, iter.Val()[prefixLen:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		// expired between SCAN and HGETALL
+		if s == nil {
+			continue
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	if err := iter.Err(); err != nil {
+		r.Logger.Error(ctx, "Could not scan sessions in redis", "userID", userID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return sessions, nil
+}
+
+func (r *redisTokenRepository) DeleteSession(ctx context.Context, userID string, sessionID string) error {
+	deleted, err := deleteSessionScript.Run(ctx, r.Redis,
+		[]string{sessionKey(userID, sessionID)},
+		refreshTokenKey(userID, ""), sessionTokenField,
+	).Int()
+
+	if err != nil {
+		r.Logger.Error(ctx, "Could not delete session from redis", "userID", userID, "sessionID", sessionID, "err", err)
 		return apperrors.NewInternal()
 	}
 
-	//Val returns count of deleated keys.
-	// If no key was deleated, the refresh token is invalid
-	if result.Val() < 1 {
-		log.Printf("Refresh token to redis for userID/tokenID: %s/%s does not exist\n", userID, tokenID)
-		return apperrors.NewAuthorization("Invalid refresh token")
+	if deleted == 0 {
+		return apperrors.NewNotFound("session", sessionID)
 	}
 
 	return nil
@@ -58,13 +199,14 @@ func (r *redisTokenRepository) DeleateUserRefreshTokens(ctx context.Context, use
 
 	for iter.Next(ctx) {
 		if err := r.Redis.Del(ctx, iter.Val()).Err(); err != nil {
-			log.Printf("Could not delete refresh token to redis for userID/tokenID: %s/%s: %v\n", userID, iter.Val(), err)
+			r.Logger.Error(ctx, "Could not delete refresh token from redis", "userID", userID, "key", iter.Val(), "err", err)
 			failcount++
 		}
 	}
 	
 	if err := iter.Err(); err != nil {
-		log.Printf("Failed to delete refresh token: %s\n", iter.Val())
+		r.Logger.Error(ctx, "Could not scan refresh tokens in redis", "userID", userID, "err", err)
+		return apperrors.NewInternal()
 	}
 
 	if failcount > 0 {
@@ -72,4 +214,32 @@ func (r *redisTokenRepository) DeleateUserRefreshTokens(ctx context.Context, use
 	}
 
 	return nil;
-}
\ No newline at end of file
+}
+
+// getSession returns nil if the session does not exist
+func (r *redisTokenRepository) getSession(ctx context.Context, userID string, sessionID string) (*model.Session, error) {
+	fields, err := r.Redis.HGetAll(ctx, sessionKey(userID, sessionID)).Result()
+
+	if err != nil {
+		r.Logger.Error(ctx, "Could not GET session from redis", "userID", userID, "sessionID", sessionID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	// unparseable times are left as zero values rather than failing the lookup
+	createdAt, _ := time.Parse(time.RFC3339, fields[sessionCreatedAtField])
+	lastRefreshedAt, _ := time.Parse(time.RFC3339, fields[sessionLastRefreshedAtField])
+
+	return &model.Session{
+		ID:              sessionID,
+		CreatedAt:       createdAt,
+		LastRefreshedAt: lastRefreshedAt,
+		UserAgent:       fields[sessionUserAgentField],
+		IP:              fields[sessionIPField],
+		ClientID:        fields[sessionClientIDField],
+		Scope:           fields[sessionScopeField],
+	}, nil
+}
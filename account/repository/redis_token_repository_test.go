@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisTokenRepository(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	r := NewTokenRepository(rdb, logging.Nop())
+	ctx := context.TODO()
+
+	uid := "a_user"
+	createdAt := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newSession := func(id string) *model.Session {
+		return &model.Session{
+			ID:              id,
+			CreatedAt:       createdAt,
+			LastRefreshedAt: createdAt,
+			UserAgent:       "Mozilla/5.0",
+			IP:              "10.0.0.1",
+		}
+	}
+
+	t.Run("Rotation keeps the session", func(t *testing.T) {
+		assert.NoError(t, r.SetRefreshToken(ctx, uid, "token1", newSession("session1"), time.Hour))
+
+		session, err := r.DeleteRefreshToken(ctx, uid, "token1")
+		assert.NoError(t, err)
+		assert.Equal(t, newSession("session1"), session)
+
+		// the rotated token can't be used again, but is remembered
+		_, err = r.DeleteRefreshToken(ctx, uid, "token1")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+
+		sessionID, err := r.FindRotatedRefreshToken(ctx, uid, "token1")
+		assert.NoError(t, err)
+		assert.Equal(t, "session1", sessionID)
+		assert.True(t, mr.TTL(rotatedTokenKey(uid, "token1")) > 0)
+	})
+
+	t.Run("Keeps OIDC client of session", func(t *testing.T) {
+		s := newSession("clientsession")
+		s.ClientID = "deck"
+		s.Scope = "openid email"
+
+		assert.NoError(t, r.SetRefreshToken(ctx, uid, "clienttoken", s, time.Hour))
+
+		session, err := r.DeleteRefreshToken(ctx, uid, "clienttoken")
+		assert.NoError(t, err)
+		assert.Equal(t, s, session)
+	})
+
+	t.Run("Unknown token is not rotated", func(t *testing.T) {
+		sessionID, err := r.FindRotatedRefreshToken(ctx, uid, "neverissued")
+		assert.NoError(t, err)
+		assert.Empty(t, sessionID)
+	})
+
+	t.Run("Token issued before sessions", func(t *testing.T) {
+		mr.Set(refreshTokenKey(uid, "legacytoken"), "0")
+
+		session, err := r.DeleteRefreshToken(ctx, uid, "legacytoken")
+		assert.NoError(t, err)
+		assert.Nil(t, session)
+	})
+
+	t.Run("Lists and deletes sessions", func(t *testing.T) {
+		mr.FlushAll()
+
+		assert.NoError(t, r.SetRefreshToken(ctx, uid, "token2", newSession("session2"), time.Hour))
+		assert.NoError(t, r.SetRefreshToken(ctx, uid, "token3", newSession("session3"), time.Hour))
+
+		sessions, err := r.GetSessions(ctx, uid)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []*model.Session{newSession("session2"), newSession("session3")}, sessions)
+
+		assert.NoError(t, r.DeleteSession(ctx, uid, "session2"))
+
+		_, err = r.DeleteRefreshToken(ctx, uid, "token2")
+		assert.Error(t, err)
+
+		err = r.DeleteSession(ctx, uid, "session2")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+
+		// other sessions are untouched
+		session, err := r.DeleteRefreshToken(ctx, uid, "token3")
+		assert.NoError(t, err)
+		assert.Equal(t, "session3", session.ID)
+	})
+
+	t.Run("Deleting a rotated session", func(t *testing.T) {
+		assert.NoError(t, r.SetRefreshToken(ctx, uid, "token6", newSession("session6"), time.Hour))
+
+		session, err := r.DeleteRefreshToken(ctx, uid, "token6")
+		assert.NoError(t, err)
+		assert.NoError(t, r.SetRefreshToken(ctx, uid, "token7", session, time.Hour))
+
+		assert.NoError(t, r.DeleteSession(ctx, uid, "session6"))
+
+		// the session's latest token is revoked with it
+		assert.False(t, mr.Exists(refreshTokenKey(uid, "token7")))
+		assert.False(t, mr.Exists(sessionKey(uid, "session6")))
+
+		// while the rotated token is still recognised if replayed
+		sessionID, err := r.FindRotatedRefreshToken(ctx, uid, "token6")
+		assert.NoError(t, err)
+		assert.Equal(t, "session6", sessionID)
+	})
+
+	t.Run("Deleting all user tokens", func(t *testing.T) {
+		assert.NoError(t, r.SetRefreshToken(ctx, uid, "token4", newSession("session4"), time.Hour))
+		assert.NoError(t, r.SetRefreshToken(ctx, "another_user", "token5", newSession("session5"), time.Hour))
+
+		assert.NoError(t, r.DeleateUserRefreshTokens(ctx, uid))
+
+		_, err := r.DeleteRefreshToken(ctx, uid, "token4")
+		assert.Error(t, err)
+
+		sessions, err := r.GetSessions(ctx, uid)
+		assert.NoError(t, err)
+		assert.Empty(t, sessions)
+
+		_, err = r.DeleteRefreshToken(ctx, "another_user", "token5")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Scan error deleting all user tokens", func(t *testing.T) {
+		mr.SetError("LOADING Redis is loading the dataset in memory")
+		defer mr.SetError("")
+
+		err := r.DeleateUserRefreshTokens(ctx, uid)
+
+		assert.Equal(t, apperrors.NewInternal(), err)
+	})
+}
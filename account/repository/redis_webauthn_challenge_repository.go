@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type redisWebAuthnChallengeRepository struct {
+	Redis  *redis.Client
+	Logger model.Logger
+}
+
+// NewWebAuthnChallengeRepository is a factory for initializing a
+// repository which keeps WebAuthn challenges in Redis
+func NewWebAuthnChallengeRepository(redisClient *redis.Client, logger model.Logger) model.WebAuthnChallengeRepository {
+	return &redisWebAuthnChallengeRepository{
+		Redis:  redisClient,
+		Logger: logging.OrDefault(logger),
+	}
+}
+
+//   webauthn_challenge:key -> challenge of a ceremony in progress
+func webAuthnChallengeKey(key string) string {
+	return fmt.Sprintf("webauthn_challenge:%s", key)
+}
+
+func (r *redisWebAuthnChallengeRepository) SetChallenge(ctx context.Context, key string, challenge string, expiresIn time.Duration) error {
+	if err := r.Redis.Set(ctx, webAuthnChallengeKey(key), challenge, expiresIn).Err(); err != nil {
+		r.Logger.Error(ctx, "Could not SET webauthn challenge to redis", "key", key, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ConsumeChallenge returns a challenge and deletes it, in one
+// step, so that each challenge can only be answered once
+func (r *redisWebAuthnChallengeRepository) ConsumeChallenge(ctx context.Context, key string) (string, error) {
+	challenge, err := r.Redis.GetDel(ctx, webAuthnChallengeKey(key)).Result()
+
+	if err == redis.Nil {
+		return "", apperrors.NewNotFound("webauthn challenge", key)
+	}
+
+	if err != nil {
+		r.Logger.Error(ctx, "Could not GETDEL webauthn challenge from redis", "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return challenge, nil
+}
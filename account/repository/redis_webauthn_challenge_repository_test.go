@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisWebAuthnChallengeRepository(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	r := NewWebAuthnChallengeRepository(rdb, logging.Nop())
+	ctx := context.TODO()
+
+	t.Run("Challenge can only be consumed once", func(t *testing.T) {
+		assert.NoError(t, r.SetChallenge(ctx, "login:abc", "abc", time.Minute))
+
+		challenge, err := r.ConsumeChallenge(ctx, "login:abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", challenge)
+
+		_, err = r.ConsumeChallenge(ctx, "login:abc")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+
+	t.Run("Challenge expires", func(t *testing.T) {
+		assert.NoError(t, r.SetChallenge(ctx, "login:def", "def", time.Minute))
+
+		mr.FastForward(time.Minute)
+
+		_, err := r.ConsumeChallenge(ctx, "login:def")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// S3Config holds the settings for an S3-compatible object store.
+// Objects are addressed path-style (Endpoint/Bucket/objName), which
+// MinIO and most other S3-compatible stores support
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PublicURL is the base URL images are served from. Defaults to Endpoint/Bucket
+	PublicURL string
+	Logger    model.Logger
+}
+
+type s3ImageRepository struct {
+	HTTPClient *http.Client
+	Endpoint   string
+	Bucket     string
+	PublicURL  string
+	Signer     *s3Signer
+	Logger     model.Logger
+}
+
+// NewS3ImageRepository is a factory for initializing an image repository
+// which stores objects in an S3-compatible bucket
+func NewS3ImageRepository(httpClient *http.Client, c *S3Config) model.ImageRepository {
+	endpoint := strings.TrimSuffix(c.Endpoint, "/")
+
+	publicURL := strings.TrimSuffix(c.PublicURL, "/")
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("%s/%s", endpoint, c.Bucket)
+	}
+
+	return &s3ImageRepository{
+		HTTPClient: httpClient,
+		Endpoint:   endpoint,
+		Bucket:     c.Bucket,
+		PublicURL:  publicURL,
+		Signer: &s3Signer{
+			AccessKeyID:     c.AccessKeyID,
+			SecretAccessKey: c.SecretAccessKey,
+			Region:          c.Region,
+		},
+		Logger: logging.OrDefault(c.Logger),
+	}
+}
+
+func (r *s3ImageRepository) UpdateProfile(
+	ctx context.Context,
+	objName string,
+	imgFile multipart.File,
+) (string, error) {
+	// uploads are bounded by the handler's MaxBodyBytes, so we can
+	// afford to hash the payload up front for signing
+	data, err := ioutil.ReadAll(imgFile)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to read image file", "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, r.objectURL(objName), bytes.NewReader(data))
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to create S3 PutObject request", "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	req.Header.Set("Content-Type", http.DetectContentType(data))
+	req.Header.Set("Cache-Control", "no-cache,max-age=0")
+
+	if err := r.do(req, sha256Hex(data)); err != nil {
+		r.Logger.Error(ctx, "Unable to write file to S3 bucket", "bucket", r.Bucket, "objName", objName, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	imageURL := fmt.Sprintf("%s/%s", r.PublicURL, objName)
+
+	return imageURL, nil
+}
+
+func (r *s3ImageRepository) GetProfile(ctx context.Context, objName string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.objectURL(objName), nil)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to create S3 GetObject request", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	r.Signer.sign(req, emptyBodySHA256, time.Now())
+
+	resp, err := r.HTTPClient.Do(req)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to read image object from S3 bucket", "bucket", r.Bucket, "objName", objName, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		r.Logger.Error(ctx, "Unable to read image object from S3 bucket", "bucket", r.Bucket, "objName", objName, "status", resp.Status, "body", string(body))
+		return nil, apperrors.NewInternal()
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to read image object from S3 bucket", "bucket", r.Bucket, "objName", objName, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return data, nil
+}
+
+func (r *s3ImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, r.objectURL(objName), nil)
+
+	if err != nil {
+		r.Logger.Error(ctx, "Unable to create S3 DeleteObject request", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := r.do(req, emptyBodySHA256); err != nil {
+		r.Logger.Error(ctx, "Unable to delete image object from S3 bucket", "bucket", r.Bucket, "objName", objName, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *s3ImageRepository) objectURL(objName string) string {
+	return fmt.Sprintf("%s/%s/%s", r.Endpoint, r.Bucket, objName)
+}
+
+// do signs and sends req, treating any non-2xx response as an error
+func (r *s3ImageRepository) do(req *http.Request, payloadHash string) error {
+	r.Signer.sign(req, payloadHash, time.Now())
+
+	resp, err := r.HTTPClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status: %s: %s", resp.Status, body)
+	}
+
+	return nil
+}
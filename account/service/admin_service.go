@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+type adminService struct {
+	UserRepository  model.UserRepository
+	TokenRepository model.TokenRepository
+	AuditRepository model.AuditRepository
+	Logger          model.Logger
+}
+
+// ASConfig will hold repositories that will eventually be injected into
+// this service layer. The TokenRepository is used to sign users out
+// everywhere when their account is disabled. The AuditRepository holds
+// the audit log admins can search
+type ASConfig struct {
+	UserRepository  model.UserRepository
+	TokenRepository model.TokenRepository
+	AuditRepository model.AuditRepository
+	Logger          model.Logger
+}
+
+// NewAdminService is a factory function for
+// initializing an AdminService with its repository layer dependencies
+func NewAdminService(c *ASConfig) model.AdminService {
+	return &adminService{
+		UserRepository:  c.UserRepository,
+		TokenRepository: c.TokenRepository,
+		AuditRepository: c.AuditRepository,
+		Logger:          logging.OrDefault(c.Logger),
+	}
+}
+
+// ListUsers returns a page of users matching query. Page sizes out of
+// range are clamped rather than rejected
+func (s *adminService) ListUsers(ctx context.Context, query *model.UserQuery) (*model.UserPage, error) {
+	q := *query
+	q.Limit, q.Offset = pageBounds(q.Limit, q.Offset)
+
+	users, total, err := s.UserRepository.List(ctx, &q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserPage{
+		Users:  users,
+		Total:  total,
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}, nil
+}
+
+// SetDisabled disables or enables a user's account. Disabled users can't
+// get new tokens, and are signed out everywhere, though ID tokens they
+// already have are valid until they expire
+func (s *adminService) SetDisabled(ctx context.Context, uid uuid.UUID, disabled bool) (_ *model.User, err error) {
+	action := model.AuditAdminEnable
+	if disabled {
+		action = model.AuditAdminDisable
+	}
+
+	defer func() {
+		recordAdminAudit(ctx, s.Logger, s.AuditRepository, uid, action, err)
+	}()
+
+	u, err := s.UserRepository.SetDisabled(ctx, uid, disabled)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		if err := s.TokenRepository.DeleateUserRefreshTokens(ctx, uid.String()); err != nil {
+			s.Logger.Warn(ctx, "Unable to revoke refresh tokens after disabling user", "uid", uid, "err", err)
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
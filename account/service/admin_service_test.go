@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListUsers(t *testing.T) {
+	users := []*model.User{{Email: "bob@bob.com"}}
+
+	cases := map[string]struct {
+		Query    model.UserQuery
+		Expected model.UserQuery
+	}{
+		"Defaults": {model.UserQuery{Search: "bob"}, model.UserQuery{Search: "bob", Limit: 20}},
+		"Page":     {model.UserQuery{Limit: 50, Offset: 100}, model.UserQuery{Limit: 50, Offset: 100}},
+		"Clamped":  {model.UserQuery{Limit: 1000, Offset: -5}, model.UserQuery{Limit: 100}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepository := new(mocks.MockUserRepository)
+			as := NewAdminService(&ASConfig{UserRepository: mockUserRepository})
+
+			expected := c.Expected
+			mockUserRepository.On("List", mock.Anything, &expected).Return(users, 120, nil)
+
+			query := c.Query
+			page, err := as.ListUsers(context.TODO(), &query)
+
+			assert.NoError(t, err)
+			assert.Equal(t, &model.UserPage{
+				Users:  users,
+				Total:  120,
+				Limit:  expected.Limit,
+				Offset: expected.Offset,
+			}, page)
+		})
+	}
+}
+
+func TestSetDisabled(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Disabling signs user out", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+		})
+
+		u := &model.User{UID: uid, Disabled: true}
+		mockUserRepository.On("SetDisabled", mock.Anything, uid, true).Return(u, nil)
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+
+		got, err := as.SetDisabled(context.TODO(), uid, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u, got)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Enabling", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		as := NewAdminService(&ASConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+		})
+
+		u := &model.User{UID: uid}
+		mockUserRepository.On("SetDisabled", mock.Anything, uid, false).Return(u, nil)
+
+		got, err := as.SetDisabled(context.TODO(), uid, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u, got)
+		mockTokenRepository.AssertNotCalled(t, "DeleateUserRefreshTokens", mock.Anything, mock.Anything)
+	})
+}
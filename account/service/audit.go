@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// bounds of pages of users and audit entries
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageBounds clamps a page's limit and offset into range, rather than
+// rejecting them
+func pageBounds(limit int, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
+
+// recordAudit records an attempt at action by the user with uid, if
+// known, from the client of ctx. It failed if err is set. Failing to
+// record it is only logged, so the audit log can't stop users signing in
+func recordAudit(ctx context.Context, logger model.Logger, repo model.AuditRepository, uid *uuid.UUID, action string, err error) {
+	recordAuditEntry(ctx, logger, repo, &model.AuditEntry{UID: uid, Action: action}, err)
+}
+
+// recordAdminAudit records an action the admin of ctx took on target's
+// account. The entry is the admin's, so it outlives the target's account
+func recordAdminAudit(ctx context.Context, logger model.Logger, repo model.AuditRepository, target uuid.UUID, action string, err error) {
+	recordAuditEntry(ctx, logger, repo, &model.AuditEntry{UID: model.AdminFromContext(ctx), Action: action, TargetUID: &target}, err)
+}
+
+func recordAuditEntry(ctx context.Context, logger model.Logger, repo model.AuditRepository, entry *model.AuditEntry, err error) {
+	if repo == nil {
+		return
+	}
+
+	entry.Outcome = model.AuditSuccess
+	if err != nil {
+		entry.Outcome = model.AuditFailure
+	}
+
+	client := model.ClientInfoFromContext(ctx)
+	entry.IP = client.IP
+	entry.UserAgent = client.UserAgent
+
+	if err := repo.Record(ctx, entry); err != nil {
+		logger.Error(ctx, "Unable to record audit entry", "action", entry.Action, "uid", entry.UID, "err", err)
+	}
+}
+
+// GetActivity returns a page of the user's own audit log, newest first
+func (s *userService) GetActivity(ctx context.Context, uid uuid.UUID, limit int, offset int) (*model.AuditPage, error) {
+	limit, offset = pageBounds(limit, offset)
+
+	entries, err := s.AuditRepository.Find(ctx, &model.AuditQuery{
+		UID:    &uid,
+		Limit:  limit,
+		Offset: offset,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AuditPage{
+		Entries: entries,
+		Limit:   limit,
+		Offset:  offset,
+	}, nil
+}
+
+// AuditLog returns a page of audit entries matching query, newest first
+func (s *adminService) AuditLog(ctx context.Context, query *model.AuditQuery) (*model.AuditPage, error) {
+	q := *query
+	q.Limit, q.Offset = pageBounds(q.Limit, q.Offset)
+
+	entries, err := s.AuditRepository.Find(ctx, &q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AuditPage{
+		Entries: entries,
+		Limit:   q.Limit,
+		Offset:  q.Offset,
+	}, nil
+}
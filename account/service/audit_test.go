@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// auditEntry matches an audit entry recorded for uid (nil when the
+// user isn't known), action and outcome
+func auditEntry(uid *uuid.UUID, action string, outcome string) interface{} {
+	return mock.MatchedBy(func(e *model.AuditEntry) bool {
+		return e.Action == action &&
+			e.Outcome == outcome &&
+			assert.ObjectsAreEqual(uid, e.UID) &&
+			e.IP == "203.0.113.7" &&
+			e.UserAgent == "Firefox"
+	})
+}
+
+func TestSigninAudit(t *testing.T) {
+	ctx := model.ContextWithClientInfo(context.TODO(), model.ClientInfo{IP: "203.0.113.7", UserAgent: "Firefox"})
+
+	uid, _ := uuid.NewRandom()
+	hashedPassword, _ := hashPassword("correctpassword", DefaultPasswordParams)
+
+	setup := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockAuditRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			AuditRepository: mockAuditRepository,
+			PasswordParams:  DefaultPasswordParams,
+		})
+
+		return us, mockUserRepository, mockAuditRepository
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockUserRepository, mockAuditRepository := setup()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com", Password: hashedPassword}, nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "correctpassword"})
+		assert.NoError(t, err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditSignin, model.AuditSuccess))
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		us, mockUserRepository, mockAuditRepository := setup()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com", Password: hashedPassword}, nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "wrongpassword"})
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditSignin, model.AuditFailure))
+	})
+
+	t.Run("Disabled account", func(t *testing.T) {
+		us, mockUserRepository, mockAuditRepository := setup()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com", Password: hashedPassword, Disabled: true}, nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "correctpassword"})
+		assert.Equal(t, apperrors.NewForbidden("Your account has been disabled"), err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditSignin, model.AuditFailure))
+	})
+
+	t.Run("Deleted account", func(t *testing.T) {
+		us, mockUserRepository, mockAuditRepository := setup()
+
+		deletedAt := time.Now()
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com", Password: hashedPassword, DeletedAt: &deletedAt}, nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "correctpassword"})
+		assert.Equal(t, apperrors.NewForbidden("Your account has been deleted"), err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditSignin, model.AuditFailure))
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		us, mockUserRepository, mockAuditRepository := setup()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "alice@alice.com").Return(nil, apperrors.NewNotFound("email", "alice@alice.com"))
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		err := us.Signin(ctx, &model.User{Email: "alice@alice.com", Password: "apassword"})
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(nil, model.AuditSignin, model.AuditFailure))
+	})
+
+	t.Run("Recording fails", func(t *testing.T) {
+		us, mockUserRepository, mockAuditRepository := setup()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com", Password: hashedPassword}, nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(errors.New("connection refused"))
+
+		// users can still sign in
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "correctpassword"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestNewPairFromUserAudit(t *testing.T) {
+	ctx := model.ContextWithClientInfo(context.TODO(), model.ClientInfo{IP: "203.0.113.7", UserAgent: "Firefox"})
+
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+	uid, _ := uuid.NewRandom()
+
+	setup := func() (model.TokenService, *mocks.MockTokenRepository, *mocks.MockAuditRepository) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		ts := NewTokenService(&TSConfig{
+			TokenRepository:       mockTokenRepository,
+			AuditRepository:       mockAuditRepository,
+			PrivKey:               privKey,
+			PubKey:                pubKey,
+			RefreshSecret:         "randomtestsecret",
+			IDExpiratonSecs:       15 * 60,
+			RefreshExpirationSecs: 3 * 24 * 3600,
+		})
+
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, uid.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).Return(nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		return ts, mockTokenRepository, mockAuditRepository
+	}
+
+	t.Run("Session start", func(t *testing.T) {
+		ts, _, mockAuditRepository := setup()
+
+		_, err := ts.NewPairFromUser(ctx, &model.User{UID: uid}, "")
+		assert.NoError(t, err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditSessionStart, model.AuditSuccess))
+	})
+
+	t.Run("Token refresh", func(t *testing.T) {
+		ts, mockTokenRepository, mockAuditRepository := setup()
+
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, uid.String(), "aprevioustokenid").Return(&model.Session{ID: "asession"}, nil)
+
+		_, err := ts.NewPairFromUser(ctx, &model.User{UID: uid}, "aprevioustokenid")
+		assert.NoError(t, err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditTokenRefresh, model.AuditSuccess))
+	})
+
+	t.Run("Disabled user", func(t *testing.T) {
+		ts, _, mockAuditRepository := setup()
+
+		_, err := ts.NewPairFromUser(ctx, &model.User{UID: uid, Disabled: true}, "")
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditSessionStart, model.AuditFailure))
+	})
+}
+
+func TestAccountChangeAudit(t *testing.T) {
+	ctx := model.ContextWithClientInfo(context.TODO(), model.ClientInfo{IP: "203.0.113.7", UserAgent: "Firefox"})
+
+	uid, _ := uuid.NewRandom()
+	hashedPassword, _ := hashPassword("correctpassword", DefaultPasswordParams)
+
+	setup := func(gracePeriod time.Duration) (model.UserService, *mocks.MockUserRepository, *mocks.MockTokenRepository, *mocks.MockAuditRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:      mockUserRepository,
+			TokenRepository:     mockTokenRepository,
+			AuditRepository:     mockAuditRepository,
+			PasswordParams:      DefaultPasswordParams,
+			DeletionGracePeriod: gracePeriod,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com", Password: hashedPassword}, nil)
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		return us, mockUserRepository, mockTokenRepository, mockAuditRepository
+	}
+
+	t.Run("Password change with wrong password", func(t *testing.T) {
+		us, _, _, mockAuditRepository := setup(0)
+
+		_, err := us.ChangePassword(ctx, uid, "wrongpassword", "newpassword")
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditPasswordChange, model.AuditFailure))
+	})
+
+	t.Run("Disabling TOTP", func(t *testing.T) {
+		us, mockUserRepository, _, mockAuditRepository := setup(0)
+
+		mockUserRepository.ExpectedCalls = nil
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com", Password: hashedPassword, TOTPEnabled: true}, nil)
+		mockUserRepository.On("UpdateTOTP", mock.Anything, uid, "", false, mock.Anything).Return(nil)
+
+		err := us.DisableTOTP(ctx, uid, "correctpassword")
+		assert.NoError(t, err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditTOTPDisable, model.AuditSuccess))
+	})
+
+	t.Run("Account deletion", func(t *testing.T) {
+		us, mockUserRepository, _, mockAuditRepository := setup(time.Hour)
+
+		mockUserRepository.On("SoftDelete", mock.Anything, uid, mock.AnythingOfType("time.Time")).Return(nil)
+
+		err := us.DeleteAccount(ctx, uid, "correctpassword")
+		assert.NoError(t, err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, auditEntry(&uid, model.AuditAccountDelete, model.AuditSuccess))
+	})
+
+	t.Run("Account deleted straight away", func(t *testing.T) {
+		us, mockUserRepository, _, mockAuditRepository := setup(0)
+
+		mockUserRepository.On("Delete", mock.Anything, uid).Return(nil)
+
+		err := us.DeleteAccount(ctx, uid, "correctpassword")
+		assert.NoError(t, err)
+
+		// the user's entries are deleted along with them
+		mockAuditRepository.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Admin deletion", func(t *testing.T) {
+		us, mockUserRepository, _, mockAuditRepository := setup(0)
+
+		adminUID, _ := uuid.NewRandom()
+		mockUserRepository.On("Delete", mock.Anything, uid).Return(nil)
+
+		err := us.Delete(model.ContextWithAdmin(ctx, adminUID), uid)
+		assert.NoError(t, err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, adminAuditEntry(adminUID, uid, model.AuditAdminDelete))
+	})
+}
+
+// adminAuditEntry matches a successful audit entry recorded for an
+// admin's action on target's account
+func adminAuditEntry(admin uuid.UUID, target uuid.UUID, action string) interface{} {
+	return mock.MatchedBy(func(e *model.AuditEntry) bool {
+		return e.Action == action &&
+			e.Outcome == model.AuditSuccess &&
+			assert.ObjectsAreEqual(&admin, e.UID) &&
+			assert.ObjectsAreEqual(&target, e.TargetUID)
+	})
+}
+
+func TestAdminAudit(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	adminUID, _ := uuid.NewRandom()
+
+	ctx := model.ContextWithAdmin(context.TODO(), adminUID)
+
+	t.Run("Disable", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		as := NewAdminService(&ASConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			AuditRepository: mockAuditRepository,
+		})
+
+		mockUserRepository.On("SetDisabled", mock.Anything, uid, true).Return(&model.User{UID: uid, Disabled: true}, nil)
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		_, err := as.SetDisabled(ctx, uid, true)
+		assert.NoError(t, err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, adminAuditEntry(adminUID, uid, model.AuditAdminDisable))
+	})
+
+	t.Run("Signout", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+		privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+		pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
+		pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+		ts := NewTokenService(&TSConfig{
+			TokenRepository: mockTokenRepository,
+			AuditRepository: mockAuditRepository,
+			PrivKey:         privKey,
+			PubKey:          pubKey,
+		})
+
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockAuditRepository.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		err := ts.Signout(ctx, uid)
+		assert.NoError(t, err)
+
+		mockAuditRepository.AssertCalled(t, "Record", mock.Anything, adminAuditEntry(adminUID, uid, model.AuditAdminSignout))
+		mockAuditRepository.AssertNotCalled(t, "Record", mock.Anything, mock.MatchedBy(func(e *model.AuditEntry) bool {
+			return e.Action == model.AuditSignout
+		}))
+	})
+}
+
+func TestGetActivity(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	entries := []*model.AuditEntry{{ID: 2, UID: &uid, Action: model.AuditSignout, Outcome: model.AuditSuccess}}
+
+	setup := func() (model.UserService, *mocks.MockAuditRepository) {
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		us := NewUserService(&USConfig{
+			AuditRepository: mockAuditRepository,
+		})
+
+		return us, mockAuditRepository
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockAuditRepository := setup()
+
+		mockAuditRepository.On("Find", mock.Anything, &model.AuditQuery{UID: &uid, Limit: 10, Offset: 20}).Return(entries, nil)
+
+		page, err := us.GetActivity(context.TODO(), uid, 10, 20)
+		assert.NoError(t, err)
+		assert.Equal(t, &model.AuditPage{Entries: entries, Limit: 10, Offset: 20}, page)
+	})
+
+	t.Run("Clamps page", func(t *testing.T) {
+		us, mockAuditRepository := setup()
+
+		mockAuditRepository.On("Find", mock.Anything, &model.AuditQuery{UID: &uid, Limit: maxPageSize, Offset: 0}).Return(entries, nil)
+
+		page, err := us.GetActivity(context.TODO(), uid, 1000, -5)
+		assert.NoError(t, err)
+		assert.Equal(t, maxPageSize, page.Limit)
+		assert.Equal(t, 0, page.Offset)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		us, mockAuditRepository := setup()
+
+		mockAuditRepository.On("Find", mock.Anything, mock.Anything).Return(nil, apperrors.NewInternal())
+
+		page, err := us.GetActivity(context.TODO(), uid, 0, 0)
+		assert.Error(t, err)
+		assert.Nil(t, page)
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	entries := []*model.AuditEntry{{ID: 3, UID: &uid, Action: model.AuditSignin, Outcome: model.AuditFailure, IP: "203.0.113.7"}}
+
+	mockAuditRepository := new(mocks.MockAuditRepository)
+
+	as := NewAdminService(&ASConfig{
+		AuditRepository: mockAuditRepository,
+	})
+
+	query := &model.AuditQuery{UID: &uid, Outcome: model.AuditFailure}
+
+	mockAuditRepository.On("Find", mock.Anything, &model.AuditQuery{UID: &uid, Outcome: model.AuditFailure, Limit: defaultPageSize}).Return(entries, nil)
+
+	page, err := as.AuditLog(context.TODO(), query)
+	assert.NoError(t, err)
+	assert.Equal(t, &model.AuditPage{Entries: entries, Limit: defaultPageSize, Offset: 0}, page)
+
+	// the caller's query is left as it was
+	assert.Equal(t, 0, query.Limit)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// how many accounts PurgeDeletedAccounts removes per run
+const purgeBatchSize = 100
+
+// DeleteAccount deletes a signed in user's own account once they've
+// confirmed their password. With a DeletionGracePeriod the account is
+// only marked deleted, and signed out, until it is purged
+func (s *userService) DeleteAccount(ctx context.Context, uid uuid.UUID, password string) (err error) {
+	// an account deleted straight away takes its audit entries with it
+	deleted := false
+
+	defer func() {
+		if !deleted {
+			recordAudit(ctx, s.Logger, s.AuditRepository, &uid, model.AuditAccountDelete, err)
+		}
+	}()
+
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.confirmPassword(ctx, u, password); err != nil {
+		return err
+	}
+
+	if s.DeletionGracePeriod <= 0 {
+		err = s.Delete(ctx, uid)
+		deleted = err == nil
+		return err
+	}
+
+	if err := s.UserRepository.SoftDelete(ctx, uid, time.Now()); err != nil {
+		return err
+	}
+
+	if err := s.TokenRepository.DeleateUserRefreshTokens(ctx, uid.String()); err != nil {
+		s.Logger.Warn(ctx, "Unable to revoke refresh tokens after marking user deleted", "uid", uid, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes a user's account. Their profile image is removed first,
+// so that a failure leaves an account which can be deleted again rather
+// than images nothing refers to. Deletions by an admin are audited
+func (s *userService) Delete(ctx context.Context, uid uuid.UUID) (err error) {
+	if model.AdminFromContext(ctx) != nil {
+		defer func() {
+			recordAdminAudit(ctx, s.Logger, s.AuditRepository, uid, model.AuditAdminDelete, err)
+		}()
+	}
+
+	if err := s.ClearProfileImage(ctx, uid); err != nil {
+		s.Logger.Warn(ctx, "Unable to remove profile image before deleting user", "uid", uid, "err", err)
+		return err
+	}
+
+	if err := s.TokenRepository.DeleateUserRefreshTokens(ctx, uid.String()); err != nil {
+		s.Logger.Warn(ctx, "Unable to revoke refresh tokens before deleting user", "uid", uid, "err", err)
+		return err
+	}
+
+	return withinTransaction(ctx, s.Transactor, func(ctx context.Context) error {
+		if err := s.UserRepository.Delete(ctx, uid); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, s.Logger, s.EventBroker, newEvent(model.EventUserDeleted, uid, nil))
+	})
+}
+
+// PurgeDeletedAccounts removes accounts whose deletion grace period is
+// over, returning how many were removed. Accounts which fail to be
+// removed are left for the next run
+func (s *userService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	users, err := s.UserRepository.FindDeleted(ctx, time.Now().Add(-s.DeletionGracePeriod), purgeBatchSize)
+
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+
+	for _, u := range users {
+		if err := s.Delete(ctx, u.UID); err != nil {
+			s.Logger.Error(ctx, "Unable to purge deleted user", "uid", u.UID, "err", err)
+			continue
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/events"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDelete(t *testing.T) {
+	var broker *events.MemoryBroker
+
+	setup := func(u *model.User) (model.UserService, *mocks.MockUserRepository, *mocks.MockImageRepository, *mocks.MockTokenRepository) {
+		broker = events.NewMemoryBroker()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+			TokenRepository: mockTokenRepository,
+			EventBroker:     broker,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, u.UID).Return(u, nil)
+
+		return us, mockUserRepository, mockImageRepository, mockTokenRepository
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{UID: uid, ImageURL: "https://storage.googleapis.com/bucket/imageobject"}
+
+		us, mockUserRepository, mockImageRepository, mockTokenRepository := setup(u)
+
+		mockImageRepository.On("DeleteProfile", mock.Anything, "imageobject").Return(nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, "", model.ImageThumbnails(nil)).Return(&model.User{UID: uid}, nil)
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockUserRepository.On("Delete", mock.Anything, uid).Return(nil)
+		err := us.Delete(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockImageRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+
+		published := broker.Events()
+		assert.Len(t, published, 2)
+		assert.Equal(t, model.EventUserImageChanged, published[0].Type)
+		assert.Equal(t, model.EventUserDeleted, published[1].Type)
+		assert.Equal(t, uid, published[1].UID)
+		assert.Nil(t, published[1].User)
+	})
+
+	t.Run("Image removal fails", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		u := &model.User{UID: uid, ImageURL: "https://storage.googleapis.com/bucket/imageobject"}
+
+		us, mockUserRepository, mockImageRepository, mockTokenRepository := setup(u)
+
+		mockImageRepository.On("DeleteProfile", mock.Anything, "imageobject").Return(apperrors.NewInternal())
+
+		err := us.Delete(context.TODO(), uid)
+
+		assert.Equal(t, http.StatusInternalServerError, apperrors.Status(err))
+		mockTokenRepository.AssertNotCalled(t, "DeleateUserRefreshTokens", mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		assert.Empty(t, broker.Events())
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	hashedPassword, _ := hashPassword("currentpassword", DefaultPasswordParams)
+
+	newService := func(gracePeriod time.Duration) (model.UserService, *mocks.MockUserRepository, *mocks.MockTokenRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:      mockUserRepository,
+			TokenRepository:     mockTokenRepository,
+			DeletionGracePeriod: gracePeriod,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{
+			UID:      uid,
+			Email:    "bob@bob.com",
+			Password: hashedPassword,
+		}, nil)
+
+		return us, mockUserRepository, mockTokenRepository
+	}
+
+	t.Run("Deleted straight away", func(t *testing.T) {
+		us, mockUserRepository, mockTokenRepository := newService(0)
+
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+		mockUserRepository.On("Delete", mock.Anything, uid).Return(nil)
+
+		err := us.DeleteAccount(context.TODO(), uid, "currentpassword")
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockUserRepository.AssertNotCalled(t, "SoftDelete", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Grace period", func(t *testing.T) {
+		us, mockUserRepository, mockTokenRepository := newService(30 * 24 * time.Hour)
+
+		mockUserRepository.On("SoftDelete", mock.Anything, uid, mock.AnythingOfType("time.Time")).Return(nil)
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+
+		err := us.DeleteAccount(context.TODO(), uid, "currentpassword")
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+		mockUserRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Incorrect password", func(t *testing.T) {
+		us, mockUserRepository, mockTokenRepository := newService(0)
+
+		err := us.DeleteAccount(context.TODO(), uid, "wrongpassword")
+
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+		mockTokenRepository.AssertNotCalled(t, "DeleateUserRefreshTokens", mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	uid1, _ := uuid.NewRandom()
+	uid2, _ := uuid.NewRandom()
+	gracePeriod := 30 * 24 * time.Hour
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockTokenRepository := new(mocks.MockTokenRepository)
+
+	us := NewUserService(&USConfig{
+		UserRepository:      mockUserRepository,
+		TokenRepository:     mockTokenRepository,
+		DeletionGracePeriod: gracePeriod,
+	})
+
+	users := []*model.User{{UID: uid1}, {UID: uid2}}
+
+	mockUserRepository.On("FindDeleted", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		// only accounts deleted a whole grace period ago
+		return time.Since(before) >= gracePeriod && time.Since(before) < gracePeriod+time.Minute
+	}), purgeBatchSize).Return(users, nil)
+
+	for _, u := range users {
+		mockUserRepository.On("FindByID", mock.Anything, u.UID).Return(u, nil)
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, u.UID.String()).Return(nil)
+	}
+
+	// a failure is left for the next run
+	mockUserRepository.On("Delete", mock.Anything, uid1).Return(apperrors.NewInternal())
+	mockUserRepository.On("Delete", mock.Anything, uid2).Return(nil)
+
+	purged, err := us.PurgeDeletedAccounts(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	mockUserRepository.AssertExpectations(t)
+}
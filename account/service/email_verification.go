@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// purpose claim of email verification tokens, so that no other token
+// signed with the same secret can be passed off as one
+const verifyEmailPurpose = "verify_email"
+
+type emailTokenCustomClaims struct {
+	UID     uuid.UUID `json:"uid"`
+	Email   string    `json:"email"`
+	Purpose string    `json:"purpose"`
+	jwt.StandardClaims
+}
+
+// generateEmailToken signs a token vouching that whoever holds it
+// received mail sent to email
+func generateEmailToken(uid uuid.UUID, email string, purpose string, key string, exp int64) (string, error) {
+	unixTime := time.Now().Unix()
+
+	claims := emailTokenCustomClaims{
+		UID:     uid,
+		Email:   email,
+		Purpose: purpose,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  unixTime,
+			ExpiresAt: unixTime + exp,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ss, err := token.SignedString([]byte(key))
+
+	if err != nil {
+		return "", err
+	}
+
+	return ss, nil
+}
+
+func validateEmailToken(tokenString string, purpose string, key string) (*emailTokenCustomClaims, error) {
+	claims := &emailTokenCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(key), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("Email token is invalid")
+	}
+
+	if claims.Purpose != purpose {
+		return nil, fmt.Errorf("Email token is for: %s, not: %s", claims.Purpose, purpose)
+	}
+
+	return claims, nil
+}
+
+// linkWithToken adds token to the query of the page at base
+func linkWithToken(base string, token string) (string, error) {
+	link, err := url.Parse(base)
+
+	if err != nil {
+		return "", err
+	}
+
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	return link.String(), nil
+}
+
+// SendVerificationEmail sends the user a link to verify their email address
+func (s *userService) SendVerificationEmail(ctx context.Context, uid uuid.UUID) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return err
+	}
+
+	if u.EmailVerified {
+		return apperrors.NewBadRequest("email is already verified")
+	}
+
+	return s.sendVerificationEmail(ctx, u)
+}
+
+func (s *userService) sendVerificationEmail(ctx context.Context, u *model.User) error {
+	if s.Mailer == nil {
+		return nil
+	}
+
+	token, err := generateEmailToken(u.UID, u.Email, verifyEmailPurpose, s.EmailTokenSecret, s.EmailTokenExpirationSecs)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to generate email verification token", "uid", u.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	link, err := linkWithToken(s.VerifyEmailURL, token)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to create email verification link", "err", err)
+		return apperrors.NewInternal()
+	}
+
+	email := &model.Email{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Please verify your email address by following the link below. The link expires in %v.\n\n%s\n",
+			time.Duration(s.EmailTokenExpirationSecs)*time.Second, link,
+		),
+	}
+
+	if err := s.Mailer.Send(ctx, email); err != nil {
+		s.Logger.Error(ctx, "Unable to send verification email", "uid", u.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email a verification token was sent to verified,
+// if it is still the user's email
+func (s *userService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	claims, err := validateEmailToken(token, verifyEmailPurpose, s.EmailTokenSecret)
+
+	if err != nil {
+		s.Logger.Debug(ctx, "Unable to validate or parse email verification token", "err", err)
+		return nil, apperrors.NewAuthorization("Email verification link is invalid or has expired")
+	}
+
+	u, err := s.UserRepository.SetEmailVerified(ctx, claims.UID, claims.Email)
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, apperrors.NewAuthorization("Email verification link is no longer valid")
+		}
+
+		return nil, err
+	}
+
+	return u, nil
+}
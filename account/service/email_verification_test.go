@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testEmailTokenSecret = "anemailtokensecret"
+
+func newEmailTestService() (model.UserService, *mocks.MockUserRepository, *mocks.MockMailer) {
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockMailer := new(mocks.MockMailer)
+
+	us := NewUserService(&USConfig{
+		UserRepository:           mockUserRepository,
+		Mailer:                   mockMailer,
+		EmailTokenSecret:         testEmailTokenSecret,
+		EmailTokenExpirationSecs: 60,
+		VerifyEmailURL:           "https://memrizr.test/verify?lang=en",
+	})
+
+	return us, mockUserRepository, mockMailer
+}
+
+// linkFromEmail extracts the link from an email's body
+func linkFromEmail(t *testing.T, email *model.Email) *url.URL {
+	for _, line := range strings.Split(email.Body, "\n") {
+		if strings.HasPrefix(line, "https://") {
+			link, err := url.Parse(line)
+			assert.NoError(t, err)
+			return link
+		}
+	}
+
+	t.Fatal("no link in email")
+	return nil
+}
+
+func TestSendVerificationEmail(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Sends token for the user's email", func(t *testing.T) {
+		us, mockUserRepository, mockMailer := newEmailTestService()
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+
+		var sent *model.Email
+		mockMailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+			Run(func(args mock.Arguments) {
+				sent = args.Get(1).(*model.Email)
+			}).Return(nil)
+
+		err := us.SendVerificationEmail(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "bob@bob.com", sent.To)
+
+		link := linkFromEmail(t, sent)
+		assert.Equal(t, "en", link.Query().Get("lang"))
+
+		claims, err := validateEmailToken(link.Query().Get("token"), verifyEmailPurpose, testEmailTokenSecret)
+		assert.NoError(t, err)
+		assert.Equal(t, uid, claims.UID)
+		assert.Equal(t, "bob@bob.com", claims.Email)
+	})
+
+	t.Run("Already verified", func(t *testing.T) {
+		us, mockUserRepository, mockMailer := newEmailTestService()
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true}, nil)
+
+		err := us.SendVerificationEmail(context.TODO(), uid)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Sent on signup", func(t *testing.T) {
+		us, mockUserRepository, mockMailer := newEmailTestService()
+
+		u := &model.User{Email: "alice@alice.com", Password: "howdyhoneighbor"}
+
+		mockUserRepository.On("Create", mock.Anything, u).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(e *model.Email) bool {
+			return e.To == "alice@alice.com"
+		})).Return(apperrors.NewInternal())
+
+		// failing to send doesn't fail the signup
+		err := us.Signup(context.TODO(), u)
+
+		assert.NoError(t, err)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("Sent when email changes", func(t *testing.T) {
+		us, mockUserRepository, mockMailer := newEmailTestService()
+
+		u := &model.User{UID: uid, Email: "new@bob.com"}
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true}, nil)
+		mockUserRepository.On("Update", mock.Anything, u).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.Anything).Return(nil)
+
+		err := us.UpdateDetails(context.TODO(), u)
+
+		assert.NoError(t, err)
+		mockMailer.AssertNumberOfCalls(t, "Send", 1)
+
+		// not when only other details change
+		u = &model.User{UID: uid, Email: "bob@bob.com", Name: "Bob"}
+		mockUserRepository.On("Update", mock.Anything, u).Return(nil)
+
+		err = us.UpdateDetails(context.TODO(), u)
+
+		assert.NoError(t, err)
+		mockMailer.AssertNumberOfCalls(t, "Send", 1)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockUserRepository, _ := newEmailTestService()
+
+		verifiedUser := &model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true}
+		mockUserRepository.On("SetEmailVerified", mock.Anything, uid, "bob@bob.com").Return(verifiedUser, nil)
+
+		token, _ := generateEmailToken(uid, "bob@bob.com", verifyEmailPurpose, testEmailTokenSecret, 60)
+
+		u, err := us.VerifyEmail(context.TODO(), token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, verifiedUser, u)
+	})
+
+	t.Run("Email has since changed", func(t *testing.T) {
+		us, mockUserRepository, _ := newEmailTestService()
+
+		mockUserRepository.On("SetEmailVerified", mock.Anything, uid, "old@bob.com").Return(nil, apperrors.NewNotFound("email", "old@bob.com"))
+
+		token, _ := generateEmailToken(uid, "old@bob.com", verifyEmailPurpose, testEmailTokenSecret, 60)
+
+		u, err := us.VerifyEmail(context.TODO(), token)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	invalidTokens := map[string]string{
+		"Expired":       mustEmailToken(uid, verifyEmailPurpose, testEmailTokenSecret, -60),
+		"Wrong purpose": mustEmailToken(uid, "reset_password", testEmailTokenSecret, 60),
+		"Wrong secret":  mustEmailToken(uid, verifyEmailPurpose, "someothersecret", 60),
+		"Garbage":       "notatoken",
+	}
+
+	for name, token := range invalidTokens {
+		t.Run(name, func(t *testing.T) {
+			us, mockUserRepository, _ := newEmailTestService()
+
+			u, err := us.VerifyEmail(context.TODO(), token)
+
+			assert.Nil(t, u)
+			assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+			mockUserRepository.AssertNotCalled(t, "SetEmailVerified", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func mustEmailToken(uid uuid.UUID, purpose string, secret string, exp int64) string {
+	token, err := generateEmailToken(uid, "bob@bob.com", purpose, secret, exp)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// newEvent describes something which just happened to a user. u is
+// the user as they are after it, if they still exist
+func newEvent(eventType string, uid uuid.UUID, u *model.User) *model.Event {
+	return &model.Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		UID:        uid,
+		OccurredAt: time.Now().UTC(),
+		User:       u,
+	}
+}
+
+// publishEvent publishes event with broker, when there is one
+func publishEvent(ctx context.Context, logger model.Logger, broker model.EventBroker, event *model.Event) error {
+	if broker == nil {
+		return nil
+	}
+
+	if err := broker.Publish(ctx, event); err != nil {
+		logger.Error(ctx, "Unable to publish event", "type", event.Type, "uid", event.UID, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// withinTransaction runs fn in a transaction of transactor, when there
+// is one, so that changes and the events describing them are stored
+// together or not at all
+func withinTransaction(ctx context.Context, transactor model.Transactor, fn func(ctx context.Context) error) error {
+	if transactor == nil {
+		return fn(ctx)
+	}
+
+	return transactor.WithinTransaction(ctx, fn)
+}
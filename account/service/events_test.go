@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/events"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fakeTxKey struct{}
+
+// fakeTransactor marks the contexts it runs functions with, so tests
+// can check what took part in a transaction, and records how each ended
+type fakeTransactor struct {
+	committed  int
+	rolledBack int
+}
+
+func (t *fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(context.WithValue(ctx, fakeTxKey{}, true)); err != nil {
+		t.rolledBack++
+		return err
+	}
+
+	t.committed++
+	return nil
+}
+
+// inTx matches a context given by a fakeTransactor
+var inTx = mock.MatchedBy(func(ctx context.Context) bool {
+	return ctx.Value(fakeTxKey{}) != nil
+})
+
+func TestUserEvents(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Signup publishes created in its transaction", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockEventBroker := new(mocks.MockEventBroker)
+		transactor := &fakeTransactor{}
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			EventBroker:    mockEventBroker,
+			Transactor:     transactor,
+		})
+
+		u := &model.User{Email: "bob@bob.com", Password: "passwordSample"}
+
+		mockUserRepository.On("Create", inTx, u).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.User).UID = uid
+		}).Return(nil)
+		mockEventBroker.On("Publish", inTx, mock.MatchedBy(func(e *model.Event) bool {
+			return e.Type == model.EventUserCreated && e.UID == uid && e.User == u && e.ID != uuid.Nil
+		})).Return(nil)
+
+		err := us.Signup(context.TODO(), u)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, transactor.committed)
+		mockUserRepository.AssertExpectations(t)
+		mockEventBroker.AssertExpectations(t)
+	})
+
+	t.Run("Signup fails when its event can't be stored", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockEventBroker := new(mocks.MockEventBroker)
+		mockMailer := new(mocks.MockMailer)
+		transactor := &fakeTransactor{}
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			EventBroker:    mockEventBroker,
+			Transactor:     transactor,
+			Mailer:         mockMailer,
+		})
+
+		u := &model.User{Email: "bob@bob.com", Password: "passwordSample"}
+
+		mockUserRepository.On("Create", inTx, u).Return(nil)
+		mockEventBroker.On("Publish", inTx, mock.Anything).Return(apperrors.NewInternal())
+
+		err := us.Signup(context.TODO(), u)
+
+		assert.Error(t, err)
+		// so the user isn't created without it
+		assert.Equal(t, 1, transactor.rolledBack)
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("UpdateDetails publishes updated", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		broker := events.NewMemoryBroker()
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			EventBroker:    broker,
+		})
+
+		u := &model.User{UID: uid, Email: "bob@bob.com", Name: "Bob"}
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+		mockUserRepository.On("Update", mock.Anything, u).Return(nil)
+
+		err := us.UpdateDetails(context.TODO(), u)
+
+		assert.NoError(t, err)
+
+		published := broker.Events()
+		assert.Len(t, published, 1)
+		assert.Equal(t, model.EventUserUpdated, published[0].Type)
+		assert.Equal(t, u, published[0].User)
+	})
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+type exportService struct {
+	UserRepository               model.UserRepository
+	TokenRepository              model.TokenRepository
+	UserIdentityRepository       model.UserIdentityRepository
+	WebAuthnCredentialRepository model.WebAuthnCredentialRepository
+	ImageRepository              model.ImageRepository
+	AuditRepository              model.AuditRepository
+	Logger                       model.Logger
+}
+
+// ESConfig will hold repositories that will eventually be injected into
+// this service layer. Each of them holds part of a user's data
+type ESConfig struct {
+	UserRepository               model.UserRepository
+	TokenRepository              model.TokenRepository
+	UserIdentityRepository       model.UserIdentityRepository
+	WebAuthnCredentialRepository model.WebAuthnCredentialRepository
+	ImageRepository              model.ImageRepository
+	AuditRepository              model.AuditRepository
+	Logger                       model.Logger
+}
+
+// NewExportService is a factory function for
+// initializing an ExportService with its repository layer dependencies
+func NewExportService(c *ESConfig) model.ExportService {
+	return &exportService{
+		UserRepository:               c.UserRepository,
+		TokenRepository:              c.TokenRepository,
+		UserIdentityRepository:       c.UserIdentityRepository,
+		WebAuthnCredentialRepository: c.WebAuthnCredentialRepository,
+		ImageRepository:              c.ImageRepository,
+		AuditRepository:              c.AuditRepository,
+		Logger:                       logging.OrDefault(c.Logger),
+	}
+}
+
+// Export gathers everything held about a user. Secrets, such as their
+// password hash, are left out by the models' JSON encoding
+func (s *exportService) Export(ctx context.Context, uid uuid.UUID) (*model.UserExport, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.TokenRepository.GetSessions(ctx, uid.String())
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to get sessions to export", "uid", uid, "err", err)
+		return nil, err
+	}
+
+	identities, err := s.UserIdentityRepository.FindByUser(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := s.WebAuthnCredentialRepository.FindByUser(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	activity, err := s.AuditRepository.Find(ctx, &model.AuditQuery{UID: &uid})
+
+	if err != nil {
+		return nil, err
+	}
+
+	profileImage, err := s.exportProfileImage(ctx, u)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserExport{
+		ExportedAt:   time.Now().UTC(),
+		User:         u,
+		Sessions:     sessions,
+		Identities:   identities,
+		Passkeys:     passkeys,
+		Activity:     activity,
+		ProfileImage: profileImage,
+	}, nil
+}
+
+// exportProfileImage fetches the user's profile image, if they have one.
+// Thumbnails are left out, as they're only smaller copies of it
+func (s *exportService) exportProfileImage(ctx context.Context, u *model.User) (*model.ExportedImage, error) {
+	if u.ImageURL == "" {
+		return nil, nil
+	}
+
+	objName, err := objNameFromURL(u.ImageURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.ImageRepository.GetProfile(ctx, objName)
+
+	// an image which is already gone holds nothing to export
+	if apperrors.Status(err) == http.StatusNotFound {
+		s.Logger.Warn(ctx, "Profile image to export is missing", "uid", u.UID, "imageURL", u.ImageURL)
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ExportedImage{
+		Name:        objName,
+		ContentType: http.DetectContentType(data),
+		Data:        data,
+	}, nil
+}
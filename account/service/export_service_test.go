@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExport(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	sessions := []*model.Session{{ID: "asession", UserAgent: "Firefox"}}
+	identities := []*model.UserIdentity{{Provider: "github", Subject: "1234", UID: uid}}
+	passkeys := []*model.WebAuthnCredential{{ID: []byte("acredential"), UID: uid, Name: "My laptop"}}
+	activity := []*model.AuditEntry{{ID: 1, UID: &uid, Action: model.AuditSignin, Outcome: model.AuditSuccess, IP: "203.0.113.7"}}
+
+	setup := func(u *model.User) (model.ExportService, *mocks.MockImageRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockUserIdentityRepository := new(mocks.MockUserIdentityRepository)
+		mockWebAuthnCredentialRepository := new(mocks.MockWebAuthnCredentialRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		es := NewExportService(&ESConfig{
+			UserRepository:               mockUserRepository,
+			TokenRepository:              mockTokenRepository,
+			UserIdentityRepository:       mockUserIdentityRepository,
+			WebAuthnCredentialRepository: mockWebAuthnCredentialRepository,
+			ImageRepository:              mockImageRepository,
+			AuditRepository:              mockAuditRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+		mockTokenRepository.On("GetSessions", mock.Anything, uid.String()).Return(sessions, nil)
+		mockUserIdentityRepository.On("FindByUser", mock.Anything, uid).Return(identities, nil)
+		mockWebAuthnCredentialRepository.On("FindByUser", mock.Anything, uid).Return(passkeys, nil)
+		mockAuditRepository.On("Find", mock.Anything, &model.AuditQuery{UID: &uid}).Return(activity, nil)
+
+		return es, mockImageRepository
+	}
+
+	t.Run("With profile image", func(t *testing.T) {
+		u := &model.User{UID: uid, Email: "bob@bob.com", ImageURL: "https://storage.googleapis.com/bucket/imageobject"}
+		es, mockImageRepository := setup(u)
+
+		png := []byte("\x89PNG\r\n\x1a\n")
+		mockImageRepository.On("GetProfile", mock.Anything, "imageobject").Return(png, nil)
+
+		export, err := es.Export(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u, export.User)
+		assert.Equal(t, sessions, export.Sessions)
+		assert.Equal(t, identities, export.Identities)
+		assert.Equal(t, passkeys, export.Passkeys)
+		assert.Equal(t, activity, export.Activity)
+		assert.Equal(t, &model.ExportedImage{
+			Name:        "imageobject",
+			ContentType: "image/png",
+			Data:        png,
+		}, export.ProfileImage)
+		assert.False(t, export.ExportedAt.IsZero())
+	})
+
+	t.Run("Without profile image", func(t *testing.T) {
+		es, mockImageRepository := setup(&model.User{UID: uid})
+
+		export, err := es.Export(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Nil(t, export.ProfileImage)
+		mockImageRepository.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Missing profile image", func(t *testing.T) {
+		es, mockImageRepository := setup(&model.User{UID: uid, ImageURL: "https://storage.googleapis.com/bucket/imageobject"})
+
+		mockImageRepository.On("GetProfile", mock.Anything, "imageobject").Return(nil, apperrors.NewNotFound("image", "imageobject"))
+
+		export, err := es.Export(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Nil(t, export.ProfileImage)
+	})
+
+	t.Run("Image store failure", func(t *testing.T) {
+		es, mockImageRepository := setup(&model.User{UID: uid, ImageURL: "https://storage.googleapis.com/bucket/imageobject"})
+
+		mockImageRepository.On("GetProfile", mock.Anything, "imageobject").Return(nil, apperrors.NewInternal())
+
+		export, err := es.Export(context.TODO(), uid)
+
+		assert.Error(t, err)
+		assert.Nil(t, export)
+	})
+}
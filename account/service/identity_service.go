@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// state, nonce and PKCE code verifier lengths, in random bytes
+const oauthRandomLength = 32
+
+type identityService struct {
+	UserRepository      model.UserRepository
+	IdentityRepository  model.UserIdentityRepository
+	StateRepository     model.OAuthStateRepository
+	Providers           map[string]model.IdentityProvider
+	StateExpirationSecs int64
+	EventBroker         model.EventBroker
+	Transactor          model.Transactor
+	Logger              model.Logger
+}
+
+// ISConfig will hold repositories that will eventually be injected into
+// this service layer. Providers maps the names used in URLs (eg, google)
+// to the identity providers users can sign in with. Users created at
+// signin are published as events with an EventBroker
+type ISConfig struct {
+	UserRepository      model.UserRepository
+	IdentityRepository  model.UserIdentityRepository
+	StateRepository     model.OAuthStateRepository
+	Providers           map[string]model.IdentityProvider
+	StateExpirationSecs int64
+	EventBroker         model.EventBroker
+	Transactor          model.Transactor
+	Logger              model.Logger
+}
+
+// NewIdentityService is a factory function for
+// initializing an IdentityService with its repository layer dependencies
+func NewIdentityService(c *ISConfig) model.IdentityService {
+	return &identityService{
+		UserRepository:      c.UserRepository,
+		IdentityRepository:  c.IdentityRepository,
+		StateRepository:     c.StateRepository,
+		Providers:           c.Providers,
+		StateExpirationSecs: c.StateExpirationSecs,
+		EventBroker:         c.EventBroker,
+		Transactor:          c.Transactor,
+		Logger:              logging.OrDefault(c.Logger),
+	}
+}
+
+func randomURLString() (string, error) {
+	b := make([]byte, oauthRandomLength)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// pkceChallenge is the S256 code challenge for a code verifier
+func pkceChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL starts a signin with provider, returning the URL to send
+// the user to and the state they'll be sent back with
+func (s *identityService) AuthCodeURL(ctx context.Context, provider string) (string, string, error) {
+	p, ok := s.Providers[provider]
+
+	if !ok {
+		return "", "", apperrors.NewNotFound("provider", provider)
+	}
+
+	var values [3]string
+
+	for i := range values {
+		v, err := randomURLString()
+
+		if err != nil {
+			s.Logger.Error(ctx, "Unable to generate OAuth state", "provider", provider, "err", err)
+			return "", "", apperrors.NewInternal()
+		}
+
+		values[i] = v
+	}
+
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+
+	expiresIn := time.Duration(s.StateExpirationSecs) * time.Second
+
+	if err := s.StateRepository.SetState(ctx, state, &model.OAuthState{
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+	}, expiresIn); err != nil {
+		return "", "", err
+	}
+
+	url, err := p.AuthCodeURL(ctx, state, nonce, pkceChallenge(codeVerifier))
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to get authorization URL", "provider", provider, "err", err)
+		return "", "", apperrors.NewServiceUnavailable()
+	}
+
+	return url, state, nil
+}
+
+// Signin completes a signin when the user returns from the provider with
+// an authorization code. The user is found by their linked identity, or
+// else by the provider's verified email, which links the identity. A
+// user is created for a verified email no user has
+func (s *identityService) Signin(ctx context.Context, state string, code string) (*model.User, error) {
+	st, err := s.StateRepository.ConsumeState(ctx, state)
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, apperrors.NewAuthorization("Sign in has expired or was already completed")
+		}
+
+		return nil, err
+	}
+
+	p, ok := s.Providers[st.Provider]
+
+	if !ok {
+		return nil, apperrors.NewNotFound("provider", st.Provider)
+	}
+
+	ext, err := p.Exchange(ctx, code, st.CodeVerifier, st.Nonce)
+
+	if err != nil {
+		s.Logger.Info(ctx, "Unable to complete signin with provider", "provider", st.Provider, "err", err)
+		return nil, apperrors.NewAuthorization("Unable to sign in with " + st.Provider)
+	}
+
+	identity, err := s.IdentityRepository.FindByProviderSubject(ctx, st.Provider, ext.Subject)
+
+	if err == nil {
+		return s.UserRepository.FindByID(ctx, identity.UID)
+	}
+
+	if apperrors.Status(err) != http.StatusNotFound {
+		return nil, err
+	}
+
+	if ext.Email == "" || !ext.EmailVerified {
+		return nil, apperrors.NewBadRequest("Your " + st.Provider + " account does not have a verified email")
+	}
+
+	u, err := s.userForEmail(ctx, ext)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.IdentityRepository.Create(ctx, &model.UserIdentity{
+		Provider: st.Provider,
+		Subject:  ext.Subject,
+		UID:      u.UID,
+		Email:    ext.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// userForEmail finds the user an identity with a new subject is linked
+// to. Linking to an existing user requires they verified the email too,
+// or whoever signed up with it could be let into another's account
+func (s *identityService) userForEmail(ctx context.Context, ext *model.ExternalIdentity) (*model.User, error) {
+	u, err := s.UserRepository.FindByEmail(ctx, ext.Email)
+
+	if err == nil {
+		if !u.EmailVerified {
+			return nil, apperrors.NewConflict("email", ext.Email)
+		}
+
+		return u, nil
+	}
+
+	if apperrors.Status(err) != http.StatusNotFound {
+		return nil, err
+	}
+
+	// the user has no password until they reset one
+	u = &model.User{Email: ext.Email}
+
+	err = withinTransaction(ctx, s.Transactor, func(ctx context.Context) error {
+		if err := s.UserRepository.Create(ctx, u); err != nil {
+			return err
+		}
+
+		verified, err := s.UserRepository.SetEmailVerified(ctx, u.UID, u.Email)
+
+		if err != nil {
+			return err
+		}
+
+		u = verified
+
+		if ext.Name != "" {
+			u.Name = ext.Name
+
+			if err := s.UserRepository.Update(ctx, u); err != nil {
+				s.Logger.Warn(ctx, "Unable to set name of new user", "uid", u.UID, "err", err)
+				return err
+			}
+		}
+
+		return publishEvent(ctx, s.Logger, s.EventBroker, newEvent(model.EventUserCreated, u.UID, u))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
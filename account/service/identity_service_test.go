@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestIdentityService() (model.IdentityService, *mocks.MockUserRepository, *mocks.MockUserIdentityRepository, *mocks.MockOAuthStateRepository, *mocks.MockIdentityProvider) {
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockIdentityRepository := new(mocks.MockUserIdentityRepository)
+	mockStateRepository := new(mocks.MockOAuthStateRepository)
+	mockProvider := new(mocks.MockIdentityProvider)
+
+	is := NewIdentityService(&ISConfig{
+		UserRepository:      mockUserRepository,
+		IdentityRepository:  mockIdentityRepository,
+		StateRepository:     mockStateRepository,
+		Providers:           map[string]model.IdentityProvider{"google": mockProvider},
+		StateExpirationSecs: 600,
+	})
+
+	return is, mockUserRepository, mockIdentityRepository, mockStateRepository, mockProvider
+}
+
+func TestIdentityAuthCodeURL(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		is, _, _, mockStateRepository, mockProvider := newTestIdentityService()
+
+		var state string
+		var stored *model.OAuthState
+
+		mockStateRepository.On("SetState", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.OAuthState"), 600*time.Second).Return(nil).Run(func(args mock.Arguments) {
+			state = args.String(1)
+			stored = args.Get(2).(*model.OAuthState)
+		})
+
+		mockProvider.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("https://accounts.google.test/authorize", nil)
+
+		url, returnedState, err := is.AuthCodeURL(context.TODO(), "google")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://accounts.google.test/authorize", url)
+		assert.Equal(t, state, returnedState)
+
+		assert.Equal(t, "google", stored.Provider)
+		assert.NotEmpty(t, state)
+		assert.NotEqual(t, state, stored.Nonce)
+		assert.NotEqual(t, state, stored.CodeVerifier)
+
+		// the provider is sent the verifier's S256 challenge, never the verifier
+		mockProvider.AssertCalled(t, "AuthCodeURL", mock.Anything, state, stored.Nonce, pkceChallenge(stored.CodeVerifier))
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		is, _, _, mockStateRepository, _ := newTestIdentityService()
+
+		_, _, err := is.AuthCodeURL(context.TODO(), "myspace")
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+
+		mockStateRepository.AssertNotCalled(t, "SetState", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	// RFC 7636 Appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", pkceChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
+
+func TestIdentitySignin(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	state := &model.OAuthState{
+		Provider:     "google",
+		Nonce:        "nonce",
+		CodeVerifier: "verifier",
+	}
+
+	ext := &model.ExternalIdentity{
+		Provider:      "google",
+		Subject:       "12345",
+		Email:         "bob@bob.com",
+		EmailVerified: true,
+		Name:          "Bob",
+	}
+
+	// setup returns a service where the state is valid and the code is
+	// exchanged for identity
+	setup := func(identity *model.ExternalIdentity) (model.IdentityService, *mocks.MockUserRepository, *mocks.MockUserIdentityRepository) {
+		is, mockUserRepository, mockIdentityRepository, mockStateRepository, mockProvider := newTestIdentityService()
+
+		mockStateRepository.On("ConsumeState", mock.Anything, "state").Return(state, nil)
+		mockProvider.On("Exchange", mock.Anything, "code", "verifier", "nonce").Return(identity, nil)
+
+		return is, mockUserRepository, mockIdentityRepository
+	}
+
+	t.Run("Linked identity", func(t *testing.T) {
+		is, mockUserRepository, mockIdentityRepository := setup(ext)
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "google", "12345").Return(&model.UserIdentity{UID: uid}, nil)
+
+		// the email at the provider no longer matters
+		u := &model.User{UID: uid, Email: "old@bob.com"}
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+
+		got, err := is.Signin(context.TODO(), "state", "code")
+		assert.NoError(t, err)
+		assert.Equal(t, u, got)
+
+		mockIdentityRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Links user with verified email", func(t *testing.T) {
+		is, mockUserRepository, mockIdentityRepository := setup(ext)
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "google", "12345").Return(nil, apperrors.NewNotFound("identity", "google"))
+
+		u := &model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true}
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(u, nil)
+
+		identity := &model.UserIdentity{Provider: "google", Subject: "12345", UID: uid, Email: "bob@bob.com"}
+		mockIdentityRepository.On("Create", mock.Anything, identity).Return(nil)
+
+		got, err := is.Signin(context.TODO(), "state", "code")
+		assert.NoError(t, err)
+		assert.Equal(t, u, got)
+
+		mockIdentityRepository.AssertCalled(t, "Create", mock.Anything, identity)
+		mockUserRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Won't link user with unverified email", func(t *testing.T) {
+		is, mockUserRepository, mockIdentityRepository := setup(ext)
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "google", "12345").Return(nil, apperrors.NewNotFound("identity", "google"))
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+
+		_, err := is.Signin(context.TODO(), "state", "code")
+		assert.Equal(t, http.StatusConflict, apperrors.Status(err))
+
+		mockIdentityRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Creates user", func(t *testing.T) {
+		is, mockUserRepository, mockIdentityRepository := setup(ext)
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "google", "12345").Return(nil, apperrors.NewNotFound("identity", "google"))
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(nil, apperrors.NewNotFound("email", "bob@bob.com"))
+
+		mockUserRepository.On("Create", mock.Anything, &model.User{Email: "bob@bob.com"}).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.User).UID = uid
+		})
+
+		verified := &model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true}
+		mockUserRepository.On("SetEmailVerified", mock.Anything, uid, "bob@bob.com").Return(verified, nil)
+		mockUserRepository.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
+
+		identity := &model.UserIdentity{Provider: "google", Subject: "12345", UID: uid, Email: "bob@bob.com"}
+		mockIdentityRepository.On("Create", mock.Anything, identity).Return(nil)
+
+		got, err := is.Signin(context.TODO(), "state", "code")
+		assert.NoError(t, err)
+		assert.Equal(t, uid, got.UID)
+		assert.True(t, got.EmailVerified)
+		assert.Equal(t, "Bob", got.Name)
+		assert.Empty(t, got.Password)
+
+		mockIdentityRepository.AssertCalled(t, "Create", mock.Anything, identity)
+	})
+
+	t.Run("Unverified provider email", func(t *testing.T) {
+		unverified := *ext
+		unverified.EmailVerified = false
+
+		is, mockUserRepository, mockIdentityRepository := setup(&unverified)
+
+		mockIdentityRepository.On("FindByProviderSubject", mock.Anything, "google", "12345").Return(nil, apperrors.NewNotFound("identity", "google"))
+
+		_, err := is.Signin(context.TODO(), "state", "code")
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Expired or reused state", func(t *testing.T) {
+		is, _, _, mockStateRepository, mockProvider := newTestIdentityService()
+
+		mockStateRepository.On("ConsumeState", mock.Anything, "state").Return(nil, apperrors.NewNotFound("oauth state", "state"))
+
+		_, err := is.Signin(context.TODO(), "state", "code")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+
+		mockProvider.AssertNotCalled(t, "Exchange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Exchange fails", func(t *testing.T) {
+		is, _, _, mockStateRepository, mockProvider := newTestIdentityService()
+
+		mockStateRepository.On("ConsumeState", mock.Anything, "state").Return(state, nil)
+		mockProvider.On("Exchange", mock.Anything, "code", "verifier", "nonce").Return(nil, errors.New("invalid_grant"))
+
+		_, err := is.Signin(context.TODO(), "state", "code")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// keyID computes the RFC 7638 JWK thumbprint of key, so a key's
+// kid never needs to be configured and can't get out of sync
+func keyID(key *rsa.PublicKey) string {
+	jwk := toJWK(key, "")
+
+	// members in lexicographic order and without whitespace, per the RFC
+	thumbprintInput := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	sum := sha256.Sum256([]byte(thumbprintInput))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func toJWK(key *rsa.PublicKey, kid string) model.JWK {
+	return model.JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// EnrollTOTP starts enrolling a user in TOTP two-factor authentication.
+// The new secret isn't used at signin until ConfirmTOTP shows the user's
+// authenticator app has it
+func (s *userService) EnrollTOTP(ctx context.Context, uid uuid.UUID) (*model.TOTPEnrollment, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.TOTPEnabled {
+		return nil, apperrors.NewBadRequest("Two-factor authentication is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to generate TOTP secret", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdateTOTP(ctx, uid, secret, false, nil); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(s.TOTPIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication with a first code from
+// the user's authenticator app. It returns the user's recovery codes,
+// which can't be retrieved again
+func (s *userService) ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) (_ []string, err error) {
+	defer func() {
+		recordAudit(ctx, s.Logger, s.AuditRepository, &uid, model.AuditTOTPEnable, err)
+	}()
+
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.TOTPEnabled {
+		return nil, apperrors.NewBadRequest("Two-factor authentication is already enabled")
+	}
+
+	if u.TOTPSecret == "" {
+		return nil, apperrors.NewBadRequest("Two-factor authentication enrollment has not been started")
+	}
+
+	ok, err := s.useTOTPCode(ctx, u, code)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, apperrors.NewBadRequest("Invalid two-factor authentication code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to generate recovery codes", "uid", uid, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdateTOTP(ctx, uid, u.TOTPSecret, true, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns off two-factor authentication, once the user has
+// confirmed their password
+func (s *userService) DisableTOTP(ctx context.Context, uid uuid.UUID, password string) (err error) {
+	defer func() {
+		recordAudit(ctx, s.Logger, s.AuditRepository, &uid, model.AuditTOTPDisable, err)
+	}()
+
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return err
+	}
+
+	if !u.TOTPEnabled {
+		return apperrors.NewBadRequest("Two-factor authentication is not enabled")
+	}
+
+	if err := s.confirmPassword(ctx, u, password); err != nil {
+		return err
+	}
+
+	return s.UserRepository.UpdateTOTP(ctx, uid, "", false, nil)
+}
+
+// VerifyMFA checks the second factor of a user who has signed in with
+// their password, which is either a TOTP code or an unused recovery code.
+// Wrong codes count as failed signins
+func (s *userService) VerifyMFA(ctx context.Context, uid uuid.UUID, code string) (*model.User, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// disabled since the user signed in
+	if !u.TOTPEnabled {
+		return nil, apperrors.NewAuthorization("Two-factor authentication has expired, please sign in again")
+	}
+
+	var attemptKeys []signinAttemptKey
+
+	if s.SigninAttemptRepository != nil {
+		attemptKeys = s.signinAttemptKeys(ctx, u.Email)
+
+		if err := s.checkSigninLockout(ctx, attemptKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	var ok bool
+
+	if isTOTPCode(code) {
+		ok, err = s.useTOTPCode(ctx, u, code)
+	} else {
+		ok, err = s.useRecoveryCode(ctx, u, code)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		s.recordSigninFailure(ctx, attemptKeys)
+		return nil, apperrors.NewAuthorization("Invalid two-factor authentication code")
+	}
+
+	s.resetEmailSigninFailures(ctx, u.Email)
+
+	return u, nil
+}
+
+// useTOTPCode checks code against the user's TOTP secret. Each code is
+// only accepted once, so one seen over the user's shoulder can't be reused
+func (s *userService) useTOTPCode(ctx context.Context, u *model.User, code string) (bool, error) {
+	step, ok := validateTOTP(u.TOTPSecret, code, time.Now())
+
+	if !ok {
+		return false, nil
+	}
+
+	if err := s.UserRepository.UseTOTPStep(ctx, u.UID, step); err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *userService) useRecoveryCode(ctx context.Context, u *model.User, code string) (bool, error) {
+	if err := s.UserRepository.UseRecoveryCode(ctx, u.UID, hashRecoveryCode(code)); err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// currentTOTPCode is the code an authenticator app would show for secret
+func currentTOTPCode(secret string) string {
+	key, _ := totpEncoding.DecodeString(secret)
+	return hotp(key, uint64(totpStep(time.Now())))
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			TOTPIssuer:     "Memrizr",
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+		mockUserRepository.On("UpdateTOTP", mock.Anything, uid, mock.AnythingOfType("string"), false, model.RecoveryCodes(nil)).Return(nil)
+
+		enrollment, err := us.EnrollTOTP(context.TODO(), uid)
+		assert.NoError(t, err)
+
+		uri, err := url.Parse(enrollment.URI)
+		assert.NoError(t, err)
+		assert.Equal(t, enrollment.Secret, uri.Query().Get("secret"))
+		assert.Equal(t, "/Memrizr:bob@bob.com", uri.Path)
+
+		// the stored secret is the one returned
+		mockUserRepository.AssertCalled(t, "UpdateTOTP", mock.Anything, uid, enrollment.Secret, false, model.RecoveryCodes(nil))
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, TOTPSecret: "JBSWY3DPEHPK3PXP", TOTPEnabled: true}, nil)
+
+		enrollment, err := us.EnrollTOTP(context.TODO(), uid)
+
+		assert.Nil(t, enrollment)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdateTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	secret, _ := generateTOTPSecret()
+
+	newService := func(u *model.User) (model.UserService, *mocks.MockUserRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+
+		return us, mockUserRepository
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockUserRepository := newService(&model.User{UID: uid, TOTPSecret: secret})
+
+		mockUserRepository.On("UseTOTPStep", mock.Anything, uid, mock.AnythingOfType("int64")).Return(nil)
+		mockUserRepository.On("UpdateTOTP", mock.Anything, uid, secret, true, mock.AnythingOfType("model.RecoveryCodes")).Return(nil)
+
+		codes, err := us.ConfirmTOTP(context.TODO(), uid, currentTOTPCode(secret))
+
+		assert.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+
+		// only hashes of the recovery codes are stored
+		hashes := mockUserRepository.Calls[2].Arguments.Get(4).(model.RecoveryCodes)
+		for i, code := range codes {
+			assert.NotContains(t, hashes, code)
+			assert.Equal(t, hashRecoveryCode(code), hashes[i])
+		}
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		us, mockUserRepository := newService(&model.User{UID: uid, TOTPSecret: secret})
+
+		codes, err := us.ConfirmTOTP(context.TODO(), uid, "000000")
+
+		// in the unlikely case 000000 is the current code
+		if err == nil {
+			t.Skip("000000 is the current code")
+		}
+
+		assert.Nil(t, codes)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdateTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		us, _ := newService(&model.User{UID: uid})
+
+		codes, err := us.ConfirmTOTP(context.TODO(), uid, "123456")
+
+		assert.Nil(t, codes)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		us, _ := newService(&model.User{UID: uid, TOTPSecret: secret, TOTPEnabled: true})
+
+		codes, err := us.ConfirmTOTP(context.TODO(), uid, currentTOTPCode(secret))
+
+		assert.Nil(t, codes)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+}
+
+func TestDisableTOTP(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	hashedPassword, _ := hashPassword("currentpassword", DefaultPasswordParams)
+
+	newService := func() (model.UserService, *mocks.MockUserRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{
+			UID:         uid,
+			Email:       "bob@bob.com",
+			Password:    hashedPassword,
+			TOTPSecret:  "JBSWY3DPEHPK3PXP",
+			TOTPEnabled: true,
+		}, nil)
+
+		return us, mockUserRepository
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockUserRepository := newService()
+
+		mockUserRepository.On("UpdateTOTP", mock.Anything, uid, "", false, model.RecoveryCodes(nil)).Return(nil)
+
+		err := us.DisableTOTP(context.TODO(), uid, "currentpassword")
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Incorrect password", func(t *testing.T) {
+		us, mockUserRepository := newService()
+
+		err := us.DisableTOTP(context.TODO(), uid, "wrongpassword")
+
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdateTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestVerifyMFA(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	secret, _ := generateTOTPSecret()
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockSigninAttemptRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockSigninAttemptRepository := new(mocks.MockSigninAttemptRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:          mockUserRepository,
+			SigninAttemptRepository: mockSigninAttemptRepository,
+			EmailLockout: LockoutPolicy{
+				FreeAttempts: 3,
+				BaseDelay:    time.Second,
+				MaxDelay:     time.Minute,
+				Window:       time.Hour,
+			},
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{
+			UID:         uid,
+			Email:       "bob@bob.com",
+			TOTPSecret:  secret,
+			TOTPEnabled: true,
+		}, nil)
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, "email:bob@bob.com").Return(time.Duration(0), nil)
+
+		return us, mockUserRepository, mockSigninAttemptRepository
+	}
+
+	t.Run("TOTP code", func(t *testing.T) {
+		us, mockUserRepository, mockSigninAttemptRepository := newService()
+
+		mockUserRepository.On("UseTOTPStep", mock.Anything, uid, totpStep(time.Now())).Return(nil)
+		mockSigninAttemptRepository.On("ResetFailures", mock.Anything, "email:bob@bob.com").Return(nil)
+
+		u, err := us.VerifyMFA(context.TODO(), uid, currentTOTPCode(secret))
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+		mockUserRepository.AssertExpectations(t)
+		mockSigninAttemptRepository.AssertExpectations(t)
+	})
+
+	t.Run("Reused TOTP code", func(t *testing.T) {
+		us, mockUserRepository, mockSigninAttemptRepository := newService()
+
+		mockUserRepository.On("UseTOTPStep", mock.Anything, uid, mock.AnythingOfType("int64")).Return(apperrors.NewNotFound("totp_step", "1"))
+		mockSigninAttemptRepository.On("IncrementFailures", mock.Anything, "email:bob@bob.com", time.Hour).Return(int64(1), nil)
+
+		u, err := us.VerifyMFA(context.TODO(), uid, currentTOTPCode(secret))
+
+		assert.Nil(t, u)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockSigninAttemptRepository.AssertExpectations(t)
+	})
+
+	t.Run("Recovery code", func(t *testing.T) {
+		us, mockUserRepository, mockSigninAttemptRepository := newService()
+
+		mockUserRepository.On("UseRecoveryCode", mock.Anything, uid, hashRecoveryCode("abcd-efgh-ijkl-mnop")).Return(nil)
+		mockSigninAttemptRepository.On("ResetFailures", mock.Anything, "email:bob@bob.com").Return(nil)
+
+		u, err := us.VerifyMFA(context.TODO(), uid, "ABCDEFGHIJKLMNOP")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Unknown recovery code", func(t *testing.T) {
+		us, mockUserRepository, mockSigninAttemptRepository := newService()
+
+		mockUserRepository.On("UseRecoveryCode", mock.Anything, uid, mock.Anything).Return(apperrors.NewNotFound("recovery_code", ""))
+		mockSigninAttemptRepository.On("IncrementFailures", mock.Anything, "email:bob@bob.com", time.Hour).Return(int64(1), nil)
+
+		u, err := us.VerifyMFA(context.TODO(), uid, "abcd-efgh-ijkl-mnop")
+
+		assert.Nil(t, u)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockSigninAttemptRepository.AssertExpectations(t)
+	})
+
+	t.Run("Locked out", func(t *testing.T) {
+		us, mockUserRepository, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.ExpectedCalls = nil
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, "email:bob@bob.com").Return(time.Minute, nil)
+
+		u, err := us.VerifyMFA(context.TODO(), uid, currentTOTPCode(secret))
+
+		assert.Nil(t, u)
+		assert.Equal(t, http.StatusTooManyRequests, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UseTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
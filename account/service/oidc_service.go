@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// scopes clients may request. Others are ignored
+var oidcScopes = []string{"openid", "email", "profile"}
+
+type oidcService struct {
+	UserRepository     model.UserRepository
+	CodeRepository     model.OIDCCodeRepository
+	Clients            map[string]*model.OIDCClient
+	Issuer             string
+	LoginURL           string
+	CodeExpirationSecs int64
+	Logger             model.Logger
+}
+
+// OSConfig will hold repositories that will eventually be injected into
+// this service layer. Issuer is the public URL of the account API, which
+// the OpenID Connect endpoints are under. Users are sent to LoginURL, a
+// page of the web client, to sign in and approve a client's request,
+// which is passed on in its query string
+type OSConfig struct {
+	UserRepository     model.UserRepository
+	CodeRepository     model.OIDCCodeRepository
+	Clients            []*model.OIDCClient
+	Issuer             string
+	LoginURL           string
+	CodeExpirationSecs int64
+	Logger             model.Logger
+}
+
+// NewOIDCService is a factory function for
+// initializing an OIDCService with its repository layer dependencies
+func NewOIDCService(c *OSConfig) model.OIDCService {
+	clients := make(map[string]*model.OIDCClient, len(c.Clients))
+
+	for _, client := range c.Clients {
+		clients[client.ID] = client
+	}
+
+	return &oidcService{
+		UserRepository:     c.UserRepository,
+		CodeRepository:     c.CodeRepository,
+		Clients:            clients,
+		Issuer:             strings.TrimSuffix(c.Issuer, "/"),
+		LoginURL:           c.LoginURL,
+		CodeExpirationSecs: c.CodeExpirationSecs,
+		Logger:             logging.OrDefault(c.Logger),
+	}
+}
+
+// hasScope reports whether a space separated list of scopes includes scope
+func hasScope(scopes string, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hashAuthorizationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Discovery returns the OpenID Connect discovery document
+func (s *oidcService) Discovery() *model.OIDCDiscovery {
+	return &model.OIDCDiscovery{
+		Issuer:                            s.Issuer,
+		AuthorizationEndpoint:             s.Issuer + "/oidc/authorize",
+		TokenEndpoint:                     s.Issuer + "/oidc/token",
+		UserinfoEndpoint:                  s.Issuer + "/oidc/userinfo",
+		JWKSURI:                           s.Issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   oidcScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified", "name", "picture"},
+	}
+}
+
+// checkClient checks a request is from a registered client and
+// redirect URI. Until it is, errors can't be sent to the redirect URI
+func (s *oidcService) checkClient(req *model.OIDCAuthorizationRequest) (*model.OIDCClient, error) {
+	client, ok := s.Clients[req.ClientID]
+
+	if !ok {
+		return nil, apperrors.NewBadRequest("Unknown client_id")
+	}
+
+	for _, uri := range client.RedirectURIs {
+		if uri == req.RedirectURI {
+			return client, nil
+		}
+	}
+
+	return nil, apperrors.NewBadRequest("redirect_uri is not registered for the client")
+}
+
+// checkAuthorizationRequest checks the rest of a request from a known
+// client. Its errors are sent to the redirect URI
+func checkAuthorizationRequest(client *model.OIDCClient, req *model.OIDCAuthorizationRequest) *model.OAuthError {
+	if req.ResponseType != "code" {
+		return &model.OAuthError{Code: "unsupported_response_type", Description: "Only the code response type is supported"}
+	}
+
+	if !hasScope(req.Scope, "openid") {
+		return &model.OAuthError{Code: "invalid_scope", Description: "The openid scope is required"}
+	}
+
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		return &model.OAuthError{Code: "invalid_request", Description: "Only the S256 code_challenge_method is supported"}
+	}
+
+	if client.Secret == "" && req.CodeChallenge == "" {
+		return &model.OAuthError{Code: "invalid_request", Description: "Public clients must use PKCE"}
+	}
+
+	return nil
+}
+
+// redirectURL adds params and the request's state to its redirect URI
+func redirectURL(req *model.OIDCAuthorizationRequest, params url.Values) string {
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	u, _ := url.Parse(req.RedirectURI)
+	q := u.Query()
+
+	for k, v := range params {
+		q[k] = v
+	}
+
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+func errorRedirectURL(req *model.OIDCAuthorizationRequest, e *model.OAuthError) string {
+	return redirectURL(req, url.Values{
+		"error":             {e.Code},
+		"error_description": {e.Description},
+	})
+}
+
+func authorizationQuery(req *model.OIDCAuthorizationRequest) url.Values {
+	q := url.Values{
+		"client_id":     {req.ClientID},
+		"redirect_uri":  {req.RedirectURI},
+		"response_type": {req.ResponseType},
+		"scope":         {req.Scope},
+	}
+
+	optional := map[string]string{
+		"state":                 req.State,
+		"nonce":                 req.Nonce,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+	}
+
+	for k, v := range optional {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+
+	return q
+}
+
+// StartAuthorization checks a client's authorization request, returning
+// the URL of the login page to send the user to, or of the client's
+// redirect URI with an error
+func (s *oidcService) StartAuthorization(ctx context.Context, req *model.OIDCAuthorizationRequest) (string, error) {
+	client, err := s.checkClient(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	if e := checkAuthorizationRequest(client, req); e != nil {
+		return errorRedirectURL(req, e), nil
+	}
+
+	u, err := url.Parse(s.LoginURL)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to parse OIDC login URL", "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	q := u.Query()
+	for k, v := range authorizationQuery(req) {
+		q[k] = v
+	}
+
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// Authorize records that the signed in user approved a client's
+// request, returning the client's redirect URI with an authorization
+// code for the client to exchange for tokens
+func (s *oidcService) Authorize(ctx context.Context, uid uuid.UUID, req *model.OIDCAuthorizationRequest) (string, error) {
+	client, err := s.checkClient(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	if e := checkAuthorizationRequest(client, req); e != nil {
+		return errorRedirectURL(req, e), nil
+	}
+
+	code, err := randomURLString()
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to generate authorization code", "clientID", client.ID, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	var scopes []string
+	for _, scope := range oidcScopes {
+		if hasScope(req.Scope, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	expiresIn := time.Duration(s.CodeExpirationSecs) * time.Second
+
+	if err := s.CodeRepository.SetAuthorization(ctx, hashAuthorizationCode(code), &model.OIDCAuthorization{
+		ClientID:      client.ID,
+		RedirectURI:   req.RedirectURI,
+		UID:           uid,
+		Scope:         strings.Join(scopes, " "),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      time.Now().Unix(),
+	}, expiresIn); err != nil {
+		return "", err
+	}
+
+	return redirectURL(req, url.Values{"code": {code}}), nil
+}
+
+// AuthenticateClient checks a client's credentials at the token
+// endpoint. Public clients have no secret to check
+func (s *oidcService) AuthenticateClient(clientID string, clientSecret string) (*model.OIDCClient, error) {
+	client, ok := s.Clients[clientID]
+
+	if !ok || subtle.ConstantTimeCompare([]byte(client.Secret), []byte(clientSecret)) != 1 {
+		return nil, &model.OAuthError{Code: "invalid_client", Description: "Client authentication failed"}
+	}
+
+	return client, nil
+}
+
+// ExchangeCode redeems an authorization code issued to client,
+// returning what the user authorized
+func (s *oidcService) ExchangeCode(ctx context.Context, client *model.OIDCClient, code string, redirectURI string, codeVerifier string) (*model.OIDCAuthorization, error) {
+	invalidGrant := &model.OAuthError{Code: "invalid_grant", Description: "Invalid or expired authorization code"}
+
+	a, err := s.CodeRepository.ConsumeAuthorization(ctx, hashAuthorizationCode(code))
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, invalidGrant
+		}
+
+		return nil, err
+	}
+
+	if a.ClientID != client.ID || a.RedirectURI != redirectURI {
+		return nil, invalidGrant
+	}
+
+	if a.CodeChallenge != "" && subtle.ConstantTimeCompare([]byte(pkceChallenge(codeVerifier)), []byte(a.CodeChallenge)) != 1 {
+		return nil, &model.OAuthError{Code: "invalid_grant", Description: "Invalid code_verifier"}
+	}
+
+	return a, nil
+}
+
+// UserInfo returns the claims about a user for the userinfo endpoint,
+// limited to the scope the client was authorized
+func (s *oidcService) UserInfo(ctx context.Context, access *model.OIDCAccessToken) (*model.OIDCUserInfo, error) {
+	u, err := s.UserRepository.FindByID(ctx, access.UID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info := &model.OIDCUserInfo{
+		Sub: u.UID.String(),
+	}
+
+	if hasScope(access.Scope, "email") {
+		info.Email = u.Email
+		info.EmailVerified = &u.EmailVerified
+	}
+
+	if hasScope(access.Scope, "profile") {
+		info.Name = u.Name
+		info.Picture = u.ImageURL
+		info.Website = u.Website
+	}
+
+	return info, nil
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	testConfidentialClient = &model.OIDCClient{
+		ID:           "deck",
+		Secret:       "decksecret",
+		RedirectURIs: []string{"https://deck.memrizr.test/callback"},
+	}
+	testPublicClient = &model.OIDCClient{
+		ID:           "cli",
+		RedirectURIs: []string{"http://127.0.0.1:8765/callback"},
+	}
+)
+
+func newTestOIDCService() (model.OIDCService, *mocks.MockUserRepository, *mocks.MockOIDCCodeRepository) {
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockCodeRepository := new(mocks.MockOIDCCodeRepository)
+
+	oidc := NewOIDCService(&OSConfig{
+		UserRepository:     mockUserRepository,
+		CodeRepository:     mockCodeRepository,
+		Clients:            []*model.OIDCClient{testConfidentialClient, testPublicClient},
+		Issuer:             "https://memrizr.test/api/account/",
+		LoginURL:           "https://memrizr.test/authorize",
+		CodeExpirationSecs: 60,
+	})
+
+	return oidc, mockUserRepository, mockCodeRepository
+}
+
+func testAuthorizationRequest() *model.OIDCAuthorizationRequest {
+	return &model.OIDCAuthorizationRequest{
+		ClientID:     "deck",
+		RedirectURI:  "https://deck.memrizr.test/callback",
+		ResponseType: "code",
+		Scope:        "openid email",
+		State:        "state",
+		Nonce:        "nonce",
+	}
+}
+
+func TestOIDCDiscovery(t *testing.T) {
+	oidc, _, _ := newTestOIDCService()
+
+	d := oidc.Discovery()
+
+	assert.Equal(t, "https://memrizr.test/api/account", d.Issuer)
+	assert.Equal(t, "https://memrizr.test/api/account/oidc/authorize", d.AuthorizationEndpoint)
+	assert.Equal(t, "https://memrizr.test/api/account/oidc/token", d.TokenEndpoint)
+	assert.Equal(t, "https://memrizr.test/api/account/oidc/userinfo", d.UserinfoEndpoint)
+	assert.Equal(t, "https://memrizr.test/api/account/.well-known/jwks.json", d.JWKSURI)
+}
+
+func TestOIDCStartAuthorization(t *testing.T) {
+	oidc, _, _ := newTestOIDCService()
+
+	t.Run("Sends user to login page", func(t *testing.T) {
+		loginURL, err := oidc.StartAuthorization(context.TODO(), testAuthorizationRequest())
+		assert.NoError(t, err)
+
+		u, _ := url.Parse(loginURL)
+		assert.Equal(t, "https://memrizr.test/authorize", u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(t, "deck", u.Query().Get("client_id"))
+		assert.Equal(t, "openid email", u.Query().Get("scope"))
+		assert.Equal(t, "state", u.Query().Get("state"))
+		assert.Equal(t, "nonce", u.Query().Get("nonce"))
+	})
+
+	t.Run("Unknown client", func(t *testing.T) {
+		req := testAuthorizationRequest()
+		req.ClientID = "unknown"
+
+		_, err := oidc.StartAuthorization(context.TODO(), req)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Unregistered redirect URI", func(t *testing.T) {
+		req := testAuthorizationRequest()
+		req.RedirectURI = "https://evil.test/callback"
+
+		_, err := oidc.StartAuthorization(context.TODO(), req)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Errors are sent to the client", func(t *testing.T) {
+		req := testAuthorizationRequest()
+		req.Scope = "email"
+
+		redirectURL, err := oidc.StartAuthorization(context.TODO(), req)
+		assert.NoError(t, err)
+
+		u, _ := url.Parse(redirectURL)
+		assert.Equal(t, "deck.memrizr.test", u.Host)
+		assert.Equal(t, "invalid_scope", u.Query().Get("error"))
+		assert.Equal(t, "state", u.Query().Get("state"))
+	})
+
+	t.Run("Public client without PKCE", func(t *testing.T) {
+		req := testAuthorizationRequest()
+		req.ClientID = "cli"
+		req.RedirectURI = "http://127.0.0.1:8765/callback"
+
+		redirectURL, err := oidc.StartAuthorization(context.TODO(), req)
+		assert.NoError(t, err)
+
+		u, _ := url.Parse(redirectURL)
+		assert.Equal(t, "invalid_request", u.Query().Get("error"))
+	})
+}
+
+func TestOIDCAuthorize(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Issues code", func(t *testing.T) {
+		oidc, _, mockCodeRepository := newTestOIDCService()
+
+		var codeHash string
+		var stored *model.OIDCAuthorization
+
+		mockCodeRepository.On("SetAuthorization", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.OIDCAuthorization"), 60*time.Second).Return(nil).Run(func(args mock.Arguments) {
+			codeHash = args.String(1)
+			stored = args.Get(2).(*model.OIDCAuthorization)
+		})
+
+		req := testAuthorizationRequest()
+		req.Scope = "openid email offline_access"
+
+		redirectURL, err := oidc.Authorize(context.TODO(), uid, req)
+		assert.NoError(t, err)
+
+		u, _ := url.Parse(redirectURL)
+		code := u.Query().Get("code")
+
+		assert.Equal(t, "deck.memrizr.test", u.Host)
+		assert.Equal(t, "state", u.Query().Get("state"))
+
+		// codes are only stored hashed
+		assert.Equal(t, hashAuthorizationCode(code), codeHash)
+
+		assert.Equal(t, uid, stored.UID)
+		assert.Equal(t, "deck", stored.ClientID)
+		assert.Equal(t, "nonce", stored.Nonce)
+		// unsupported scopes are dropped
+		assert.Equal(t, "openid email", stored.Scope)
+	})
+
+	t.Run("Unknown client", func(t *testing.T) {
+		oidc, _, mockCodeRepository := newTestOIDCService()
+
+		req := testAuthorizationRequest()
+		req.ClientID = "unknown"
+
+		_, err := oidc.Authorize(context.TODO(), uid, req)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		mockCodeRepository.AssertNotCalled(t, "SetAuthorization", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestOIDCAuthenticateClient(t *testing.T) {
+	oidc, _, _ := newTestOIDCService()
+
+	client, err := oidc.AuthenticateClient("deck", "decksecret")
+	assert.NoError(t, err)
+	assert.Equal(t, testConfidentialClient, client)
+
+	client, err = oidc.AuthenticateClient("cli", "")
+	assert.NoError(t, err)
+	assert.Equal(t, testPublicClient, client)
+
+	for _, creds := range [][2]string{{"deck", "wrong"}, {"deck", ""}, {"unknown", ""}} {
+		_, err := oidc.AuthenticateClient(creds[0], creds[1])
+		assert.Equal(t, &model.OAuthError{Code: "invalid_client", Description: "Client authentication failed"}, err)
+	}
+}
+
+func TestOIDCExchangeCode(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	authorization := &model.OIDCAuthorization{
+		ClientID:      "cli",
+		RedirectURI:   "http://127.0.0.1:8765/callback",
+		UID:           uid,
+		Scope:         "openid",
+		CodeChallenge: pkceChallenge(verifier),
+	}
+
+	setup := func() model.OIDCService {
+		oidc, _, mockCodeRepository := newTestOIDCService()
+		mockCodeRepository.On("ConsumeAuthorization", mock.Anything, hashAuthorizationCode("code")).Return(authorization, nil)
+		mockCodeRepository.On("ConsumeAuthorization", mock.Anything, mock.Anything).Return(nil, apperrors.NewNotFound("authorization code", ""))
+
+		return oidc
+	}
+
+	isInvalidGrant := func(t *testing.T, err error) {
+		oauthErr, ok := err.(*model.OAuthError)
+		assert.True(t, ok)
+		assert.Equal(t, "invalid_grant", oauthErr.Code)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		a, err := setup().ExchangeCode(context.TODO(), testPublicClient, "code", "http://127.0.0.1:8765/callback", verifier)
+		assert.NoError(t, err)
+		assert.Equal(t, authorization, a)
+	})
+
+	t.Run("Unknown code", func(t *testing.T) {
+		_, err := setup().ExchangeCode(context.TODO(), testPublicClient, "other", "http://127.0.0.1:8765/callback", verifier)
+		isInvalidGrant(t, err)
+	})
+
+	t.Run("Wrong code verifier", func(t *testing.T) {
+		_, err := setup().ExchangeCode(context.TODO(), testPublicClient, "code", "http://127.0.0.1:8765/callback", "wrong")
+		isInvalidGrant(t, err)
+	})
+
+	t.Run("Wrong redirect URI", func(t *testing.T) {
+		_, err := setup().ExchangeCode(context.TODO(), testPublicClient, "code", "http://127.0.0.1:9999/callback", verifier)
+		isInvalidGrant(t, err)
+	})
+
+	t.Run("Issued to another client", func(t *testing.T) {
+		_, err := setup().ExchangeCode(context.TODO(), testConfidentialClient, "code", "http://127.0.0.1:8765/callback", verifier)
+		isInvalidGrant(t, err)
+	})
+}
+
+func TestOIDCUserInfo(t *testing.T) {
+	oidc, mockUserRepository, _ := newTestOIDCService()
+
+	uid, _ := uuid.NewRandom()
+	mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{
+		UID:           uid,
+		Email:         "bob@bob.com",
+		EmailVerified: true,
+		Name:          "Bob",
+		ImageURL:      "https://images.memrizr.test/bob.jpg",
+	}, nil)
+
+	t.Run("All scopes", func(t *testing.T) {
+		info, err := oidc.UserInfo(context.TODO(), &model.OIDCAccessToken{UID: uid, ClientID: "deck", Scope: "openid email profile"})
+		assert.NoError(t, err)
+
+		emailVerified := true
+		assert.Equal(t, &model.OIDCUserInfo{
+			Sub:           uid.String(),
+			Email:         "bob@bob.com",
+			EmailVerified: &emailVerified,
+			Name:          "Bob",
+			Picture:       "https://images.memrizr.test/bob.jpg",
+		}, info)
+	})
+
+	t.Run("Only the openid scope", func(t *testing.T) {
+		info, err := oidc.UserInfo(context.TODO(), &model.OIDCAccessToken{UID: uid, ClientID: "deck", Scope: "openid"})
+		assert.NoError(t, err)
+		assert.Equal(t, &model.OIDCUserInfo{Sub: uid.String()}, info)
+	})
+}
@@ -2,40 +2,251 @@ package service
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/scrypt"
 )
 
-func hashPassword(password string) (string, error) {
-	salt := make([]byte, 32)
-	_, err := rand.Read(salt)
-	if err != nil {
+// supported password hashing algorithms
+const (
+	PasswordArgon2id = "argon2id"
+	PasswordScrypt   = "scrypt"
+)
+
+// PasswordParams select the algorithm and cost new passwords are hashed
+// with. Stored hashes record their own parameters, so these can be
+// raised at any time and older hashes are upgraded on signin
+type PasswordParams struct {
+	Algorithm string
+	// argon2id memory in KiB, iterations and parallelism
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	// scrypt CPU/memory cost, which must be a power of 2, block size and parallelism
+	ScryptN int
+	ScryptR int
+	ScryptP int
+	// SaltLength and KeyLength are in bytes
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// DefaultPasswordParams follow the OWASP recommendations for argon2id.
+// The scrypt parameters are those passwords were originally hashed with
+var DefaultPasswordParams = PasswordParams{
+	Algorithm:   PasswordArgon2id,
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	ScryptN:     32768,
+	ScryptR:     8,
+	ScryptP:     1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// passwordHash is a decoded stored password hash
+type passwordHash struct {
+	Params PasswordParams
+	Salt   []byte
+	Key    []byte
+	// Legacy hashes predate the PHC format and always need upgrading
+	Legacy bool
+}
+
+// hashPassword hashes password with params, encoded in the PHC string
+// format, eg $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+func hashPassword(password string, params PasswordParams) (string, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
 
-	shash, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+	h := &passwordHash{
+		Params: params,
+		Salt:   salt,
+	}
+
+	key, err := h.derive(password)
 	if err != nil {
 		return "", err
 	}
 
-	hashedPW := fmt.Sprintf("%s.%s", hex.EncodeToString(shash), hex.EncodeToString(salt))
+	h.Key = key
 
-	return hashedPW, nil
+	return h.encode()
 }
 
+// comparePasswords checks whether suppliedPassword matches a stored hash.
+// Users who signed up with an identity provider have no password, which
+// nothing matches
 func comparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
-	pwsalt := strings.Split(storedPassword, ".")
+	if storedPassword == "" {
+		return false, nil
+	}
 
-	salt, err := hex.DecodeString(pwsalt[1])
+	h, err := decodePasswordHash(storedPassword)
+	if err != nil {
+		return false, err
+	}
 
+	key, err := h.derive(suppliedPassword)
 	if err != nil {
-		return false, fmt.Errorf("Unable to verify user password")
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(key, h.Key) == 1, nil
+}
+
+// needsRehash reports whether a stored hash was made with a different
+// algorithm or parameters than new passwords are hashed with
+func needsRehash(storedPassword string, params PasswordParams) bool {
+	h, err := decodePasswordHash(storedPassword)
+	if err != nil || h.Legacy {
+		return true
+	}
+
+	if h.Params.Algorithm != params.Algorithm ||
+		uint32(len(h.Salt)) != params.SaltLength ||
+		uint32(len(h.Key)) != params.KeyLength {
+		return true
+	}
+
+	if params.Algorithm == PasswordArgon2id {
+		return h.Params.Memory != params.Memory ||
+			h.Params.Iterations != params.Iterations ||
+			h.Params.Parallelism != params.Parallelism
+	}
+
+	return h.Params.ScryptN != params.ScryptN ||
+		h.Params.ScryptR != params.ScryptR ||
+		h.Params.ScryptP != params.ScryptP
+}
+
+func (h *passwordHash) derive(password string) ([]byte, error) {
+	p := h.Params
+	keyLen := p.KeyLength
+	if h.Key != nil {
+		keyLen = uint32(len(h.Key))
+	}
+
+	switch p.Algorithm {
+	case PasswordArgon2id:
+		// argon2 panics rather than returning errors for these
+		if p.Iterations < 1 || p.Parallelism < 1 || p.Memory < 8*uint32(p.Parallelism) || keyLen < 4 {
+			return nil, fmt.Errorf("invalid argon2id parameters: m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism)
+		}
+		return argon2.IDKey([]byte(password), h.Salt, p.Iterations, p.Memory, p.Parallelism, keyLen), nil
+	case PasswordScrypt:
+		return scrypt.Key([]byte(password), h.Salt, p.ScryptN, p.ScryptR, p.ScryptP, int(keyLen))
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm: %s", p.Algorithm)
 	}
+}
+
+func (h *passwordHash) encode() (string, error) {
+	salt := base64.RawStdEncoding.EncodeToString(h.Salt)
+	key := base64.RawStdEncoding.EncodeToString(h.Key)
+	p := h.Params
+
+	switch p.Algorithm {
+	case PasswordArgon2id:
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism, salt, key), nil
+	case PasswordScrypt:
+		ln := 0
+		for n := p.ScryptN; n > 1; n >>= 1 {
+			ln++
+		}
+		if p.ScryptN != 1<<ln {
+			return "", fmt.Errorf("scrypt N must be a power of 2, got: %d", p.ScryptN)
+		}
+		return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", ln, p.ScryptR, p.ScryptP, salt, key), nil
+	default:
+		return "", fmt.Errorf("unsupported password hashing algorithm: %s", p.Algorithm)
+	}
+}
+
+func decodePasswordHash(encoded string) (*passwordHash, error) {
+	if !strings.HasPrefix(encoded, "$") {
+		return decodeLegacyPasswordHash(encoded)
+	}
+
+	parts := strings.Split(encoded, "$")
+	h := &passwordHash{}
+	var params, salt, key string
 
-	shash, err := scrypt.Key([]byte(suppliedPassword), salt, 32768, 8, 1, 32)
+	switch {
+	case len(parts) == 6 && parts[1] == PasswordArgon2id:
+		var version int
+		if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+			return nil, fmt.Errorf("unsupported argon2id version: %s", parts[2])
+		}
+
+		h.Params.Algorithm = PasswordArgon2id
+		params, salt, key = parts[3], parts[4], parts[5]
+
+		if _, err := fmt.Sscanf(params, "m=%d,t=%d,p=%d", &h.Params.Memory, &h.Params.Iterations, &h.Params.Parallelism); err != nil {
+			return nil, fmt.Errorf("invalid argon2id parameters: %s", params)
+		}
+	case len(parts) == 5 && parts[1] == PasswordScrypt:
+		h.Params.Algorithm = PasswordScrypt
+		params, salt, key = parts[2], parts[3], parts[4]
+
+		var ln uint
+		if _, err := fmt.Sscanf(params, "ln=%d,r=%d,p=%d", &ln, &h.Params.ScryptR, &h.Params.ScryptP); err != nil || ln > 30 {
+			return nil, fmt.Errorf("invalid scrypt parameters: %s", params)
+		}
+		h.Params.ScryptN = 1 << ln
+	default:
+		return nil, fmt.Errorf("unrecognized password hash format")
+	}
+
+	var err error
+
+	if h.Salt, err = base64.RawStdEncoding.DecodeString(salt); err != nil {
+		return nil, fmt.Errorf("invalid password hash salt")
+	}
 
-	return hex.EncodeToString(shash) == pwsalt[0], nil
-}
\ No newline at end of file
+	if h.Key, err = base64.RawStdEncoding.DecodeString(key); err != nil || len(h.Key) == 0 {
+		return nil, fmt.Errorf("invalid password hash key")
+	}
+
+	return h, nil
+}
+
+// decodeLegacyPasswordHash decodes the original hex "hash.salt"
+// format, which was always scrypt with fixed parameters
+func decodeLegacyPasswordHash(encoded string) (*passwordHash, error) {
+	pwsalt := strings.Split(encoded, ".")
+
+	if len(pwsalt) != 2 {
+		return nil, fmt.Errorf("unrecognized password hash format")
+	}
+
+	key, err := hex.DecodeString(pwsalt[0])
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid password hash key")
+	}
+
+	salt, err := hex.DecodeString(pwsalt[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid password hash salt")
+	}
+
+	return &passwordHash{
+		Params: PasswordParams{
+			Algorithm: PasswordScrypt,
+			ScryptN:   32768,
+			ScryptR:   8,
+			ScryptP:   1,
+		},
+		Salt:   salt,
+		Key:    key,
+		Legacy: true,
+	}, nil
+}
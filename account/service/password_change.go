@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// ChangePassword sets a new password for a signed in user who knows their
+// current one
+func (s *userService) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) (_ *model.User, err error) {
+	defer func() {
+		recordAudit(ctx, s.Logger, s.AuditRepository, &uid, model.AuditPasswordChange, err)
+	}()
+
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.confirmPassword(ctx, u, currentPassword); err != nil {
+		return nil, err
+	}
+
+	if err := s.replacePassword(ctx, u, newPassword); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// confirmPassword checks a signed in user's password before a sensitive
+// change. Wrong guesses count as failed signins, so a stolen ID token
+// can't be used to guess the password any faster than signing in would
+func (s *userService) confirmPassword(ctx context.Context, u *model.User, password string) error {
+	var attemptKeys []signinAttemptKey
+
+	if s.SigninAttemptRepository != nil {
+		attemptKeys = s.signinAttemptKeys(ctx, u.Email)
+
+		if err := s.checkSigninLockout(ctx, attemptKeys); err != nil {
+			return err
+		}
+	}
+
+	match, err := comparePasswords(u.Password, password)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to compare passwords", "uid", u.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if !match {
+		s.recordSigninFailure(ctx, attemptKeys)
+		return apperrors.NewForbidden("Current password is incorrect")
+	}
+
+	return nil
+}
+
+// replacePassword stores the hash of a user's new password and signs
+// them out everywhere, as the old password may have been compromised
+func (s *userService) replacePassword(ctx context.Context, u *model.User, password string) error {
+	pw, err := hashPassword(password, s.PasswordParams)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to hash password", "uid", u.UID, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, u.UID, pw); err != nil {
+		return err
+	}
+
+	u.Password = pw
+
+	if err := s.TokenRepository.DeleateUserRefreshTokens(ctx, u.UID.String()); err != nil {
+		s.Logger.Warn(ctx, "Unable to revoke refresh tokens after password change", "uid", u.UID, "err", err)
+		return err
+	}
+
+	// failed guesses at the old password shouldn't lock out the new one
+	s.resetEmailSigninFailures(ctx, u.Email)
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestChangePassword(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	hashedPassword, _ := hashPassword("currentpassword", DefaultPasswordParams)
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockTokenRepository, *mocks.MockSigninAttemptRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockSigninAttemptRepository := new(mocks.MockSigninAttemptRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:          mockUserRepository,
+			TokenRepository:         mockTokenRepository,
+			SigninAttemptRepository: mockSigninAttemptRepository,
+			EmailLockout: LockoutPolicy{
+				FreeAttempts: 3,
+				BaseDelay:    time.Second,
+				MaxDelay:     time.Minute,
+				Window:       time.Hour,
+			},
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{
+			UID:      uid,
+			Email:    "bob@bob.com",
+			Password: hashedPassword,
+		}, nil)
+
+		return us, mockUserRepository, mockTokenRepository, mockSigninAttemptRepository
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockUserRepository, mockTokenRepository, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, "email:bob@bob.com").Return(time.Duration(0), nil)
+		mockSigninAttemptRepository.On("ResetFailures", mock.Anything, "email:bob@bob.com").Return(nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.MatchedBy(func(pw string) bool {
+			match, err := comparePasswords(pw, "anewpassword")
+			return err == nil && match
+		})).Return(nil)
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+
+		u, err := us.ChangePassword(context.TODO(), uid, "currentpassword", "anewpassword")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+		mockSigninAttemptRepository.AssertExpectations(t)
+	})
+
+	t.Run("Incorrect current password", func(t *testing.T) {
+		us, mockUserRepository, mockTokenRepository, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, "email:bob@bob.com").Return(time.Duration(0), nil)
+		mockSigninAttemptRepository.On("IncrementFailures", mock.Anything, "email:bob@bob.com", time.Hour).Return(int64(1), nil)
+
+		u, err := us.ChangePassword(context.TODO(), uid, "wrongpassword", "anewpassword")
+
+		assert.Nil(t, u)
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+		mockSigninAttemptRepository.AssertExpectations(t)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		mockTokenRepository.AssertNotCalled(t, "DeleateUserRefreshTokens", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Locked out", func(t *testing.T) {
+		us, mockUserRepository, _, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, "email:bob@bob.com").Return(time.Minute, nil)
+
+		u, err := us.ChangePassword(context.TODO(), uid, "currentpassword", "anewpassword")
+
+		assert.Nil(t, u)
+		assert.Equal(t, http.StatusTooManyRequests, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// generateResetToken returns a random token and the hash it is stored
+// under, so tokens can't be read back out of Redis
+func generateResetToken() (string, string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, hashResetToken(token), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// how long looking up the user and sending them a reset email may take
+const passwordResetSendTimeout = 30 * time.Second
+
+// ForgotPassword emails the user a link to reset their password. Whether
+// or not there is an account for email, nothing is returned which would
+// tell the caller, so the endpoint can't be used to discover accounts.
+// For the same reason the work is done in the background, as how long it
+// took to respond would otherwise give the account away
+func (s *userService) ForgotPassword(ctx context.Context, email string) error {
+	// the request's context is cancelled once we respond, so only its
+	// request ID is kept for logging
+	bgCtx := model.ContextWithRequestID(context.Background(), model.RequestIDFromContext(ctx))
+
+	s.background(func() {
+		ctx, cancel := context.WithTimeout(bgCtx, passwordResetSendTimeout)
+		defer cancel()
+
+		s.forgotPassword(ctx, email)
+	})
+
+	return nil
+}
+
+func (s *userService) forgotPassword(ctx context.Context, email string) {
+	u, err := s.UserRepository.FindByEmail(ctx, email)
+
+	if err != nil {
+		s.Logger.Info(ctx, "Password reset requested for unknown email", "email", email)
+		return
+	}
+
+	if err := s.sendPasswordResetEmail(ctx, u); err != nil {
+		s.Logger.Error(ctx, "Unable to send password reset email", "uid", u.UID, "err", err)
+	}
+}
+
+func (s *userService) sendPasswordResetEmail(ctx context.Context, u *model.User) error {
+	if s.Mailer == nil {
+		return nil
+	}
+
+	token, tokenHash, err := generateResetToken()
+
+	if err != nil {
+		return err
+	}
+
+	expiresIn := time.Duration(s.PasswordResetExpirationSecs) * time.Second
+
+	if err := s.PasswordResetRepository.SetResetToken(ctx, tokenHash, u.UID.String(), expiresIn); err != nil {
+		return err
+	}
+
+	link, err := linkWithToken(s.ResetPasswordURL, token)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(ctx, &model.Email{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"We received a request to reset your password. Follow the link below to choose a new one. The link expires in %v and can only be used once.\n\n%s\n\nIf you didn't request this, you can ignore this email.\n",
+			expiresIn, link,
+		),
+	})
+}
+
+// ResetPassword sets a new password for the user a reset token was sent to.
+// The user is then signed out everywhere, in case the account was taken over
+func (s *userService) ResetPassword(ctx context.Context, token string, password string) (err error) {
+	// the user is only known once the token is found
+	var uid *uuid.UUID
+
+	defer func() {
+		recordAudit(ctx, s.Logger, s.AuditRepository, uid, model.AuditPasswordReset, err)
+	}()
+
+	userID, err := s.PasswordResetRepository.ConsumeResetToken(ctx, hashResetToken(token))
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return apperrors.NewAuthorization("Password reset link is invalid or has expired")
+		}
+
+		return err
+	}
+
+	parsed, err := uuid.Parse(userID)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Password reset token has invalid userID", "userID", userID)
+		return apperrors.NewInternal()
+	}
+
+	uid = &parsed
+
+	u, err := s.UserRepository.FindByID(ctx, parsed)
+
+	if err != nil {
+		return err
+	}
+
+	return s.replacePassword(ctx, u, password)
+}
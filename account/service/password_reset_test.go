@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type passwordResetMocks struct {
+	UserRepository          *mocks.MockUserRepository
+	TokenRepository         *mocks.MockTokenRepository
+	PasswordResetRepository *mocks.MockPasswordResetRepository
+	Mailer                  *mocks.MockMailer
+}
+
+func newPasswordResetTestService() (model.UserService, *passwordResetMocks) {
+	m := &passwordResetMocks{
+		UserRepository:          new(mocks.MockUserRepository),
+		TokenRepository:         new(mocks.MockTokenRepository),
+		PasswordResetRepository: new(mocks.MockPasswordResetRepository),
+		Mailer:                  new(mocks.MockMailer),
+	}
+
+	us := NewUserService(&USConfig{
+		UserRepository:              m.UserRepository,
+		TokenRepository:             m.TokenRepository,
+		PasswordResetRepository:     m.PasswordResetRepository,
+		Mailer:                      m.Mailer,
+		PasswordResetExpirationSecs: 60 * 60,
+		ResetPasswordURL:            "https://memrizr.test/reset",
+	})
+
+	// the reset email is sent before ForgotPassword returns
+	us.(*userService).background = func(f func()) { f() }
+
+	return us, m
+}
+
+func TestForgotPassword(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Sends single use token", func(t *testing.T) {
+		us, m := newPasswordResetTestService()
+
+		m.UserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+
+		var storedHash string
+		m.PasswordResetRepository.On("SetResetToken", mock.Anything, mock.AnythingOfType("string"), uid.String(), time.Hour).
+			Run(func(args mock.Arguments) {
+				storedHash = args.String(1)
+			}).Return(nil)
+
+		var sent *model.Email
+		m.Mailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+			Run(func(args mock.Arguments) {
+				sent = args.Get(1).(*model.Email)
+			}).Return(nil)
+
+		err := us.ForgotPassword(context.TODO(), "bob@bob.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "bob@bob.com", sent.To)
+
+		link := linkFromEmail(t, sent)
+		assert.Equal(t, "memrizr.test", link.Host)
+		assert.Equal(t, "/reset", link.Path)
+
+		// only the hash of the emailed token is stored
+		token := link.Query().Get("token")
+		assert.NotEmpty(t, token)
+		assert.NotEqual(t, token, storedHash)
+		assert.Equal(t, hashResetToken(token), storedHash)
+	})
+
+	t.Run("Unknown email gives the same response", func(t *testing.T) {
+		us, m := newPasswordResetTestService()
+
+		m.UserRepository.On("FindByEmail", mock.Anything, "nobody@bob.com").Return(nil, apperrors.NewNotFound("email", "nobody@bob.com"))
+
+		err := us.ForgotPassword(context.TODO(), "nobody@bob.com")
+
+		assert.NoError(t, err)
+		m.Mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Returns before looking up the email", func(t *testing.T) {
+		us, m := newPasswordResetTestService()
+
+		var work func()
+		us.(*userService).background = func(f func()) { work = f }
+
+		m.UserRepository.On("FindByEmail", mock.Anything, "nobody@bob.com").Return(nil, apperrors.NewNotFound("email", "nobody@bob.com"))
+
+		ctx := model.ContextWithRequestID(context.TODO(), "arequestid")
+		err := us.ForgotPassword(ctx, "nobody@bob.com")
+
+		assert.NoError(t, err)
+		m.UserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+
+		work()
+
+		// the lookup outlives the request, keeping only its request ID
+		m.UserRepository.AssertCalled(t, "FindByEmail", mock.MatchedBy(func(ctx context.Context) bool {
+			return model.RequestIDFromContext(ctx) == "arequestid"
+		}), "nobody@bob.com")
+	})
+
+	t.Run("Failures aren't revealed", func(t *testing.T) {
+		us, m := newPasswordResetTestService()
+
+		m.UserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+		m.PasswordResetRepository.On("SetResetToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(apperrors.NewInternal())
+
+		err := us.ForgotPassword(context.TODO(), "bob@bob.com")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	tokenHash := hashResetToken("aresettoken")
+
+	t.Run("Success", func(t *testing.T) {
+		us, m := newPasswordResetTestService()
+
+		m.PasswordResetRepository.On("ConsumeResetToken", mock.Anything, tokenHash).Return(uid.String(), nil)
+		m.UserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+		m.UserRepository.On("UpdatePassword", mock.Anything, uid, mock.MatchedBy(func(pw string) bool {
+			match, err := comparePasswords(pw, "anewpassword")
+			return err == nil && match
+		})).Return(nil)
+		m.TokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+
+		err := us.ResetPassword(context.TODO(), "aresettoken", "anewpassword")
+
+		assert.NoError(t, err)
+		m.UserRepository.AssertExpectations(t)
+		m.TokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid or used token", func(t *testing.T) {
+		us, m := newPasswordResetTestService()
+
+		m.PasswordResetRepository.On("ConsumeResetToken", mock.Anything, tokenHash).Return("", apperrors.NewNotFound("password reset token", tokenHash))
+
+		err := us.ResetPassword(context.TODO(), "aresettoken", "anewpassword")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		m.UserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		m.TokenRepository.AssertNotCalled(t, "DeleateUserRefreshTokens", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/scrypt"
+)
+
+// legacyHash hashes password in the original "hash.salt" format
+func legacyHash(password string) string {
+	salt := make([]byte, 32)
+	rand.Read(salt)
+
+	key, _ := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+
+	return fmt.Sprintf("%s.%s", hex.EncodeToString(key), hex.EncodeToString(salt))
+}
+
+func TestPasswordHashing(t *testing.T) {
+	scryptParams := DefaultPasswordParams
+	scryptParams.Algorithm = PasswordScrypt
+
+	algorithms := map[string]struct {
+		Params PasswordParams
+		Prefix string
+	}{
+		"argon2id": {DefaultPasswordParams, "$argon2id$v=19$m=19456,t=2,p=1$"},
+		"scrypt":   {scryptParams, "$scrypt$ln=15,r=8,p=1$"},
+	}
+
+	for name, a := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			hash, err := hashPassword("apassword", a.Params)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hash, a.Prefix), hash)
+
+			match, err := comparePasswords(hash, "apassword")
+			assert.NoError(t, err)
+			assert.True(t, match)
+
+			match, err = comparePasswords(hash, "anotherpassword")
+			assert.NoError(t, err)
+			assert.False(t, match)
+
+			assert.False(t, needsRehash(hash, a.Params))
+		})
+	}
+
+	t.Run("Legacy hashes are still accepted", func(t *testing.T) {
+		hash := legacyHash("apassword")
+
+		match, err := comparePasswords(hash, "apassword")
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = comparePasswords(hash, "anotherpassword")
+		assert.NoError(t, err)
+		assert.False(t, match)
+
+		// even with scrypt, as the format is outdated
+		assert.True(t, needsRehash(hash, scryptParams))
+	})
+
+	t.Run("Changed parameters need rehash", func(t *testing.T) {
+		hash, _ := hashPassword("apassword", DefaultPasswordParams)
+
+		stronger := DefaultPasswordParams
+		stronger.Iterations = 3
+
+		assert.True(t, needsRehash(hash, stronger))
+		assert.True(t, needsRehash(hash, scryptParams))
+	})
+
+	t.Run("Nothing matches no password", func(t *testing.T) {
+		// users who signed up with an identity provider
+		match, err := comparePasswords("", "")
+
+		assert.NoError(t, err)
+		assert.False(t, match)
+	})
+
+	t.Run("Malformed hashes are errors, not panics", func(t *testing.T) {
+		malformed := []string{
+			"nodot",
+			"too.many.dots",
+			"zz.zz",
+			"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+			"$argon2id$v=18$m=19456,t=2,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=0,t=0,p=0$c2FsdA$a2V5",
+			"$argon2id$v=19$nonsense$c2FsdA$a2V5",
+			"$scrypt$ln=99,r=8,p=1$c2FsdA$a2V5",
+			"$scrypt$ln=15,r=8,p=1$!!!$a2V5",
+			"$bcrypt$2b$10$abcdefghijklmnopqrstuv",
+		}
+
+		for _, hash := range malformed {
+			match, err := comparePasswords(hash, "apassword")
+
+			assert.Error(t, err, hash)
+			assert.False(t, match, hash)
+			assert.True(t, needsRehash(hash, DefaultPasswordParams), hash)
+		}
+	})
+}
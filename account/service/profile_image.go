@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"golang.org/x/image/draw"
+)
+
+// edge length, in pixels, of the square profile image we store
+const profileImageSize = 512
+
+// edge lengths of the thumbnails stored next to the profile image
+var thumbnailSizes = []int{64, 128, 256}
+
+// largest source image we are willing to decode, guards against
+// small files which claim huge dimensions (decompression bombs)
+const maxSourcePixels = 50 * 1000 * 1000
+
+const jpegQuality = 85
+
+// processedImage holds the encoded profile image and its thumbnails
+// keyed by edge length
+type processedImage struct {
+	Image      []byte
+	Thumbnails map[int][]byte
+}
+
+// processProfileImage decodes a png or jpeg, applies any EXIF orientation,
+// center-crops it to a square and re-encodes it along with thumbnails.
+// Re-encoding drops all metadata (EXIF, GPS location, etc.) from the original
+func processProfileImage(ctx context.Context, logger model.Logger, r io.Reader) (*processedImage, error) {
+	data, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		logger.Error(ctx, "Unable to read image file", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil {
+		logger.Debug(ctx, "Unable to decode image config", "err", err)
+		return nil, apperrors.NewBadRequest("imageFile could not be decoded")
+	}
+
+	if format != "png" && format != "jpeg" {
+		return nil, apperrors.NewUnsupportedMediaType("imageFile must be 'image/jpeg' or 'image/png'")
+	}
+
+	if cfg.Width*cfg.Height > maxSourcePixels {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("imageFile must have fewer than %d pixels", maxSourcePixels))
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		logger.Debug(ctx, "Unable to decode image", "err", err)
+		return nil, apperrors.NewBadRequest("imageFile could not be decoded")
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	size := profileImageSize
+	if side := minInt(src.Bounds().Dx(), src.Bounds().Dy()); side < size {
+		size = side
+	}
+
+	// orientation doesn't change the center square, so it's applied after the crop
+	profile := orient(resize(centerSquare(src), size), orientation)
+
+	encoded, err := encodeImage(ctx, logger, profile, format)
+
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnails := make(map[int][]byte, len(thumbnailSizes))
+
+	for _, thumbSize := range thumbnailSizes {
+		thumb, err := encodeImage(ctx, logger, resize(profile, thumbSize), format)
+
+		if err != nil {
+			return nil, err
+		}
+
+		thumbnails[thumbSize] = thumb
+	}
+
+	return &processedImage{
+		Image:      encoded,
+		Thumbnails: thumbnails,
+	}, nil
+}
+
+// imageBuffer adapts encoded image bytes to the multipart.File
+// expected by an ImageRepository
+type imageBuffer struct {
+	*bytes.Reader
+}
+
+func newImageBuffer(b []byte) imageBuffer {
+	return imageBuffer{bytes.NewReader(b)}
+}
+
+func (imageBuffer) Close() error {
+	return nil
+}
+
+func encodeImage(ctx context.Context, logger model.Logger, img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+
+	if err != nil {
+		logger.Error(ctx, "Unable to encode image", "format", format, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return buf.Bytes(), nil
+}
+
+// centerSquare returns the largest square centered in img
+func centerSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := minInt(b.Dx(), b.Dy())
+
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	return subImage(img, image.Rect(x0, y0, x0+side, y0+side))
+}
+
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+// resize scales a square image to size x size
+func resize(img image.Image, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// orient transforms a square image so it displays upright
+// for the given EXIF orientation (1-8)
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	n := img.Bounds().Dx()
+	dst := image.NewRGBA(img.Bounds())
+
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			var sx, sy int
+
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = n-1-x, y
+			case 3: // rotated 180
+				sx, sy = n-1-x, n-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, n-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs 90 clockwise rotation
+				sx, sy = y, n-1-x
+			case 7: // transversed
+				sx, sy = n-1-y, n-1-x
+			case 8: // needs 90 counter-clockwise rotation
+				sx, sy = n-1-y, x
+			}
+
+			dst.SetRGBA(x, y, img.RGBAAt(sx, sy))
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag from jpeg data.
+// It returns 1 (upright) when there is no readable tag
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+
+		// start of scan, there will be no more metadata
+		if marker == 0xDA {
+			return 1
+		}
+
+		segLen := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if segLen < 2 || i+2+segLen > len(data) {
+			return 1
+		}
+
+		seg := data[i+4 : i+2+segLen]
+
+		if marker == 0xE1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+
+		i += 2 + segLen
+	}
+
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+
+	for e := 0; e < entries; e++ {
+		off := ifd + 2 + e*12
+		if off+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[off:off+2]) == 0x0112 {
+			v := int(order.Uint16(tiff[off+8 : off+10]))
+			if v < 1 || v > 8 {
+				return 1
+			}
+			return v
+		}
+	}
+
+	return 1
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessProfileImage(t *testing.T) {
+	t.Run("Corrupt image", func(t *testing.T) {
+		_, err := processProfileImage(context.TODO(), logging.Nop(), bytes.NewReader([]byte("\x89PNG\r\n\x1a\nnot really a png")))
+
+		assert.Error(t, err)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Crops and resizes png", func(t *testing.T) {
+		src := halvesImage(800, 600)
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, src))
+
+		processed, err := processProfileImage(context.TODO(), logging.Nop(), &buf)
+		assert.NoError(t, err)
+
+		img, format, err := image.Decode(bytes.NewReader(processed.Image))
+		assert.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, image.Rect(0, 0, profileImageSize, profileImageSize), img.Bounds())
+
+		assert.Len(t, processed.Thumbnails, len(thumbnailSizes))
+		for _, size := range thumbnailSizes {
+			thumb, _, err := image.Decode(bytes.NewReader(processed.Thumbnails[size]))
+			assert.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, size, size), thumb.Bounds())
+		}
+	})
+
+	t.Run("Small jpeg is not upscaled", func(t *testing.T) {
+		src := halvesImage(300, 200)
+		var buf bytes.Buffer
+		assert.NoError(t, jpeg.Encode(&buf, src, nil))
+
+		processed, err := processProfileImage(context.TODO(), logging.Nop(), &buf)
+		assert.NoError(t, err)
+
+		img, format, err := image.Decode(bytes.NewReader(processed.Image))
+		assert.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, image.Rect(0, 0, 200, 200), img.Bounds())
+	})
+
+	t.Run("Applies EXIF orientation and strips metadata", func(t *testing.T) {
+		// left half red, right half blue
+		src := halvesImage(100, 100)
+		var buf bytes.Buffer
+		assert.NoError(t, jpeg.Encode(&buf, src, &jpeg.Options{Quality: 100}))
+
+		withExif := withExifOrientation(buf.Bytes(), 6)
+		assert.Equal(t, 6, jpegOrientation(withExif))
+
+		processed, err := processProfileImage(context.TODO(), logging.Nop(), bytes.NewReader(withExif))
+		assert.NoError(t, err)
+
+		assert.Equal(t, 1, jpegOrientation(processed.Image))
+		assert.NotContains(t, string(processed.Image), "Exif")
+
+		img, _, err := image.Decode(bytes.NewReader(processed.Image))
+		assert.NoError(t, err)
+
+		// rotated 90 degrees clockwise, the red half is now on top
+		top := color.RGBAModel.Convert(img.At(50, 10)).(color.RGBA)
+		bottom := color.RGBAModel.Convert(img.At(50, 90)).(color.RGBA)
+
+		assert.True(t, top.R > 200 && top.B < 50, "expected red top, got %v", top)
+		assert.True(t, bottom.B > 200 && bottom.R < 50, "expected blue bottom, got %v", bottom)
+	})
+}
+
+func halvesImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+
+	return img
+}
+
+// withExifOrientation inserts a big-endian EXIF APP1 segment holding only
+// an orientation tag directly after the jpeg's start of image marker
+func withExifOrientation(jpg []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, binary.BigEndian, uint16(42))
+	binary.Write(&tiff, binary.BigEndian, uint32(8))      // offset of IFD0
+	binary.Write(&tiff, binary.BigEndian, uint16(1))      // entry count
+	binary.Write(&tiff, binary.BigEndian, uint16(0x0112)) // orientation tag
+	binary.Write(&tiff, binary.BigEndian, uint16(3))      // SHORT
+	binary.Write(&tiff, binary.BigEndian, uint32(1))      // value count
+	binary.Write(&tiff, binary.BigEndian, orientation)
+	binary.Write(&tiff, binary.BigEndian, uint16(0)) // value padding
+	binary.Write(&tiff, binary.BigEndian, uint32(0)) // no next IFD
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write(jpg[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(jpg[2:])
+
+	return out.Bytes()
+}
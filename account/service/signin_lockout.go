@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+// LockoutPolicy configures how failed signins are throttled. After
+// FreeAttempts failures, each further failure locks out signins for
+// BaseDelay, doubling with every failure up to MaxDelay. Failures are
+// forgotten once there have been none for Window. A zero BaseDelay
+// disables the policy
+type LockoutPolicy struct {
+	FreeAttempts int64
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Window       time.Duration
+}
+
+func (p LockoutPolicy) enabled() bool {
+	return p.BaseDelay > 0 && p.Window > 0
+}
+
+// delay returns how long to lock out signins after the given number of failures
+func (p LockoutPolicy) delay(failures int64) time.Duration {
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	d := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	return d
+}
+
+// signinAttemptKey is a key failed signins are counted under
+// along with the policy applied to it
+type signinAttemptKey struct {
+	Key    string
+	Policy LockoutPolicy
+}
+
+// signinAttemptKeys returns the keys a signin for email is throttled by.
+// Counting per client IP as well as per email slows down guessing
+// across many accounts, not just repeated guesses at one
+func (s *userService) signinAttemptKeys(ctx context.Context, email string) []signinAttemptKey {
+	var keys []signinAttemptKey
+
+	if s.EmailLockout.enabled() {
+		keys = append(keys, signinAttemptKey{emailAttemptKey(email), s.EmailLockout})
+	}
+
+	if ip := model.ClientInfoFromContext(ctx).IP; ip != "" && s.IPLockout.enabled() {
+		keys = append(keys, signinAttemptKey{"ip:" + ip, s.IPLockout})
+	}
+
+	return keys
+}
+
+func emailAttemptKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+// resetEmailSigninFailures clears failed signins and any lockout for email
+func (s *userService) resetEmailSigninFailures(ctx context.Context, email string) {
+	if s.SigninAttemptRepository == nil || !s.EmailLockout.enabled() {
+		return
+	}
+
+	key := emailAttemptKey(email)
+
+	if err := s.SigninAttemptRepository.ResetFailures(ctx, key); err != nil {
+		s.Logger.Warn(ctx, "Unable to reset signin failures", "key", key, "err", err)
+	}
+}
+
+// checkSigninLockout returns a TooManyRequests error if any of keys
+// is locked out. We fail open if Redis is unavailable, as refusing
+// every signin would be worse than briefly not throttling
+func (s *userService) checkSigninLockout(ctx context.Context, keys []signinAttemptKey) error {
+	var retryAfter time.Duration
+
+	for _, k := range keys {
+		d, err := s.SigninAttemptRepository.GetLockout(ctx, k.Key)
+
+		if err != nil {
+			s.Logger.Warn(ctx, "Unable to check signin lockout", "key", k.Key, "err", err)
+			continue
+		}
+
+		if d > retryAfter {
+			retryAfter = d
+		}
+	}
+
+	if retryAfter > 0 {
+		return apperrors.NewTooManyRequests(retryAfter)
+	}
+
+	return nil
+}
+
+// recordSigninFailure counts a failed signin against keys, locking
+// out those which have failed too many times
+func (s *userService) recordSigninFailure(ctx context.Context, keys []signinAttemptKey) {
+	for _, k := range keys {
+		failures, err := s.SigninAttemptRepository.IncrementFailures(ctx, k.Key, k.Policy.Window)
+
+		if err != nil {
+			s.Logger.Warn(ctx, "Unable to record signin failure", "key", k.Key, "err", err)
+			continue
+		}
+
+		if d := k.Policy.delay(failures); d > 0 {
+			s.Logger.Info(ctx, "Locking out signins", "key", k.Key, "duration", d, "failures", failures)
+
+			if err := s.SigninAttemptRepository.SetLockout(ctx, k.Key, d); err != nil {
+				s.Logger.Warn(ctx, "Unable to lock out signins", "key", k.Key, "err", err)
+			}
+		}
+	}
+}
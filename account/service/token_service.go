@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"crypto/rsa"
-	"log"
+	"net/http"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
 	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
 )
@@ -13,59 +15,110 @@ import (
 type tokenService struct {
 	TokenRepository			model.TokenRepository
 	PrivKey 				*rsa.PrivateKey
-	PubKey 					*rsa.PublicKey
+	KeyID 					string
+	PubKeys 				map[string]*rsa.PublicKey
 	RefreshSecret 			string
 	IDExpiratonSecs 		int64
 	RefreshExpirationSecs 	int64
+	RevokeAllOnReuse 		bool
+	MFAChallengeSecret 		string
+	MFAChallengeExpirationSecs int64
+	OIDCIssuer 				string
+	EventBroker 			model.EventBroker
+	AuditRepository 		model.AuditRepository
+	Logger 					model.Logger
 }
 
+// TSConfig will hold repositories that will eventually be injected into
+// this service layer. When RevokeAllOnReuse is set, replaying a rotated
+// refresh token signs the user out everywhere rather than only revoking
+// the token's family. PrevPubKeys holds keys which no longer sign tokens,
+// but which are still accepted until tokens they signed have expired.
+// MFAChallengeSecret signs the challenges issued in place of tokens to
+// users with two-factor authentication, and must differ from RefreshSecret.
+// OIDCIssuer is the iss claim of ID tokens issued to OpenID Connect clients.
+// Signouts are only published as events with an EventBroker. Issuing
+// tokens and signing out are audited with an AuditRepository. Without a
+// Logger, entries are written to stderr
 type TSConfig struct {
 	TokenRepository			model.TokenRepository
 	PrivKey 				*rsa.PrivateKey
 	PubKey 					*rsa.PublicKey
+	PrevPubKeys 			[]*rsa.PublicKey
 	RefreshSecret 			string
 	IDExpiratonSecs 		int64
 	RefreshExpirationSecs 	int64
+	RevokeAllOnReuse 		bool
+	MFAChallengeSecret 		string
+	MFAChallengeExpirationSecs int64
+	OIDCIssuer 				string
+	EventBroker 			model.EventBroker
+	AuditRepository 		model.AuditRepository
+	Logger 					model.Logger
 }
 
 func NewTokenService(c *TSConfig) model.TokenService {
+	kid := keyID(c.PubKey)
+	pubKeys := map[string]*rsa.PublicKey{kid: c.PubKey}
+
+	for _, k := range c.PrevPubKeys {
+		pubKeys[keyID(k)] = k
+	}
+
 	return &tokenService{
 		TokenRepository: c.TokenRepository,
 		PrivKey: 		c.PrivKey,
-		PubKey: 		c.PubKey,
+		KeyID: 			kid,
+		PubKeys: 		pubKeys,
 		RefreshSecret:	c.RefreshSecret,
 		IDExpiratonSecs: c.IDExpiratonSecs,
 		RefreshExpirationSecs: c.RefreshExpirationSecs,
+		RevokeAllOnReuse: c.RevokeAllOnReuse,
+		MFAChallengeSecret: c.MFAChallengeSecret,
+		MFAChallengeExpirationSecs: c.MFAChallengeExpirationSecs,
+		OIDCIssuer: c.OIDCIssuer,
+		EventBroker: c.EventBroker,
+		AuditRepository: c.AuditRepository,
+		Logger: 		logging.OrDefault(c.Logger),
 	}
 }
 
+// NewPairFromUser creates fresh id and refresh tokens for the current user.
+// If a previous token is included, the previous token is rotated out and the
+// new refresh token continues its session (token family). Disabled and
+// deleted users can't get tokens, however they signed in
 func (s *tokenService) NewPairFromUser(ctx context.Context, u *model.User, prevTokenID string) (*model.TokenPair, error){
+	pair, err := s.newPairFromUser(ctx, u, prevTokenID)
 
+	action := model.AuditSessionStart
 	if prevTokenID != "" {
-		if err := s.TokenRepository.DeleteRefreshToken(ctx, u.UID.String(), prevTokenID); err != nil {
-			log.Printf("Could not delete previous refreshToken for uid: %v, tokenID: %v\n", u.UID.String(), prevTokenID)
-
-			return nil, err
-		}
+		action = model.AuditTokenRefresh
 	}
 
-	idToken, err := generateIDToken(u, s.PrivKey, s.IDExpiratonSecs)
+	uid := u.UID
+	recordAudit(ctx, s.Logger, s.AuditRepository, &uid, action, err)
+
+	return pair, err
+}
+
+func (s *tokenService) newPairFromUser(ctx context.Context, u *model.User, prevTokenID string) (*model.TokenPair, error) {
+	session, err := s.rotateSession(ctx, u, prevTokenID, "")
 
 	if err != nil {
-		log.Printf("Error generating idToken for uid: %v. Error: %v\n", u.UID, err.Error())
-		return nil, apperrors.NewInternal()
+		return nil, err
 	}
 
-	refreshToken, err := generateRefreshToken(u.UID, s.RefreshSecret, s.RefreshExpirationSecs)
+	idToken, err := generateIDToken(u, s.PrivKey, s.KeyID, s.IDExpiratonSecs)
 
 	if err != nil {
-		log.Printf("Error generating refreshToken for uid: %v. Error: %v\n", u.UID, err.Error())
+		s.Logger.Error(ctx, "Unable to generate idToken", "uid", u.UID, "err", err)
 		return nil, apperrors.NewInternal()
 	}
 
-	if err := s.TokenRepository.SetRefreshToken(ctx, u.UID.String(), refreshToken.ID.String(), refreshToken.ExpiresIn); err != nil {
-		log.Printf("Error storing tokenID for uid: %v. Error: %v\n", u.UID, err.Error())
-		return nil, apperrors.NewInternal()
+	refreshToken, err := s.newRefreshToken(ctx, u, session)
+
+	if err != nil {
+		return nil, err
 	}
 
 	return &model.TokenPair{
@@ -74,40 +127,298 @@ func (s *tokenService) NewPairFromUser(ctx context.Context, u *model.User, prevT
 	}, nil
 }
 
-//Signout reaches out to the repository layer to delete all valid token for a user
+// rotateSession returns the session new tokens for u belong to. With a
+// prevTokenID, the token is rotated out and its session continued, but
+// only for the OpenID Connect client, if any, the session was started for.
+// Otherwise a new session is started for clientID
+func (s *tokenService) rotateSession(ctx context.Context, u *model.User, prevTokenID string, clientID string) (*model.Session, error) {
+	if u.Disabled {
+		return nil, apperrors.NewForbidden("Your account has been disabled")
+	}
+
+	if u.DeletedAt != nil {
+		return nil, apperrors.NewForbidden("Your account has been deleted")
+	}
+
+	var session *model.Session
+
+	if prevTokenID != "" {
+		prevSession, err := s.TokenRepository.DeleteRefreshToken(ctx, u.UID.String(), prevTokenID)
+
+		if err != nil {
+			s.Logger.Info(ctx, "Unable to delete previous refresh token", "uid", u.UID, "tokenID", prevTokenID, "err", err)
+
+			return nil, s.checkRefreshTokenReuse(ctx, u.UID, prevTokenID, err)
+		}
+
+		// the token was checked before, but a client must never continue
+		// another client's session
+		if prevSession != nil && prevSession.ClientID != clientID {
+			s.Logger.Warn(ctx, "Refresh token used by another client", "uid", u.UID, "tokenID", prevTokenID, "clientID", clientID)
+			return nil, apperrors.NewAuthorization("Invalid refresh token")
+		}
+
+		session = prevSession
+	}
+
+	now := time.Now()
+
+	// signin, signup and tokens minted before sessions existed start a new session
+	if session == nil {
+		sessionID, err := uuid.NewRandom()
+
+		if err != nil {
+			s.Logger.Error(ctx, "Unable to generate session ID", "uid", u.UID, "err", err)
+			return nil, apperrors.NewInternal()
+		}
+
+		session = &model.Session{
+			ID:        sessionID.String(),
+			CreatedAt: now,
+			ClientID:  clientID,
+		}
+	}
+
+	client := model.ClientInfoFromContext(ctx)
+	session.LastRefreshedAt = now
+	session.UserAgent = client.UserAgent
+	session.IP = client.IP
+
+	return session, nil
+}
+
+// newRefreshToken creates and stores the session's next refresh token
+func (s *tokenService) newRefreshToken(ctx context.Context, u *model.User, session *model.Session) (*refreshTokenData, error) {
+	refreshToken, err := generateRefreshToken(u.UID, session, s.RefreshSecret, s.RefreshExpirationSecs)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to generate refreshToken", "uid", u.UID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := s.TokenRepository.SetRefreshToken(ctx, u.UID.String(), refreshToken.ID.String(), session, refreshToken.ExpiresIn); err != nil {
+		s.Logger.Error(ctx, "Unable to store refresh token ID", "uid", u.UID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return refreshToken, nil
+}
+
+// NewOIDCTokens creates tokens for the OpenID Connect client of an
+// authorization. They start or continue a session of the client's own,
+// with an access token only accepted by the userinfo endpoint. An ID
+// token for the client is included when the tokens are for a new
+// authorization, rather than a refresh
+func (s *tokenService) NewOIDCTokens(ctx context.Context, u *model.User, authorization *model.OIDCAuthorization, prevTokenID string) (*model.OIDCTokens, error) {
+	tokens, err := s.newOIDCTokens(ctx, u, authorization, prevTokenID)
+
+	action := model.AuditSessionStart
+	if prevTokenID != "" {
+		action = model.AuditTokenRefresh
+	}
+
+	uid := u.UID
+	recordAudit(ctx, s.Logger, s.AuditRepository, &uid, action, err)
+
+	return tokens, err
+}
+
+func (s *tokenService) newOIDCTokens(ctx context.Context, u *model.User, authorization *model.OIDCAuthorization, prevTokenID string) (*model.OIDCTokens, error) {
+	session, err := s.rotateSession(ctx, u, prevTokenID, authorization.ClientID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	session.Scope = authorization.Scope
+
+	accessToken, err := generateOIDCAccessToken(u.UID, authorization.ClientID, authorization.Scope, s.OIDCIssuer, s.PrivKey, s.KeyID, s.IDExpiratonSecs)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to generate OIDC accessToken", "uid", u.UID, "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	refreshToken, err := s.newRefreshToken(ctx, u, session)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := &model.OIDCTokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    s.IDExpiratonSecs,
+		RefreshToken: refreshToken.SS,
+		Scope:        authorization.Scope,
+	}
+
+	if prevTokenID == "" {
+		idToken, err := generateOIDCIDToken(u, authorization, s.OIDCIssuer, s.PrivKey, s.KeyID, s.IDExpiratonSecs)
+
+		if err != nil {
+			s.Logger.Error(ctx, "Unable to generate OIDC idToken", "uid", u.UID, "err", err)
+			return nil, apperrors.NewInternal()
+		}
+
+		tokens.IDToken = idToken
+	}
+
+	return tokens, nil
+}
+
+// checkRefreshTokenReuse is called when a refresh token could not be rotated.
+// A token which was already rotated is being replayed, which means it has
+// likely been stolen, so we revoke its session (or all of the user's sessions)
+func (s *tokenService) checkRefreshTokenReuse(ctx context.Context, uid uuid.UUID, tokenID string, rotateErr error) error {
+	sessionID, err := s.TokenRepository.FindRotatedRefreshToken(ctx, uid.String(), tokenID)
+
+	if err != nil || sessionID == "" {
+		return rotateErr
+	}
+
+	s.Logger.Warn(ctx, "Refresh token reuse detected, revoking tokens", "uid", uid, "tokenID", tokenID, "sessionID", sessionID)
+
+	if s.RevokeAllOnReuse {
+		err = s.TokenRepository.DeleateUserRefreshTokens(ctx, uid.String())
+	} else {
+		err = s.TokenRepository.DeleteSession(ctx, uid.String(), sessionID)
+	}
+
+	// the session may already have been revoked or expired
+	if err != nil && apperrors.Status(err) != http.StatusNotFound {
+		s.Logger.Error(ctx, "Unable to revoke tokens after refresh token reuse", "uid", uid, "err", err)
+		return apperrors.NewInternal()
+	}
+
+	return apperrors.NewAuthorization("Refresh token has already been used")
+}
+
+//Signout reaches out to the repository layer to delete all valid token for a user.
+// It is audited as an admin's when ctx carries one
 func (s *tokenService) Signout(ctx context.Context, uid uuid.UUID) error {
-	return s.TokenRepository.DeleateUserRefreshTokens(ctx, uid.String())
+	err := s.TokenRepository.DeleateUserRefreshTokens(ctx, uid.String())
+
+	if model.AdminFromContext(ctx) != nil {
+		recordAdminAudit(ctx, s.Logger, s.AuditRepository, uid, model.AuditAdminSignout, err)
+	} else {
+		recordAudit(ctx, s.Logger, s.AuditRepository, &uid, model.AuditSignout, err)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return publishEvent(ctx, s.Logger, s.EventBroker, newEvent(model.EventUserSignedOut, uid, nil))
+}
+
+// GetSessions lists the user's signed in devices
+func (s *tokenService) GetSessions(ctx context.Context, uid uuid.UUID) ([]*model.Session, error) {
+	return s.TokenRepository.GetSessions(ctx, uid.String())
 }
 
-func (s *tokenService) ValidateIDToken(tokenString string) (*model.User, error) {
-	claims, err := validateIDToken(tokenString, s.PubKey)
+// RevokeSession signs the user out of a single device
+func (s *tokenService) RevokeSession(ctx context.Context, uid uuid.UUID, sessionID string) error {
+	return s.TokenRepository.DeleteSession(ctx, uid.String(), sessionID)
+}
+
+// GetJWKS returns the public keys ID tokens can be verified with, the
+// current signing key first
+func (s *tokenService) GetJWKS() *model.JWKS {
+	jwks := &model.JWKS{
+		Keys: []model.JWK{toJWK(s.PubKeys[s.KeyID], s.KeyID)},
+	}
+
+	for kid, key := range s.PubKeys {
+		if kid != s.KeyID {
+			jwks.Keys = append(jwks.Keys, toJWK(key, kid))
+		}
+	}
+
+	return jwks
+}
+
+// NewMFAChallenge creates a short lived token showing that the user has
+// signed in with their password, to be exchanged for tokens along with
+// their second factor
+func (s *tokenService) NewMFAChallenge(ctx context.Context, u *model.User) (string, error) {
+	challenge, err := generateMFAChallenge(u.UID, s.MFAChallengeSecret, s.MFAChallengeExpirationSecs)
 
 	if err != nil {
-		log.Printf("Unable to validate or parse idToken - Error: %v\n", err)
+		s.Logger.Error(ctx, "Unable to generate MFA challenge", "uid", u.UID, "err", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return challenge, nil
+}
+
+// ValidateMFAChallenge returns the uid of the user an MFA challenge was issued to
+func (s *tokenService) ValidateMFAChallenge(ctx context.Context, tokenString string) (uuid.UUID, error) {
+	claims, err := validateMFAChallenge(tokenString, s.MFAChallengeSecret)
+
+	if err != nil {
+		s.Logger.Debug(ctx, "Unable to validate or parse MFA challenge", "err", err)
+		return uuid.Nil, apperrors.NewAuthorization("Two-factor authentication has expired, please sign in again")
+	}
+
+	return claims.UID, nil
+}
+
+func (s *tokenService) ValidateIDToken(ctx context.Context, tokenString string) (*model.User, error) {
+	claims, err := validateIDToken(tokenString, s.PubKeys, s.KeyID)
+
+	if err != nil {
+		s.Logger.Debug(ctx, "Unable to validate or parse idToken", "err", err)
 		return nil, apperrors.NewAuthorization("Unable to verify user from idToken")
 	}
 
 	return claims.User, nil
 }
 
-func (s *tokenService) ValidateRefreshToken(tokenString string) (*model.RefreshToken, error) {
+func (s *tokenService) ValidateRefreshToken(ctx context.Context, tokenString string) (*model.RefreshToken, error) {
 
 	claims, err := validateRefreshToken(tokenString, s.RefreshSecret)
 
 	if err != nil {
-		log.Printf("Unable to validate or parse refreshToken for token string: %s\n%v\n", tokenString, err)
+		s.Logger.Debug(ctx, "Unable to validate or parse refreshToken", "err", err)
 		return nil, apperrors.NewAuthorization("Unable to verify user from rehresh token")
 	}
 
 	tokenUUID, err := uuid.Parse(claims.Id)
 
 	if err != nil {
-		log.Printf("Claims ID could not be parsed as UUID: %s\n%v\n", claims.Id, err)
+		s.Logger.Warn(ctx, "Refresh token ID could not be parsed as UUID", "tokenID", claims.Id, "err", err)
 		return nil, apperrors.NewAuthorization("Unable to verity user from refresh token")
 	}
 	return &model.RefreshToken{
 		SS: tokenString,
 		ID: tokenUUID,
 		UID: claims.UID,
+		ClientID: claims.ClientID,
+		Scope: claims.Scope,
+	}, nil
+}
+
+// ValidateOIDCAccessToken returns the user, client and scope of an
+// OpenID Connect client's access token
+func (s *tokenService) ValidateOIDCAccessToken(ctx context.Context, tokenString string) (*model.OIDCAccessToken, error) {
+	claims, err := validateOIDCAccessToken(tokenString, s.PubKeys, s.KeyID, s.OIDCIssuer)
+
+	if err != nil {
+		s.Logger.Debug(ctx, "Unable to validate or parse OIDC accessToken", "err", err)
+		return nil, apperrors.NewAuthorization("Unable to verify access token")
+	}
+
+	uid, err := uuid.Parse(claims.Subject)
+
+	if err != nil {
+		s.Logger.Warn(ctx, "OIDC access token subject could not be parsed as UUID", "sub", claims.Subject, "err", err)
+		return nil, apperrors.NewAuthorization("Unable to verify access token")
+	}
+
+	return &model.OIDCAccessToken{
+		UID:      uid,
+		ClientID: claims.ClientID,
+		Scope:    claims.Scope,
 	}, nil
 }
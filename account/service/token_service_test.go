@@ -2,14 +2,21 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"fmt"
+	"math/big"
 	"io/ioutil"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/events"
 	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
 	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -49,30 +56,36 @@ func TestNewPairFromUser(t *testing.T) {
 		Password: "samplepassword",
 	}
 	prevID := "a_previous_tokenID"
+	prevSession := &model.Session{
+		ID:        "a_previous_sessionID",
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
 
 	setSuccessArguments := mock.Arguments {
-		mock.AnythingOfType("*context.emptyCtx"),
+		mock.Anything,
 		u.UID.String(),
 		mock.AnythingOfType("string"),
+		mock.AnythingOfType("*model.Session"),
 		mock.AnythingOfType("time.Duration"),
 	}
 
 	setErrorArguments := mock.Arguments{
-		mock.AnythingOfType("*context.emptyCtx"),
+		mock.Anything,
 		uidErrorCase.String(),
 		mock.AnythingOfType("string"),
+		mock.AnythingOfType("*model.Session"),
 		mock.AnythingOfType("time.Duration"),
 	}
 
 	deleteWithPrevIDArguments := mock.Arguments{
-		mock.AnythingOfType("*context.emptyCtx"),
+		mock.Anything,
 		u.UID.String(),
 		prevID,
 	}
 
 	mockTokenRepository.On("SetRefreshToken", setSuccessArguments...).Return(nil)
 	mockTokenRepository.On("SetRefreshToken", setErrorArguments...).Return(fmt.Errorf("Error setting refresh token"))
-	mockTokenRepository.On("DeleteRefreshToken", deleteWithPrevIDArguments...).Return(nil)
+	mockTokenRepository.On("DeleteRefreshToken", deleteWithPrevIDArguments...).Return(prevSession, nil)
 
 	t.Run("Returns a token pair with proper values", func(t *testing.T) {
 		ctx := context.Background()
@@ -82,16 +95,28 @@ func TestNewPairFromUser(t *testing.T) {
 		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setSuccessArguments...)
 		mockTokenRepository.AssertCalled(t, "DeleteRefreshToken", deleteWithPrevIDArguments...)
 
+		// the new refresh token continues the previous token's session
+		mockTokenRepository.AssertCalled(t, "SetRefreshToken",
+			mock.Anything,
+			u.UID.String(),
+			tokenPair.RefreshToken.ID.String(),
+			mock.MatchedBy(func(s *model.Session) bool {
+				return s.ID == prevSession.ID && s.LastRefreshedAt.After(s.CreatedAt)
+			}),
+			mock.AnythingOfType("time.Duration"),
+		)
+
 		var s string
 		assert.IsType(t, s, tokenPair.IDToken.SS)
 
 		idTokenClaims := &idTokenCustomClaims{}
 
-		_, err = jwt.ParseWithClaims(tokenPair.IDToken.SS, idTokenClaims, func(token *jwt.Token) (interface{}, error) {
+		idToken, err := jwt.ParseWithClaims(tokenPair.IDToken.SS, idTokenClaims, func(token *jwt.Token) (interface{}, error) {
 			return pubKey, nil
 		})
 
 		assert.NoError(t, err)
+		assert.Equal(t, keyID(pubKey), idToken.Header["kid"])
 
 		expectedClaims := []interface{}{
 			u.UID,
@@ -141,6 +166,28 @@ func TestNewPairFromUser(t *testing.T) {
 		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
 	})
 
+	t.Run("New session records client info", func(t *testing.T) {
+		ctx := model.ContextWithClientInfo(context.Background(), model.ClientInfo{
+			IP:        "10.0.0.1",
+			UserAgent: "Mozilla/5.0",
+		})
+		_, err := tokenService.NewPairFromUser(ctx, u, "")
+		assert.NoError(t, err)
+
+		mockTokenRepository.AssertCalled(t, "SetRefreshToken",
+			mock.Anything,
+			u.UID.String(),
+			mock.AnythingOfType("string"),
+			mock.MatchedBy(func(s *model.Session) bool {
+				return s.ID != prevSession.ID &&
+					s.IP == "10.0.0.1" &&
+					s.UserAgent == "Mozilla/5.0" &&
+					s.CreatedAt.Equal(s.LastRefreshedAt)
+			}),
+			mock.AnythingOfType("time.Duration"),
+		)
+	})
+
 	t.Run("Empty string provided for prevID", func(t *testing.T) {
 		ctx := context.Background()
 		_, err := tokenService.NewPairFromUser(ctx, u, "")
@@ -150,4 +197,397 @@ func TestNewPairFromUser(t *testing.T) {
 
 		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
 	})
-}
\ No newline at end of file
+}
+
+func TestNewPairFromDisabledOrDeletedUser(t *testing.T) {
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository: mockTokenRepository,
+		PrivKey:         privKey,
+		PubKey:          pubKey,
+		RefreshSecret:   "randomtestsecret",
+	})
+
+	uid, _ := uuid.NewRandom()
+
+	_, err := tokenService.NewPairFromUser(context.TODO(), &model.User{UID: uid, Disabled: true}, "")
+
+	assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+
+	deletedAt := time.Now()
+	_, err = tokenService.NewPairFromUser(context.TODO(), &model.User{UID: uid, DeletedAt: &deletedAt}, "")
+
+	assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+	mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSignout(t *testing.T) {
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+	uid, _ := uuid.NewRandom()
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	broker := events.NewMemoryBroker()
+
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository: mockTokenRepository,
+		PrivKey:         privKey,
+		PubKey:          pubKey,
+		EventBroker:     broker,
+	})
+
+	mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+
+	err := tokenService.Signout(context.TODO(), uid)
+
+	assert.NoError(t, err)
+
+	published := broker.Events()
+	assert.Len(t, published, 1)
+	assert.Equal(t, model.EventUserSignedOut, published[0].Type)
+	assert.Equal(t, uid, published[0].UID)
+}
+
+func TestRefreshTokenReuse(t *testing.T) {
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+	}
+
+	rotatedID := "a_rotated_tokenID"
+	sessionID := "a_sessionID"
+	invalidErr := apperrors.NewAuthorization("Invalid refresh token")
+
+	newTokenService := func(repo *mocks.MockTokenRepository, revokeAll bool) model.TokenService {
+		return NewTokenService(&TSConfig{
+			TokenRepository:       repo,
+			PrivKey:               privKey,
+			PubKey:                pubKey,
+			RefreshSecret:         "randomtestsecret",
+			IDExpiratonSecs:       15 * 60,
+			RefreshExpirationSecs: 3 * 24 * 3600,
+			RevokeAllOnReuse:      revokeAll,
+		})
+	}
+
+	t.Run("Revokes session", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, uid.String(), rotatedID).Return(nil, invalidErr)
+		mockTokenRepository.On("FindRotatedRefreshToken", mock.Anything, uid.String(), rotatedID).Return(sessionID, nil)
+		mockTokenRepository.On("DeleteSession", mock.Anything, uid.String(), sessionID).Return(nil)
+
+		tokenPair, err := newTokenService(mockTokenRepository, false).NewPairFromUser(context.TODO(), u, rotatedID)
+
+		assert.Nil(t, tokenPair)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockTokenRepository.AssertExpectations(t)
+		mockTokenRepository.AssertNotCalled(t, "DeleateUserRefreshTokens", mock.Anything, mock.Anything)
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Revokes all user tokens", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, uid.String(), rotatedID).Return(nil, invalidErr)
+		mockTokenRepository.On("FindRotatedRefreshToken", mock.Anything, uid.String(), rotatedID).Return(sessionID, nil)
+		mockTokenRepository.On("DeleateUserRefreshTokens", mock.Anything, uid.String()).Return(nil)
+
+		tokenPair, err := newTokenService(mockTokenRepository, true).NewPairFromUser(context.TODO(), u, rotatedID)
+
+		assert.Nil(t, tokenPair)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockTokenRepository.AssertExpectations(t)
+		mockTokenRepository.AssertNotCalled(t, "DeleteSession", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown token is not treated as reuse", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, uid.String(), rotatedID).Return(nil, invalidErr)
+		mockTokenRepository.On("FindRotatedRefreshToken", mock.Anything, uid.String(), rotatedID).Return("", nil)
+
+		_, err := newTokenService(mockTokenRepository, false).NewPairFromUser(context.TODO(), u, rotatedID)
+
+		assert.Equal(t, invalidErr, err)
+		mockTokenRepository.AssertNotCalled(t, "DeleteSession", mock.Anything, mock.Anything, mock.Anything)
+		mockTokenRepository.AssertNotCalled(t, "DeleateUserRefreshTokens", mock.Anything, mock.Anything)
+	})
+}
+
+func TestValidateIDToken(t *testing.T) {
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+	// the key which signed tokens before the current one
+	prevPrivKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	tokenService := NewTokenService(&TSConfig{
+		PrivKey:     privKey,
+		PubKey:      pubKey,
+		PrevPubKeys: []*rsa.PublicKey{&prevPrivKey.PublicKey},
+	})
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+	}
+
+	t.Run("Current key", func(t *testing.T) {
+		ss, _ := generateIDToken(u, privKey, keyID(pubKey), 60)
+
+		user, err := tokenService.ValidateIDToken(context.TODO(), ss)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, user.UID)
+	})
+
+	t.Run("Previous key", func(t *testing.T) {
+		ss, _ := generateIDToken(u, prevPrivKey, keyID(&prevPrivKey.PublicKey), 60)
+
+		user, err := tokenService.ValidateIDToken(context.TODO(), ss)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, user.UID)
+	})
+
+	t.Run("Token without kid uses current key", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idTokenCustomClaims{
+			User: u,
+			StandardClaims: jwt.StandardClaims{
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+			},
+		})
+		ss, _ := token.SignedString(privKey)
+
+		user, err := tokenService.ValidateIDToken(context.TODO(), ss)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, user.UID)
+	})
+
+	t.Run("Unknown kid", func(t *testing.T) {
+		retiredKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+
+		ss, _ := generateIDToken(u, retiredKey, keyID(&retiredKey.PublicKey), 60)
+
+		user, err := tokenService.ValidateIDToken(context.TODO(), ss)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Kid does not match signing key", func(t *testing.T) {
+		ss, _ := generateIDToken(u, prevPrivKey, keyID(pubKey), 60)
+
+		user, err := tokenService.ValidateIDToken(context.TODO(), ss)
+
+		assert.Nil(t, user)
+		assert.Error(t, err)
+	})
+
+	t.Run("OIDC ID token", func(t *testing.T) {
+		ss, _ := generateOIDCIDToken(u, &model.OIDCAuthorization{ClientID: "deck", Scope: "openid"}, "https://memrizr.test/api/account", privKey, keyID(pubKey), 60)
+
+		user, err := tokenService.ValidateIDToken(context.TODO(), ss)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Lists current key first in JWKS", func(t *testing.T) {
+		jwks := tokenService.GetJWKS()
+
+		assert.Len(t, jwks.Keys, 2)
+		assert.Equal(t, keyID(pubKey), jwks.Keys[0].Kid)
+		assert.Equal(t, keyID(&prevPrivKey.PublicKey), jwks.Keys[1].Kid)
+		assert.Equal(t, "AQAB", jwks.Keys[0].E)
+		assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+	})
+}
+
+func TestMFAChallenge(t *testing.T) {
+	pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+	secret := "mfachallengesecret"
+
+	tokenService := NewTokenService(&TSConfig{
+		PubKey:                     pubKey,
+		RefreshSecret:              secret,
+		MFAChallengeSecret:         secret,
+		MFAChallengeExpirationSecs: 60,
+	})
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{UID: uid}
+
+	t.Run("Round trip", func(t *testing.T) {
+		challenge, err := tokenService.NewMFAChallenge(context.TODO(), u)
+		assert.NoError(t, err)
+
+		challengeUID, err := tokenService.ValidateMFAChallenge(context.TODO(), challenge)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, challengeUID)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		challenge, _ := generateMFAChallenge(uid, secret, -1)
+
+		_, err := tokenService.ValidateMFAChallenge(context.TODO(), challenge)
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Other tokens are not challenges", func(t *testing.T) {
+		// even when signed with the same secret
+		refreshToken, _ := generateRefreshToken(uid, &model.Session{}, secret, 60)
+
+		_, err := tokenService.ValidateMFAChallenge(context.TODO(), refreshToken.SS)
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+}
+
+func TestNewOIDCTokens(t *testing.T) {
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
+	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockTokenRepository.On("SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository:       mockTokenRepository,
+		PrivKey:               privKey,
+		PubKey:                pubKey,
+		RefreshSecret:         "refreshsecret",
+		IDExpiratonSecs:       15 * 60,
+		RefreshExpirationSecs: 60 * 60,
+		OIDCIssuer:            "https://memrizr.test/api/account",
+	})
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{UID: uid, Email: "bob@bob.com", EmailVerified: true, Name: "Bob"}
+
+	parseIDToken := func(t *testing.T, idToken string) jwt.MapClaims {
+		claims := jwt.MapClaims{}
+
+		token, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+			assert.Equal(t, keyID(pubKey), token.Header["kid"])
+			return pubKey, nil
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, token.Valid)
+
+		return claims
+	}
+
+	t.Run("Authorization code", func(t *testing.T) {
+		tokens, err := tokenService.NewOIDCTokens(context.TODO(), u, &model.OIDCAuthorization{
+			ClientID: "deck",
+			UID:      uid,
+			Scope:    "openid email",
+			Nonce:    "nonce",
+			AuthTime: 1234,
+		}, "")
+		assert.NoError(t, err)
+
+		assert.Equal(t, "Bearer", tokens.TokenType)
+		assert.Equal(t, int64(15*60), tokens.ExpiresIn)
+		assert.Equal(t, "openid email", tokens.Scope)
+
+		// the access token is only good for userinfo, with the authorized scope
+		access, err := tokenService.ValidateOIDCAccessToken(context.TODO(), tokens.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, &model.OIDCAccessToken{UID: uid, ClientID: "deck", Scope: "openid email"}, access)
+
+		accessUser, err := tokenService.ValidateIDToken(context.TODO(), tokens.AccessToken)
+		assert.Nil(t, accessUser)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+
+		// the refresh token, and its session, belong to the client
+		refreshToken, err := tokenService.ValidateRefreshToken(context.TODO(), tokens.RefreshToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "deck", refreshToken.ClientID)
+		assert.Equal(t, "openid email", refreshToken.Scope)
+
+		mockTokenRepository.AssertCalled(t, "SetRefreshToken", mock.Anything, uid.String(), refreshToken.ID.String(), mock.MatchedBy(func(s *model.Session) bool {
+			return s.ClientID == "deck" && s.Scope == "openid email"
+		}), mock.Anything)
+
+		claims := parseIDToken(t, tokens.IDToken)
+		assert.Equal(t, "https://memrizr.test/api/account", claims["iss"])
+		assert.Equal(t, uid.String(), claims["sub"])
+		assert.Equal(t, "deck", claims["aud"])
+		assert.Equal(t, "nonce", claims["nonce"])
+		assert.Equal(t, float64(1234), claims["auth_time"])
+		assert.Equal(t, "bob@bob.com", claims["email"])
+		assert.Equal(t, true, claims["email_verified"])
+
+		// the profile scope wasn't authorized
+		assert.NotContains(t, claims, "name")
+
+		// nor is an ID token for a client an access token
+		_, err = tokenService.ValidateOIDCAccessToken(context.TODO(), tokens.IDToken)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, uid.String(), "prevTokenID").Return(&model.Session{ID: "session", ClientID: "deck", Scope: "openid"}, nil)
+
+		tokens, err := tokenService.NewOIDCTokens(context.TODO(), u, &model.OIDCAuthorization{ClientID: "deck", UID: uid, Scope: "openid"}, "prevTokenID")
+		assert.NoError(t, err)
+
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.NotEmpty(t, tokens.RefreshToken)
+		assert.Equal(t, "openid", tokens.Scope)
+		assert.Empty(t, tokens.IDToken)
+	})
+
+	t.Run("Refresh of another client's session", func(t *testing.T) {
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, uid.String(), "webTokenID").Return(&model.Session{ID: "websession"}, nil)
+
+		tokens, err := tokenService.NewOIDCTokens(context.TODO(), u, &model.OIDCAuthorization{ClientID: "deck", UID: uid, Scope: "openid"}, "webTokenID")
+		assert.Nil(t, tokens)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Web client can't refresh a client's session", func(t *testing.T) {
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, uid.String(), "clientTokenID").Return(&model.Session{ID: "clientsession", ClientID: "deck"}, nil)
+
+		pair, err := tokenService.NewPairFromUser(context.TODO(), u, "clientTokenID")
+		assert.Nil(t, pair)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+}
+
+func TestKeyID(t *testing.T) {
+	// example key from RFC 7638 section 3.1
+	n, _ := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: 65537,
+	}
+
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", keyID(key))
+}
@@ -3,7 +3,6 @@ package service
 import (
 	"crypto/rsa"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -16,7 +15,9 @@ type idTokenCustomClaims struct {
 	jwt.StandardClaims
 }
 
-func generateIDToken(u *model.User, key *rsa.PrivateKey, exp int64) (string, error) {
+// generateIDToken signs an ID token, recording the signing key's ID in
+// the kid header so it can be verified after the key is rotated
+func generateIDToken(u *model.User, key *rsa.PrivateKey, kid string, exp int64) (string, error) {
 	unixTime := time.Now().Unix()
 	tokenExp := unixTime + exp
 
@@ -29,10 +30,10 @@ func generateIDToken(u *model.User, key *rsa.PrivateKey, exp int64) (string, err
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
 	ss, err := token.SignedString(key)
 
 	if err != nil {
-		log.Println("Failed to sign id tokn string")
 		return "", err
 	}
 
@@ -48,23 +49,28 @@ type refreshTokenData struct {
 	ExpiresIn 	time.Duration
 }
 
+// refresh tokens of OpenID Connect clients' sessions name the client and
+// the scope it was authorized, so they can't be used by anyone else
 type refreshTokenCustomClaims struct {
-	UID uuid.UUID `json:"uid"`
+	UID      uuid.UUID `json:"uid"`
+	ClientID string    `json:"client_id,omitempty"`
+	Scope    string    `json:"scope,omitempty"`
 	jwt.StandardClaims
 }
 
-func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenData, error) {
+func generateRefreshToken(uid uuid.UUID, session *model.Session, key string, exp int64) (*refreshTokenData, error) {
 	currentTime := time.Now()
 	tokenExp := currentTime.Add(time.Duration(exp) * time.Second)
 	tokenID, err := uuid.NewRandom()
 
 	if err != nil {
-		log.Println("Failed to generat refresh toke ID")
 		return nil, err
 	}
 
 	claims := refreshTokenCustomClaims{
 		UID: uid,
+		ClientID: session.ClientID,
+		Scope: session.Scope,
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt: 	currentTime.Unix(),
 			ExpiresAt: 	tokenExp.Unix(),
@@ -76,7 +82,6 @@ func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenDa
 	ss, err := token.SignedString([]byte(key))
 
 	if err != nil {
-		log.Println("Failed to sign refresh token string")
 		return nil, err
 	}
 
@@ -87,12 +92,35 @@ func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenDa
 	}, nil
 }
 
-func validateIDToken(tokenString string, key *rsa.PublicKey) (*idTokenCustomClaims, error) {
-	claims := &idTokenCustomClaims{}
+// rsaKeyFunc finds the key to verify a token with by its kid header.
+// Tokens without a kid were signed before keys had IDs, and are checked
+// against defaultKID
+func rsaKeyFunc(keys map[string]*rsa.PublicKey, defaultKID string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid := defaultKID
+		if v, ok := token.Header["kid"]; ok {
+			kid, _ = v.(string)
+		}
+
+		key, ok := keys[kid]
+
+		if !ok {
+			return nil, fmt.Errorf("Unknown signing key: %v", kid)
+		}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return key, nil
-	})
+	}
+}
+
+// validateIDToken verifies an ID token with the key named by its kid header
+func validateIDToken(tokenString string, keys map[string]*rsa.PublicKey, defaultKID string) (*idTokenCustomClaims, error) {
+	claims := &idTokenCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, rsaKeyFunc(keys, defaultKID))
 
 	if err != nil {
 		return nil, err
@@ -108,6 +136,12 @@ func validateIDToken(tokenString string, key *rsa.PublicKey) (*idTokenCustomClai
 		return nil, fmt.Errorf("ID token valid but couldn't parse claims")
 	}
 
+	// tokens for OpenID Connect clients are signed with the same key,
+	// but are for their audience, not us, and carry no user
+	if claims.User == nil || claims.Audience != "" {
+		return nil, fmt.Errorf("Token is not a memrizr ID token")
+	}
+
 	return claims, nil
 }
 
@@ -134,3 +168,174 @@ func validateRefreshToken(tokenString string, key string) (*refreshTokenCustomCl
 	return claims, nil
 }
 
+
+// purpose claim of MFA challenges, so they can't be mistaken for
+// another kind of token should the secret be shared
+const mfaChallengePurpose = "mfa_challenge"
+
+type mfaChallengeCustomClaims struct {
+	UID     uuid.UUID `json:"uid"`
+	Purpose string    `json:"purpose"`
+	jwt.StandardClaims
+}
+
+func generateMFAChallenge(uid uuid.UUID, key string, exp int64) (string, error) {
+	unixTime := time.Now().Unix()
+
+	claims := mfaChallengeCustomClaims{
+		UID:     uid,
+		Purpose: mfaChallengePurpose,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  unixTime,
+			ExpiresAt: unixTime + exp,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ss, err := token.SignedString([]byte(key))
+
+	if err != nil {
+		return "", err
+	}
+
+	return ss, nil
+}
+
+func validateMFAChallenge(tokenString string, key string) (*mfaChallengeCustomClaims, error) {
+	claims := &mfaChallengeCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(key), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("MFA challenge is invalid")
+	}
+
+	if claims.Purpose != mfaChallengePurpose {
+		return nil, fmt.Errorf("Token is for: %s, not: %s", claims.Purpose, mfaChallengePurpose)
+	}
+
+	return claims, nil
+}
+
+type oidcIDTokenCustomClaims struct {
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Nonce         string `json:"nonce,omitempty"`
+	AuthTime      int64  `json:"auth_time,omitempty"`
+	jwt.StandardClaims
+}
+
+// generateOIDCIDToken signs an OpenID Connect ID token for the client a
+// user authorized, with the claims of the scopes they authorized. It's
+// signed with the same key as memrizr ID tokens, so is verified with the
+// JWKS
+func generateOIDCIDToken(u *model.User, a *model.OIDCAuthorization, issuer string, key *rsa.PrivateKey, kid string, exp int64) (string, error) {
+	unixTime := time.Now().Unix()
+
+	claims := oidcIDTokenCustomClaims{
+		Nonce:    a.Nonce,
+		AuthTime: a.AuthTime,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    issuer,
+			Subject:   u.UID.String(),
+			Audience:  a.ClientID,
+			IssuedAt:  unixTime,
+			ExpiresAt: unixTime + exp,
+		},
+	}
+
+	if hasScope(a.Scope, "email") {
+		claims.Email = u.Email
+		claims.EmailVerified = &u.EmailVerified
+	}
+
+	if hasScope(a.Scope, "profile") {
+		claims.Name = u.Name
+		claims.Picture = u.ImageURL
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	ss, err := token.SignedString(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	return ss, nil
+}
+
+// typ header of OpenID Connect access tokens (RFC 9068), which tells
+// them apart from ID tokens signed with the same key
+const oidcAccessTokenType = "at+jwt"
+
+type oidcAccessTokenCustomClaims struct {
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	jwt.StandardClaims
+}
+
+// generateOIDCAccessToken signs an access token for the client a user
+// authorized, which only the userinfo endpoint accepts
+func generateOIDCAccessToken(uid uuid.UUID, clientID string, scope string, issuer string, key *rsa.PrivateKey, kid string, exp int64) (string, error) {
+	unixTime := time.Now().Unix()
+	tokenID, err := uuid.NewRandom()
+
+	if err != nil {
+		return "", err
+	}
+
+	claims := oidcAccessTokenCustomClaims{
+		ClientID: clientID,
+		Scope:    scope,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    issuer,
+			Subject:   uid.String(),
+			Audience:  clientID,
+			IssuedAt:  unixTime,
+			ExpiresAt: unixTime + exp,
+			Id:        tokenID.String(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	token.Header["typ"] = oidcAccessTokenType
+
+	return token.SignedString(key)
+}
+
+func validateOIDCAccessToken(tokenString string, keys map[string]*rsa.PublicKey, defaultKID string, issuer string) (*oidcAccessTokenCustomClaims, error) {
+	claims := &oidcAccessTokenCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, rsaKeyFunc(keys, defaultKID))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("Access token is invalid")
+	}
+
+	if typ, _ := token.Header["typ"].(string); typ != oidcAccessTokenType {
+		return nil, fmt.Errorf("Token is of type: %s, not: %s", typ, oidcAccessTokenType)
+	}
+
+	if claims.Issuer != issuer || claims.ClientID == "" || claims.Audience != claims.ClientID {
+		return nil, fmt.Errorf("Access token has unexpected claims")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+)
+
+// TOTP parameters (RFC 6238). These are the defaults authenticator
+// apps assume, and some ignore any others in the provisioning URI
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// steps either side of the current one which are accepted,
+	// allowing for clock drift and slow typing
+	totpSkew         = 1
+	totpSecretLength = 20
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateTOTPSecret returns a random base32 encoded secret
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretLength)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// totpURI is the provisioning URI authenticator apps enroll with
+func totpURI(issuer string, email string, secret string) string {
+	label := url.PathEscape(issuer + ":" + email)
+
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, q.Encode())
+}
+
+// hotp computes the HOTP value (RFC 4226) of key for counter
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// totpStep is the time step now falls in
+func totpStep(now time.Time) int64 {
+	return now.Unix() / int64(totpPeriod.Seconds())
+}
+
+// validateTOTP checks code against the steps around now, returning
+// the step it was generated for
+func validateTOTP(secret string, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := totpStep(now)
+
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(step))), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// isTOTPCode reports whether code looks like a TOTP code rather than
+// a recovery code
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// generateRecoveryCodes returns recovery codes to show the user once,
+// and the hashes of them to store. Like reset tokens, they are random
+// enough that a fast hash is sufficient
+func generateRecoveryCodes() ([]string, model.RecoveryCodes, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make(model.RecoveryCodes, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, recoveryCodeLength)
+
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		// eg, "abcd-efgh-ijkl-mnop"
+		code := strings.ToLower(totpEncoding.EncodeToString(b))
+		codes[i] = fmt.Sprintf("%s-%s-%s-%s", code[0:4], code[4:8], code[8:12], code[12:16])
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case, spaces and dashes
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226, Appendix D
+	key := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range expected {
+		assert.Equal(t, code, hotp(key, uint64(counter)))
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	assert.NoError(t, err)
+
+	key, _ := totpEncoding.DecodeString(secret)
+	now := time.Unix(1700000000, 0)
+	step := totpStep(now)
+
+	t.Run("Current step", func(t *testing.T) {
+		matched, ok := validateTOTP(secret, hotp(key, uint64(step)), now)
+
+		assert.True(t, ok)
+		assert.Equal(t, step, matched)
+	})
+
+	t.Run("Adjacent steps allow for clock drift", func(t *testing.T) {
+		matched, ok := validateTOTP(secret, hotp(key, uint64(step-1)), now)
+		assert.True(t, ok)
+		assert.Equal(t, step-1, matched)
+
+		matched, ok = validateTOTP(secret, hotp(key, uint64(step+1)), now)
+		assert.True(t, ok)
+		assert.Equal(t, step+1, matched)
+	})
+
+	t.Run("Distant steps", func(t *testing.T) {
+		_, ok := validateTOTP(secret, hotp(key, uint64(step-2)), now)
+		assert.False(t, ok)
+
+		_, ok = validateTOTP(secret, hotp(key, uint64(step+2)), now)
+		assert.False(t, ok)
+	})
+
+	t.Run("Malformed codes and secrets", func(t *testing.T) {
+		_, ok := validateTOTP(secret, "12345", now)
+		assert.False(t, ok)
+
+		_, ok = validateTOTP("not base32!", hotp(key, uint64(step)), now)
+		assert.False(t, ok)
+	})
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(totpURI("Memrizr", "bob@bob.com", "JBSWY3DPEHPK3PXP"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Memrizr:bob@bob.com", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "Memrizr", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	assert.NoError(t, err)
+
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Len(t, hashes, recoveryCodeCount)
+
+	seen := map[string]bool{}
+
+	for i, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`, code)
+		assert.False(t, isTOTPCode(code))
+		assert.Equal(t, hashes[i], hashRecoveryCode(code))
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+
+	// codes may be typed without dashes or in upper case
+	assert.Equal(t, hashRecoveryCode("abcd-efgh-ijkl-mnop"), hashRecoveryCode("ABCD EFGH IJKL MNOP"))
+	assert.Equal(t, hashRecoveryCode("abcd-efgh-ijkl-mnop"), hashRecoveryCode("abcdefghijklmnop"))
+}
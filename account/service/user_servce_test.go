@@ -1,9 +1,15 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jacobsngoodwin/memrizr/account/model"
@@ -71,7 +77,7 @@ func TestSignUp(t *testing.T) {
 		})
 
 		mockUserRepository.
-			On("Create", mock.AnythingOfType("*context.emptyCtx"), mockUser).
+			On("Create", mock.Anything, mockUser).
 			Run(func(args mock.Arguments) {
 				userArg := args.Get(1).(*model.User)
 				userArg.UID = uid
@@ -101,7 +107,7 @@ func TestSignUp(t *testing.T) {
 		mockErr := apperrors.NewConflict("email", mockUser.Email)
 
 		mockUserRepository.
-			On("Create", mock.AnythingOfType("*context.emptyCtx"), mockUser).
+			On("Create", mock.Anything, mockUser).
 			Return(mockErr)
 
 		ctx := context.TODO()
@@ -111,4 +117,357 @@ func TestSignUp(t *testing.T) {
 
 		mockUserRepository.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+func TestSignin(t *testing.T) {
+	hashedPassword, _ := hashPassword("correctpassword", DefaultPasswordParams)
+	uid, _ := uuid.NewRandom()
+
+	mockUserResp := &model.User{
+		UID:      uid,
+		Email:    "bob@bob.com",
+		Password: hashedPassword,
+	}
+
+	policy := LockoutPolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     10 * time.Second,
+		Window:       time.Hour,
+	}
+
+	ctx := model.ContextWithClientInfo(context.TODO(), model.ClientInfo{IP: "10.0.0.1"})
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockSigninAttemptRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockSigninAttemptRepository := new(mocks.MockSigninAttemptRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:          mockUserRepository,
+			SigninAttemptRepository: mockSigninAttemptRepository,
+			EmailLockout:            policy,
+			IPLockout:               policy,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(mockUserResp, nil)
+
+		return us, mockUserRepository, mockSigninAttemptRepository
+	}
+
+	t.Run("Success resets email failures", func(t *testing.T) {
+		us, _, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		mockSigninAttemptRepository.On("ResetFailures", mock.Anything, "email:bob@bob.com").Return(nil)
+
+		u := &model.User{Email: "bob@bob.com", Password: "correctpassword"}
+		err := us.Signin(ctx, u)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+		mockSigninAttemptRepository.AssertCalled(t, "GetLockout", mock.Anything, "email:bob@bob.com")
+		mockSigninAttemptRepository.AssertCalled(t, "GetLockout", mock.Anything, "ip:10.0.0.1")
+		mockSigninAttemptRepository.AssertNotCalled(t, "ResetFailures", mock.Anything, "ip:10.0.0.1")
+		mockSigninAttemptRepository.AssertExpectations(t)
+	})
+
+	t.Run("Failure is counted and locks out with backoff", func(t *testing.T) {
+		us, _, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		mockSigninAttemptRepository.On("IncrementFailures", mock.Anything, "email:bob@bob.com", time.Hour).Return(int64(6), nil)
+		mockSigninAttemptRepository.On("IncrementFailures", mock.Anything, "ip:10.0.0.1", time.Hour).Return(int64(2), nil)
+		mockSigninAttemptRepository.On("SetLockout", mock.Anything, "email:bob@bob.com", 4*time.Second).Return(nil)
+
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "wrongpassword"})
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockSigninAttemptRepository.AssertNotCalled(t, "SetLockout", mock.Anything, "ip:10.0.0.1", mock.Anything)
+		mockSigninAttemptRepository.AssertNotCalled(t, "ResetFailures", mock.Anything, mock.Anything)
+		mockSigninAttemptRepository.AssertExpectations(t)
+	})
+
+	t.Run("Unknown email is counted", func(t *testing.T) {
+		us, mockUserRepository, mockSigninAttemptRepository := newService()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "nobody@bob.com").Return(nil, apperrors.NewNotFound("email", "nobody@bob.com"))
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		mockSigninAttemptRepository.On("IncrementFailures", mock.Anything, mock.Anything, time.Hour).Return(int64(1), nil)
+
+		err := us.Signin(ctx, &model.User{Email: "nobody@bob.com", Password: "wrongpassword"})
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockSigninAttemptRepository.AssertNumberOfCalls(t, "IncrementFailures", 2)
+	})
+
+	t.Run("Locked out", func(t *testing.T) {
+		us, mockUserRepository, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, "email:bob@bob.com").Return(time.Duration(0), nil)
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, "ip:10.0.0.1").Return(30*time.Second, nil)
+
+		// even the correct password is refused while locked out
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "correctpassword"})
+
+		assert.Equal(t, http.StatusTooManyRequests, apperrors.Status(err))
+		assert.Equal(t, 30, apperrors.RetryAfterSeconds(err))
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+		mockSigninAttemptRepository.AssertNotCalled(t, "IncrementFailures", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Outdated hash is upgraded", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "alice@alice.com").Return(&model.User{
+			UID:      uid,
+			Email:    "alice@alice.com",
+			Password: legacyHash("correctpassword"),
+		}, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.MatchedBy(func(pw string) bool {
+			match, err := comparePasswords(pw, "correctpassword")
+			return err == nil && match && !needsRehash(pw, DefaultPasswordParams)
+		})).Return(nil)
+
+		err := us.Signin(ctx, &model.User{Email: "alice@alice.com", Password: "correctpassword"})
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Current hash is not rehashed", func(t *testing.T) {
+		us, mockUserRepository, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		mockSigninAttemptRepository.On("ResetFailures", mock.Anything, mock.Anything).Return(nil)
+
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "correctpassword"})
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Lockout store unavailable fails open", func(t *testing.T) {
+		us, _, mockSigninAttemptRepository := newService()
+
+		mockSigninAttemptRepository.On("GetLockout", mock.Anything, mock.Anything).Return(time.Duration(0), apperrors.NewInternal())
+		mockSigninAttemptRepository.On("ResetFailures", mock.Anything, mock.Anything).Return(apperrors.NewInternal())
+
+		err := us.Signin(ctx, &model.User{Email: "bob@bob.com", Password: "correctpassword"})
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	policy := LockoutPolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     10 * time.Second,
+		Window:       time.Hour,
+	}
+
+	expected := map[int64]time.Duration{
+		1: 0,
+		3: 0,
+		4: time.Second,
+		5: 2 * time.Second,
+		6: 4 * time.Second,
+		7: 8 * time.Second,
+		8: 10 * time.Second,
+		50: 10 * time.Second,
+	}
+
+	for failures, d := range expected {
+		assert.Equal(t, d, policy.delay(failures), "failures: %d", failures)
+	}
+}
+
+func TestClearProfileImage(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		imageURL := "https://storage.googleapis.com/bucket/imageobject"
+
+		mockUser := &model.User{
+			UID:      uid,
+			Email:    "bob@bob.com",
+			ImageURL: imageURL,
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "imageobject").Return(nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, "", model.ImageThumbnails(nil)).Return(&model.User{UID: uid}, nil)
+
+		ctx := context.TODO()
+		err := us.ClearProfileImage(ctx, uid)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+		mockImageRepository.AssertExpectations(t)
+	})
+
+	t.Run("No image set", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+
+		ctx := context.TODO()
+		err := us.ClearProfileImage(ctx, uid)
+
+		assert.NoError(t, err)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("DeleteProfile Error", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:      uid,
+			ImageURL: "https://storage.googleapis.com/bucket/imageobject",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockErr := apperrors.NewInternal()
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "imageobject").Return(mockErr)
+
+		ctx := context.TODO()
+		err := us.ClearProfileImage(ctx, uid)
+
+		assert.EqualError(t, err, mockErr.Error())
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Thumbnail delete error still clears image", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			UID:      uid,
+			ImageURL: "https://storage.googleapis.com/bucket/imageobject",
+			Thumbnails: model.ImageThumbnails{
+				"64":  "https://storage.googleapis.com/bucket/thumb64",
+				"256": "https://storage.googleapis.com/bucket/thumb256",
+			},
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "imageobject").Return(nil)
+		mockImageRepository.On("DeleteProfile", mock.Anything, "thumb64").Return(apperrors.NewInternal())
+		mockImageRepository.On("DeleteProfile", mock.Anything, "thumb256").Return(nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, "", model.ImageThumbnails(nil)).Return(&model.User{UID: uid}, nil)
+
+		ctx := context.TODO()
+		err := us.ClearProfileImage(ctx, uid)
+
+		assert.NoError(t, err)
+		// the other thumbnails are still deleted
+		mockImageRepository.AssertExpectations(t)
+		mockUserRepository.AssertExpectations(t)
+	})
+}
+
+func TestSetProfileImage(t *testing.T) {
+	t.Run("Stores processed image and thumbnails", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		imageURL := "https://storage.googleapis.com/bucket/imageobject"
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		var src bytes.Buffer
+		assert.NoError(t, png.Encode(&src, halvesImage(64, 32)))
+		imageFileHeader := fileHeader(t, src.Bytes())
+
+		expectedThumbnails := model.ImageThumbnails{}
+		for _, size := range thumbnailSizes {
+			objName := fmt.Sprintf("imageobject_%d", size)
+			thumbURL := "https://storage.googleapis.com/bucket/" + objName
+			expectedThumbnails[strconv.Itoa(size)] = thumbURL
+			mockImageRepository.On("UpdateProfile", mock.Anything, objName, mock.Anything).Return(thumbURL, nil)
+		}
+
+		mockUser := &model.User{UID: uid, ImageURL: imageURL}
+		updatedUser := &model.User{UID: uid, ImageURL: imageURL, Thumbnails: expectedThumbnails}
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockImageRepository.On("UpdateProfile", mock.Anything, "imageobject", mock.Anything).Return(imageURL, nil)
+		mockUserRepository.On("UpdateImage", mock.Anything, uid, imageURL, expectedThumbnails).Return(updatedUser, nil)
+
+		ctx := context.TODO()
+		u, err := us.SetProfileImage(ctx, uid, imageFileHeader)
+
+		assert.NoError(t, err)
+		assert.Equal(t, updatedUser, u)
+		mockUserRepository.AssertExpectations(t)
+		mockImageRepository.AssertExpectations(t)
+	})
+
+	t.Run("Corrupt image is rejected", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+
+		ctx := context.TODO()
+		_, err := us.SetProfileImage(ctx, uid, fileHeader(t, []byte("\xff\xd8\xffnot a jpeg")))
+
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockImageRepository.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func fileHeader(t *testing.T, data []byte) *multipart.FileHeader {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("imageFile", "image")
+	assert.NoError(t, err)
+	_, err = part.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(int64(len(data)) + 1024)
+	assert.NoError(t, err)
+
+	return form.File["imageFile"][0]
+}
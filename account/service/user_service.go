@@ -2,12 +2,15 @@ package service
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"mime/multipart"
 	"net/url"
 	"path"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
 	"github.com/jacobsngoodwin/memrizr/account/model"
 	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
 )
@@ -15,17 +18,92 @@ import (
 type userService struct {
 	UserRepository model.UserRepository
 	ImageRepository model.ImageRepository
+	SigninAttemptRepository model.SigninAttemptRepository
+	EmailLockout LockoutPolicy
+	IPLockout LockoutPolicy
+	Mailer model.Mailer
+	EmailTokenSecret string
+	EmailTokenExpirationSecs int64
+	VerifyEmailURL string
+	TokenRepository model.TokenRepository
+	PasswordResetRepository model.PasswordResetRepository
+	PasswordResetExpirationSecs int64
+	ResetPasswordURL string
+	PasswordParams PasswordParams
+	TOTPIssuer string
+	EventBroker model.EventBroker
+	Transactor model.Transactor
+	DeletionGracePeriod time.Duration
+	AuditRepository model.AuditRepository
+	Logger model.Logger
+	// runs work the response shouldn't wait for. Tests swap it to run
+	// the work before returning
+	background func(f func())
 }
 
+// USConfig will hold repositories that will eventually be injected into
+// this service layer. Failed signins are only throttled when a
+// SigninAttemptRepository is provided, and emails are only sent with a
+// Mailer. Verification and password reset links point at VerifyEmailURL
+// and ResetPasswordURL. The TokenRepository is used to sign users out
+// everywhere when their password changes. PasswordParams defaults to
+// DefaultPasswordParams. TOTPIssuer names the service in authenticator apps.
+// Events are only published with an EventBroker, and in the same
+// transaction as the changes they describe with a Transactor. Accounts
+// users delete are kept for the DeletionGracePeriod, if any, before being
+// purged. Signins and detail changes are audited with an AuditRepository.
+// The Logger defaults to one writing to stderr
 type USConfig struct {
 	UserRepository model.UserRepository
 	ImageRepository model.ImageRepository
+	SigninAttemptRepository model.SigninAttemptRepository
+	EmailLockout LockoutPolicy
+	IPLockout LockoutPolicy
+	Mailer model.Mailer
+	EmailTokenSecret string
+	EmailTokenExpirationSecs int64
+	VerifyEmailURL string
+	TokenRepository model.TokenRepository
+	PasswordResetRepository model.PasswordResetRepository
+	PasswordResetExpirationSecs int64
+	ResetPasswordURL string
+	PasswordParams PasswordParams
+	TOTPIssuer string
+	EventBroker model.EventBroker
+	Transactor model.Transactor
+	DeletionGracePeriod time.Duration
+	AuditRepository model.AuditRepository
+	Logger model.Logger
 }
 
 func NewUserService(c *USConfig) model.UserService {
+	passwordParams := c.PasswordParams
+	if passwordParams.Algorithm == "" {
+		passwordParams = DefaultPasswordParams
+	}
+
 	return &userService {
 		UserRepository: c.UserRepository,
 		ImageRepository: c.ImageRepository,
+		SigninAttemptRepository: c.SigninAttemptRepository,
+		EmailLockout: c.EmailLockout,
+		IPLockout: c.IPLockout,
+		Mailer: c.Mailer,
+		EmailTokenSecret: c.EmailTokenSecret,
+		EmailTokenExpirationSecs: c.EmailTokenExpirationSecs,
+		VerifyEmailURL: c.VerifyEmailURL,
+		TokenRepository: c.TokenRepository,
+		PasswordResetRepository: c.PasswordResetRepository,
+		PasswordResetExpirationSecs: c.PasswordResetExpirationSecs,
+		ResetPasswordURL: c.ResetPasswordURL,
+		PasswordParams: passwordParams,
+		TOTPIssuer: c.TOTPIssuer,
+		EventBroker: c.EventBroker,
+		Transactor: c.Transactor,
+		DeletionGracePeriod: c.DeletionGracePeriod,
+		AuditRepository: c.AuditRepository,
+		Logger: logging.OrDefault(c.Logger),
+		background: func(f func()) { go f() },
 	}
 }
 func (s *userService) Get(ctx context.Context, uid uuid.UUID) (*model.User ,error) {
@@ -35,53 +113,147 @@ func (s *userService) Get(ctx context.Context, uid uuid.UUID) (*model.User ,erro
 }
 
 func (s *userService) Signup(ctx context.Context, u *model.User) error {
-	pw, err := hashPassword(u.Password)
+	pw, err := hashPassword(u.Password, s.PasswordParams)
 
 	if err != nil {
-		log.Printf("Unable to signup user for email: %v\n", u.Email)
+		s.Logger.Error(ctx, "Unable to hash password at signup", "email", u.Email, "err", err)
 		return apperrors.NewInternal()
 	}
 
 	u.Password = pw
 
-	if err := s.UserRepository.Create(ctx, u); err != nil {
+	err = withinTransaction(ctx, s.Transactor, func(ctx context.Context) error {
+		if err := s.UserRepository.Create(ctx, u); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, s.Logger, s.EventBroker, newEvent(model.EventUserCreated, u.UID, u))
+	})
+
+	if err != nil {
 		return err
 	}
 
-	// If we get around to adding events, we'd Publish it here -- maybe pubsub?
-	// err := s.EventBroker.PublishUserUpdated(u, true)
+	// the account is usable without the email, which can be sent again
+	if err := s.sendVerificationEmail(ctx, u); err != nil {
+		s.Logger.Warn(ctx, "Unable to send verification email after signup", "uid", u.UID, "err", err)
+	}
 
 	return nil
 }
 
-func (s *userService) Signin(ctx context.Context, u *model.User) error {
+// Signin checks the user's credentials. Failed attempts are counted per
+// email and client IP, and too many of them lock out further signins
+func (s *userService) Signin(ctx context.Context, u *model.User) (err error) {
+	// the user is only known once their email is found
+	var uid *uuid.UUID
+
+	defer func() {
+		recordAudit(ctx, s.Logger, s.AuditRepository, uid, model.AuditSignin, err)
+	}()
+
+	var attemptKeys []signinAttemptKey
+
+	if s.SigninAttemptRepository != nil {
+		attemptKeys = s.signinAttemptKeys(ctx, u.Email)
+
+		if err := s.checkSigninLockout(ctx, attemptKeys); err != nil {
+			return err
+		}
+	}
+
 	uFetched, err := s.UserRepository.FindByEmail(ctx, u.Email)
 
 	if err != nil {
+		s.recordSigninFailure(ctx, attemptKeys)
 		return apperrors.NewAuthorization("Invalid email and password combination")
 	}
 
+	uid = &uFetched.UID
+
 	match, err := comparePasswords(uFetched.Password, u.Password)
 
 	if err != nil {
+		s.Logger.Error(ctx, "Unable to compare passwords", "uid", uFetched.UID, "err", err)
 		return apperrors.NewInternal()
 	}
 
 	if !match {
+		s.recordSigninFailure(ctx, attemptKeys)
 		return apperrors.NewAuthorization("Invalid email and password combination")
 	}
 
+	// only checked once the password matches, so the account's status
+	// isn't given away to anyone who knows the email
+	if uFetched.Disabled {
+		return apperrors.NewForbidden("Your account has been disabled")
+	}
+
+	if uFetched.DeletedAt != nil {
+		return apperrors.NewForbidden("Your account has been deleted")
+	}
+
+	// only the email's failures are reset. Resetting the IP's would let
+	// an attacker clear them by signing in to an account of their own
+	s.resetEmailSigninFailures(ctx, u.Email)
+
+	// while we have the plain password, upgrade hashes made with an
+	// older algorithm or parameters
+	if needsRehash(uFetched.Password, s.PasswordParams) {
+		s.rehashPassword(ctx, uFetched, u.Password)
+	}
+
 	*u = *uFetched
 	return nil
 }
 
+// rehashPassword stores a new hash of the user's password. Failing
+// to do so isn't an error, as the old hash is still valid
+func (s *userService) rehashPassword(ctx context.Context, u *model.User, password string) {
+	pw, err := hashPassword(password, s.PasswordParams)
+
+	if err != nil {
+		s.Logger.Warn(ctx, "Unable to rehash password", "uid", u.UID, "err", err)
+		return
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, u.UID, pw); err != nil {
+		s.Logger.Warn(ctx, "Unable to store rehashed password", "uid", u.UID, "err", err)
+		return
+	}
+
+	u.Password = pw
+}
+
+// UpdateDetails updates the user's details. A changed email address
+// is no longer verified, so the new address is sent a verification email
 func (s *userService) UpdateDetails(ctx context.Context, u *model.User) error {
-	err := s.UserRepository.Update(ctx, u)
+	prev, err := s.UserRepository.FindByID(ctx, u.UID)
 
 	if err != nil {
 		return err
 	}
 
+	err = withinTransaction(ctx, s.Transactor, func(ctx context.Context) error {
+		if err := s.UserRepository.Update(ctx, u); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, s.Logger, s.EventBroker, newEvent(model.EventUserUpdated, u.UID, u))
+	})
+
+	recordAudit(ctx, s.Logger, s.AuditRepository, &u.UID, model.AuditDetailsUpdate, err)
+
+	if err != nil {
+		return err
+	}
+
+	if u.Email != prev.Email {
+		if err := s.sendVerificationEmail(ctx, u); err != nil {
+			s.Logger.Warn(ctx, "Unable to send verification email after email change", "uid", u.UID, "err", err)
+		}
+	}
+
 	return nil
 }
 
@@ -104,27 +276,121 @@ func (s *userService) SetProfileImage(
 
 	imageFile, err := imageFileHeader.Open()
 	if err != nil {
-		log.Printf("Failed to open image file: %v\n", err)
+		s.Logger.Error(ctx, "Unable to open image file", "uid", uid, "err", err)
 		return nil, err
 	}
 
-	imageURL, err := s.ImageRepository.UpdateProfile(ctx, objName, imageFile)	
+	defer imageFile.Close()
+
+	processed, err := processProfileImage(ctx, s.Logger, imageFile)
 
 	if err != nil {
-		log.Printf("Unable to upload image to cloud provider: %v\n", err)
+		s.Logger.Debug(ctx, "Unable to process image file", "uid", uid, "err", err)
 		return nil, err
 	}
 
-	updatedUser, err := s.UserRepository.UpdateImage(ctx, uid, imageURL)
+	imageURL, err := s.ImageRepository.UpdateProfile(ctx, objName, newImageBuffer(processed.Image))
 
 	if err != nil {
-		log.Printf("Unable to update imageURL: %v\n", err)
+		s.Logger.Error(ctx, "Unable to upload image", "uid", uid, "err", err)
+		return nil, err
+	}
+
+	thumbnails := make(model.ImageThumbnails, len(processed.Thumbnails))
+
+	for size, thumb := range processed.Thumbnails {
+		thumbURL, err := s.ImageRepository.UpdateProfile(ctx, thumbnailObjName(objName, size), newImageBuffer(thumb))
+
+		if err != nil {
+			s.Logger.Error(ctx, "Unable to upload thumbnail", "uid", uid, "size", size, "err", err)
+			return nil, err
+		}
+
+		thumbnails[strconv.Itoa(size)] = thumbURL
+	}
+
+	updatedUser, err := s.updateImage(ctx, uid, imageURL, thumbnails)
+
+	if err != nil {
+		s.Logger.Error(ctx, "Unable to update imageURL", "uid", uid, "err", err)
 		return nil, err
 	}
 
 	return updatedUser, nil
 }
 
+func (s *userService) ClearProfileImage(
+	ctx context.Context,
+	uid uuid.UUID,
+) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return err
+	}
+
+	// nothing to remove
+	if u.ImageURL == "" {
+		return nil
+	}
+
+	objName, err := objNameFromURL(u.ImageURL)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.ImageRepository.DeleteProfile(ctx, objName); err != nil {
+		s.Logger.Error(ctx, "Unable to delete image", "uid", uid, "err", err)
+		return err
+	}
+
+	// with the image gone, thumbnails are deleted best effort so the user's
+	// image is still cleared. Any left behind are only orphaned objects
+	for _, thumbURL := range u.Thumbnails {
+		thumbObjName, err := objNameFromURL(thumbURL)
+
+		if err != nil {
+			s.Logger.Warn(ctx, "Unable to find thumbnail object name", "uid", uid, "url", thumbURL, "err", err)
+			continue
+		}
+
+		if err := s.ImageRepository.DeleteProfile(ctx, thumbObjName); err != nil {
+			s.Logger.Warn(ctx, "Unable to delete thumbnail", "uid", uid, "object", thumbObjName, "err", err)
+		}
+	}
+
+	if _, err := s.updateImage(ctx, uid, "", nil); err != nil {
+		s.Logger.Error(ctx, "Unable to clear imageURL", "uid", uid, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// updateImage stores the URLs of a user's profile image, publishing
+// that it changed
+func (s *userService) updateImage(ctx context.Context, uid uuid.UUID, imageURL string, thumbnails model.ImageThumbnails) (*model.User, error) {
+	var u *model.User
+
+	err := withinTransaction(ctx, s.Transactor, func(ctx context.Context) error {
+		var err error
+		u, err = s.UserRepository.UpdateImage(ctx, uid, imageURL, thumbnails)
+
+		if err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, s.Logger, s.EventBroker, newEvent(model.EventUserImageChanged, uid, u))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
 func objNameFromURL(imageURL string) (string, error) {
 
 	if imageURL == "" {
@@ -135,9 +401,13 @@ func objNameFromURL(imageURL string) (string, error) {
 	urlPath, err := url.Parse(imageURL)
 
 	if err != nil {
-		log.Printf("Failed to parse objectNAme from imageURL: %v\n", imageURL)
 		return "", apperrors.NewInternal()
 	}
 
 	return path.Base(urlPath.Path), nil
-}
\ No newline at end of file
+}
+
+// thumbnails are stored as sibling objects of the profile image
+func thumbnailObjName(objName string, size int) string {
+	return fmt.Sprintf("%s_%d", objName, size)
+}
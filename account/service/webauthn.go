@@ -0,0 +1,262 @@
+package service
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ugorji/go/codec"
+)
+
+// WebAuthn (https://www.w3.org/TR/webauthn-2/) data structures we verify.
+// Attestation statements aren't checked, as we request "none" attestation
+// and accept passkeys from any authenticator
+
+// COSE algorithms and key parameters (RFC 8152) of the keys we accept
+const (
+	coseAlgES256 = -7
+	coseAlgRS256 = -257
+
+	coseKeyType    = 1
+	coseKeyAlg     = 3
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+	coseCurveP256  = 1
+)
+
+// authenticator data flags
+const (
+	authDataUserPresent        = 0x01
+	authDataUserVerified       = 0x04
+	authDataAttestedCredential = 0x40
+	authDataExtensions         = 0x80
+)
+
+// client data types of each ceremony
+const (
+	webAuthnCreate = "webauthn.create"
+	webAuthnGet    = "webauthn.get"
+)
+
+var cborHandle = func() *codec.CborHandle {
+	h := &codec.CborHandle{}
+	h.SignedInteger = true
+	return h
+}()
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// parseClientData checks the client data of a ceremony was collected by
+// one of our origins, leaving the challenge to be checked by the caller
+func parseClientData(raw []byte, ceremony string, origins []string) (*clientData, error) {
+	cd := &clientData{}
+
+	if err := json.Unmarshal(raw, cd); err != nil {
+		return nil, fmt.Errorf("invalid client data: %w", err)
+	}
+
+	if cd.Type != ceremony {
+		return nil, fmt.Errorf("client data is for: %s, not: %s", cd.Type, ceremony)
+	}
+
+	for _, origin := range origins {
+		if cd.Origin == origin {
+			return cd, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected origin: %s", cd.Origin)
+}
+
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// only included when a credential is registered
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("authenticator data is too short")
+	}
+
+	ad := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if ad.Flags&authDataAttestedCredential == 0 {
+		return ad, nil
+	}
+
+	// aaguid (16 bytes), credential ID length (2 bytes), credential ID
+	// and COSE public key, possibly followed by extensions
+	rest := data[37:]
+
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("attested credential data is too short")
+	}
+
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+
+	if len(rest) < idLen {
+		return nil, fmt.Errorf("credential ID is too short")
+	}
+
+	ad.CredentialID = rest[:idLen]
+	rest = rest[idLen:]
+
+	var key map[interface{}]interface{}
+	dec := codec.NewDecoderBytes(rest, cborHandle)
+
+	if err := dec.Decode(&key); err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+
+	ad.PublicKey = rest[:dec.NumBytesRead()]
+
+	if ad.Flags&authDataExtensions == 0 && dec.NumBytesRead() != len(rest) {
+		return nil, fmt.Errorf("unexpected trailing authenticator data")
+	}
+
+	return ad, nil
+}
+
+// verify checks the authenticator data is for our relying party, and that
+// the user was both present and verified (by PIN or biometric), which lets
+// a passkey stand in for a password and second factor
+func (ad *authenticatorData) verify(rpID string) error {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	if !bytes.Equal(ad.RPIDHash, rpIDHash[:]) {
+		return fmt.Errorf("authenticator data is for another relying party")
+	}
+
+	if ad.Flags&authDataUserPresent == 0 {
+		return fmt.Errorf("user was not present")
+	}
+
+	if ad.Flags&authDataUserVerified == 0 {
+		return fmt.Errorf("user was not verified")
+	}
+
+	return nil
+}
+
+// parseAttestationObject returns the authenticator data of a new credential
+func parseAttestationObject(raw []byte) (*authenticatorData, error) {
+	var obj map[interface{}]interface{}
+
+	if err := codec.NewDecoderBytes(raw, cborHandle).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+
+	authData, ok := obj["authData"].([]byte)
+
+	if !ok {
+		return nil, fmt.Errorf("attestation object has no authenticator data")
+	}
+
+	ad, err := parseAuthenticatorData(authData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ad.CredentialID == nil {
+		return nil, fmt.Errorf("attestation object has no credential")
+	}
+
+	return ad, nil
+}
+
+type coseKey struct {
+	Alg int64
+	Key crypto.PublicKey
+}
+
+// parseCOSEKey decodes an ES256 or RS256 public key
+func parseCOSEKey(raw []byte) (*coseKey, error) {
+	var m map[interface{}]interface{}
+
+	if err := codec.NewDecoderBytes(raw, cborHandle).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid COSE key: %w", err)
+	}
+
+	kty, _ := m[int64(coseKeyType)].(int64)
+	alg, _ := m[int64(coseKeyAlg)].(int64)
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == coseAlgES256:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+
+		if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid ES256 key")
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("ES256 key is not on the curve")
+		}
+
+		return &coseKey{Alg: alg, Key: key}, nil
+	case kty == coseKeyTypeRSA && alg == coseAlgRS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RS256 key")
+		}
+
+		return &coseKey{Alg: alg, Key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported COSE key type: %d, alg: %d", kty, alg)
+	}
+}
+
+// verifySignature checks an assertion's signature, which covers the
+// authenticator data and the hash of the client data
+func (k *coseKey) verifySignature(authData []byte, clientDataJSON []byte, sig []byte) error {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return fmt.Errorf("invalid ES256 signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("invalid RS256 signature: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported key type: %T", k.Key)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/ugorji/go/codec"
+)
+
+// softAuthenticator is a passkey authenticator in software, standing in
+// for the browser and authenticator in tests. It holds a single ES256
+// credential, and signs with it however it is told to
+type softAuthenticator struct {
+	RPID         string
+	Origin       string
+	Flags        byte
+	Key          *ecdsa.PrivateKey
+	CredentialID []byte
+	UserHandle   []byte
+	SignCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, rpID string, origin string) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+
+	return &softAuthenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		Flags:        authDataUserPresent | authDataUserVerified,
+		Key:          key,
+		CredentialID: credentialID,
+	}
+}
+
+func cborEncode(v interface{}) []byte {
+	var b []byte
+	codec.NewEncoderBytes(&b, cborHandle).MustEncode(v)
+	return b
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.Key.X.FillBytes(x)
+	a.Key.Y.FillBytes(y)
+
+	return cborEncode(map[int]interface{}{
+		coseKeyType: coseKeyTypeEC2,
+		coseKeyAlg:  coseAlgES256,
+		-1:          coseCurveP256,
+		-2:          x,
+		-3:          y,
+	})
+}
+
+func (a *softAuthenticator) authData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags := a.Flags
+
+	if attested {
+		flags |= authDataAttestedCredential
+	}
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.SignCount)
+
+	if attested {
+		// an all zero aaguid, as "none" attestation gives
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.CredentialID)))
+		data = append(data, a.CredentialID...)
+		data = append(data, a.coseKey()...)
+	}
+
+	return data
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	cd, _ := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+
+	return cd
+}
+
+// create registers the credential, as navigator.credentials.create would
+func (a *softAuthenticator) create(options *model.WebAuthnCreationOptions) *model.WebAuthnAttestation {
+	a.UserHandle = options.User.ID
+
+	att := &model.WebAuthnAttestation{
+		ID:   a.CredentialID,
+		Type: "public-key",
+	}
+	att.Response.ClientDataJSON = a.clientData(webAuthnCreate, options.Challenge)
+	att.Response.AttestationObject = cborEncode(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(true),
+	})
+
+	return att
+}
+
+// get signs in with the credential, as navigator.credentials.get would
+func (a *softAuthenticator) get(options *model.WebAuthnRequestOptions) *model.WebAuthnAssertion {
+	a.SignCount++
+
+	authData := a.authData(false)
+	clientDataJSON := a.clientData(webAuthnGet, options.Challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	sig, _ := ecdsa.SignASN1(rand.Reader, a.Key, digest[:])
+
+	assertion := &model.WebAuthnAssertion{
+		ID:   a.CredentialID,
+		Type: "public-key",
+	}
+	assertion.Response.ClientDataJSON = clientDataJSON
+	assertion.Response.AuthenticatorData = authData
+	assertion.Response.Signature = sig
+	assertion.Response.UserHandle = a.UserHandle
+
+	return assertion
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/logging"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+)
+
+const webAuthnChallengeLength = 32
+
+type webAuthnService struct {
+	UserRepository          model.UserRepository
+	CredentialRepository    model.WebAuthnCredentialRepository
+	ChallengeRepository     model.WebAuthnChallengeRepository
+	RPID                    string
+	RPName                  string
+	Origins                 []string
+	ChallengeExpirationSecs int64
+	AuditRepository         model.AuditRepository
+	Logger                  model.Logger
+}
+
+// WSConfig will hold repositories that will eventually be injected into
+// this service layer. RPID is the domain passkeys are bound to, and
+// Origins are the exact origins (eg, https://memrizr.com) of the pages
+// allowed to use them. Passkeys being added and removed are audited with
+// the AuditRepository
+type WSConfig struct {
+	UserRepository          model.UserRepository
+	CredentialRepository    model.WebAuthnCredentialRepository
+	ChallengeRepository     model.WebAuthnChallengeRepository
+	RPID                    string
+	RPName                  string
+	Origins                 []string
+	ChallengeExpirationSecs int64
+	AuditRepository         model.AuditRepository
+	Logger                  model.Logger
+}
+
+// NewWebAuthnService is a factory function for
+// initializing a WebAuthnService with its repository layer dependencies
+func NewWebAuthnService(c *WSConfig) model.WebAuthnService {
+	return &webAuthnService{
+		UserRepository:          c.UserRepository,
+		CredentialRepository:    c.CredentialRepository,
+		ChallengeRepository:     c.ChallengeRepository,
+		RPID:                    c.RPID,
+		RPName:                  c.RPName,
+		Origins:                 c.Origins,
+		ChallengeExpirationSecs: c.ChallengeExpirationSecs,
+		AuditRepository:         c.AuditRepository,
+		Logger:                  logging.OrDefault(c.Logger),
+	}
+}
+
+//   registration:uid -> challenge of the user's passkey registration
+//   login:challenge -> challenge of a passkey signin, whoever it is by
+func registrationChallengeKey(uid uuid.UUID) string {
+	return fmt.Sprintf("registration:%s", uid)
+}
+
+func loginChallengeKey(challenge string) string {
+	return fmt.Sprintf("login:%s", challenge)
+}
+
+// newChallenge generates and stores a random challenge under key
+func (s *webAuthnService) newChallenge(ctx context.Context, key func(string) string) ([]byte, error) {
+	challenge := make([]byte, webAuthnChallengeLength)
+
+	if _, err := rand.Read(challenge); err != nil {
+		s.Logger.Error(ctx, "Unable to generate WebAuthn challenge", "err", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(challenge)
+	expiresIn := time.Duration(s.ChallengeExpirationSecs) * time.Second
+
+	if err := s.ChallengeRepository.SetChallenge(ctx, key(encoded), encoded, expiresIn); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// BeginRegistration starts registering a passkey for the user
+func (s *webAuthnService) BeginRegistration(ctx context.Context, uid uuid.UUID) (*model.WebAuthnCreationOptions, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := s.CredentialRepository.FindByUser(ctx, uid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// passkeys the user already has on an authenticator aren't registered twice
+	exclude := make([]model.WebAuthnCredentialDescriptor, len(creds))
+	for i, c := range creds {
+		exclude[i] = model.WebAuthnCredentialDescriptor{Type: "public-key", ID: c.ID}
+	}
+
+	challenge, err := s.newChallenge(ctx, func(string) string {
+		return registrationChallengeKey(uid)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := u.Name
+	if displayName == "" {
+		displayName = u.Email
+	}
+
+	return &model.WebAuthnCreationOptions{
+		Challenge: challenge,
+		RP: model.WebAuthnRelyingParty{
+			ID:   s.RPID,
+			Name: s.RPName,
+		},
+		User: model.WebAuthnUser{
+			ID:          uid[:],
+			Name:        u.Email,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams: []model.WebAuthnCredentialParam{
+			{Type: "public-key", Alg: coseAlgES256},
+			{Type: "public-key", Alg: coseAlgRS256},
+		},
+		Timeout:            s.ChallengeExpirationSecs * 1000,
+		Attestation:        "none",
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: model.WebAuthnAuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+	}, nil
+}
+
+// FinishRegistration verifies the new credential from the user's
+// authenticator and stores it
+func (s *webAuthnService) FinishRegistration(ctx context.Context, uid uuid.UUID, name string, attestation *model.WebAuthnAttestation) (_ *model.WebAuthnCredential, err error) {
+	defer func() {
+		recordAudit(ctx, s.Logger, s.AuditRepository, &uid, model.AuditPasskeyAdd, err)
+	}()
+
+	challenge, err := s.ChallengeRepository.ConsumeChallenge(ctx, registrationChallengeKey(uid))
+
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, apperrors.NewBadRequest("Passkey registration has expired, please try again")
+		}
+		return nil, err
+	}
+
+	ad, err := s.verifyAttestation(attestation, challenge)
+
+	if err != nil {
+		s.Logger.Info(ctx, "Unable to verify passkey registration", "uid", uid, "err", err)
+		return nil, apperrors.NewBadRequest("Unable to verify passkey")
+	}
+
+	if name == "" {
+		name = "Passkey"
+	}
+
+	cred := &model.WebAuthnCredential{
+		ID:        ad.CredentialID,
+		UID:       uid,
+		PublicKey: ad.PublicKey,
+		SignCount: ad.SignCount,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.CredentialRepository.Create(ctx, cred); err != nil {
+		return nil, err
+	}
+
+	return cred, nil
+}
+
+func (s *webAuthnService) verifyAttestation(attestation *model.WebAuthnAttestation, challenge string) (*authenticatorData, error) {
+	if attestation.Type != "public-key" {
+		return nil, fmt.Errorf("unexpected credential type: %s", attestation.Type)
+	}
+
+	cd, err := parseClientData(attestation.Response.ClientDataJSON, webAuthnCreate, s.Origins)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cd.Challenge != challenge {
+		return nil, fmt.Errorf("challenge does not match")
+	}
+
+	ad, err := parseAttestationObject(attestation.Response.AttestationObject)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ad.verify(s.RPID); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(ad.CredentialID, attestation.ID) {
+		return nil, fmt.Errorf("credential ID does not match")
+	}
+
+	// only keys we can later verify signatures with are accepted
+	if _, err := parseCOSEKey(ad.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return ad, nil
+}
+
+// BeginLogin starts signing in with a passkey. Passkeys are discoverable,
+// so the authenticator tells us who the user is
+func (s *webAuthnService) BeginLogin(ctx context.Context) (*model.WebAuthnRequestOptions, error) {
+	challenge, err := s.newChallenge(ctx, loginChallengeKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.WebAuthnRequestOptions{
+		Challenge:        challenge,
+		RPID:             s.RPID,
+		Timeout:          s.ChallengeExpirationSecs * 1000,
+		AllowCredentials: []model.WebAuthnCredentialDescriptor{},
+		UserVerification: "required",
+	}, nil
+}
+
+// FinishLogin verifies a passkey's assertion, returning the user it
+// belongs to
+func (s *webAuthnService) FinishLogin(ctx context.Context, assertion *model.WebAuthnAssertion) (*model.User, error) {
+	cred, err := s.verifyAssertion(ctx, assertion)
+
+	if err != nil {
+		s.Logger.Info(ctx, "Unable to verify passkey signin", "err", err)
+
+		// failures of our own aren't the passkey's fault
+		var e *apperrors.Error
+		if errors.As(err, &e) && e.Status() == http.StatusInternalServerError {
+			return nil, err
+		}
+
+		return nil, apperrors.NewAuthorization("Unable to sign in with passkey")
+	}
+
+	return s.UserRepository.FindByID(ctx, cred.UID)
+}
+
+func (s *webAuthnService) verifyAssertion(ctx context.Context, assertion *model.WebAuthnAssertion) (*model.WebAuthnCredential, error) {
+	if assertion.Type != "public-key" {
+		return nil, fmt.Errorf("unexpected credential type: %s", assertion.Type)
+	}
+
+	cd, err := parseClientData(assertion.Response.ClientDataJSON, webAuthnGet, s.Origins)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// consumed first, so a challenge can't be retried whatever the outcome
+	if _, err := s.ChallengeRepository.ConsumeChallenge(ctx, loginChallengeKey(cd.Challenge)); err != nil {
+		return nil, err
+	}
+
+	cred, err := s.CredentialRepository.FindByID(ctx, assertion.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(assertion.Response.UserHandle) > 0 && !bytes.Equal(assertion.Response.UserHandle, cred.UID[:]) {
+		return nil, fmt.Errorf("user handle does not match credential")
+	}
+
+	ad, err := parseAuthenticatorData(assertion.Response.AuthenticatorData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ad.verify(s.RPID); err != nil {
+		return nil, err
+	}
+
+	key, err := parseCOSEKey(cred.PublicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := key.verifySignature(assertion.Response.AuthenticatorData, assertion.Response.ClientDataJSON, assertion.Response.Signature); err != nil {
+		return nil, err
+	}
+
+	// authenticators which count signatures always increase the count,
+	// so a count which hasn't means the credential may have been cloned
+	if (ad.SignCount != 0 || cred.SignCount != 0) && ad.SignCount <= cred.SignCount {
+		return nil, fmt.Errorf("sign count did not increase for credential: %v", cred.ID)
+	}
+
+	if err := s.CredentialRepository.UpdateSignCount(ctx, cred.ID, ad.SignCount); err != nil {
+		return nil, err
+	}
+
+	return cred, nil
+}
+
+// GetCredentials lists the user's passkeys
+func (s *webAuthnService) GetCredentials(ctx context.Context, uid uuid.UUID) ([]*model.WebAuthnCredential, error) {
+	return s.CredentialRepository.FindByUser(ctx, uid)
+}
+
+// DeleteCredential removes one of the user's passkeys
+func (s *webAuthnService) DeleteCredential(ctx context.Context, uid uuid.UUID, credentialID []byte) error {
+	err := s.CredentialRepository.Delete(ctx, uid, credentialID)
+
+	recordAudit(ctx, s.Logger, s.AuditRepository, &uid, model.AuditPasskeyRemove, err)
+
+	return err
+}
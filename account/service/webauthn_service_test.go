@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jacobsngoodwin/memrizr/account/model"
+	"github.com/jacobsngoodwin/memrizr/account/model/apperrors"
+	"github.com/jacobsngoodwin/memrizr/account/model/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testRPID   = "memrizr.test"
+	testOrigin = "https://memrizr.test"
+)
+
+func newTestWebAuthnService() (model.WebAuthnService, *mocks.MockUserRepository, *mocks.MockWebAuthnCredentialRepository, *mocks.MockWebAuthnChallengeRepository) {
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockCredentialRepository := new(mocks.MockWebAuthnCredentialRepository)
+	mockChallengeRepository := new(mocks.MockWebAuthnChallengeRepository)
+
+	ws := NewWebAuthnService(&WSConfig{
+		UserRepository:          mockUserRepository,
+		CredentialRepository:    mockCredentialRepository,
+		ChallengeRepository:     mockChallengeRepository,
+		RPID:                    testRPID,
+		RPName:                  "Memrizr",
+		Origins:                 []string{testOrigin},
+		ChallengeExpirationSecs: 300,
+	})
+
+	return ws, mockUserRepository, mockCredentialRepository, mockChallengeRepository
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	u := &model.User{UID: uid, Email: "bob@bob.com", Name: "Bob"}
+
+	// begin runs the first half of the ceremony, returning the options
+	// for the authenticator and the challenge the service stored
+	begin := func(t *testing.T) (model.WebAuthnService, *mocks.MockWebAuthnCredentialRepository, *mocks.MockWebAuthnChallengeRepository, *model.WebAuthnCreationOptions, string) {
+		ws, mockUserRepository, mockCredentialRepository, mockChallengeRepository := newTestWebAuthnService()
+
+		var challenge string
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+		mockCredentialRepository.On("FindByUser", mock.Anything, uid).Return([]*model.WebAuthnCredential{}, nil)
+		mockChallengeRepository.On("SetChallenge", mock.Anything, "registration:"+uid.String(), mock.Anything, 300*time.Second).Return(nil).Run(func(args mock.Arguments) {
+			challenge = args.String(2)
+		})
+
+		options, err := ws.BeginRegistration(context.TODO(), uid)
+		assert.NoError(t, err)
+
+		return ws, mockCredentialRepository, mockChallengeRepository, options, challenge
+	}
+
+	t.Run("Options", func(t *testing.T) {
+		_, _, _, options, challenge := begin(t)
+
+		assert.Equal(t, challenge, base64.RawURLEncoding.EncodeToString(options.Challenge))
+		assert.Equal(t, testRPID, options.RP.ID)
+		assert.Equal(t, model.Base64URL(uid[:]), options.User.ID)
+		assert.Equal(t, "bob@bob.com", options.User.Name)
+		assert.Equal(t, "Bob", options.User.DisplayName)
+		assert.Equal(t, "required", options.AuthenticatorSelection.UserVerification)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		ws, mockCredentialRepository, mockChallengeRepository, options, challenge := begin(t)
+		authenticator := newSoftAuthenticator(t, testRPID, testOrigin)
+
+		mockChallengeRepository.On("ConsumeChallenge", mock.Anything, "registration:"+uid.String()).Return(challenge, nil)
+		mockCredentialRepository.On("Create", mock.Anything, mock.MatchedBy(func(c *model.WebAuthnCredential) bool {
+			_, err := parseCOSEKey(c.PublicKey)
+			return err == nil && c.UID == uid && string(c.ID) == string(authenticator.CredentialID)
+		})).Return(nil)
+
+		cred, err := ws.FinishRegistration(context.TODO(), uid, "My laptop", authenticator.create(options))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "My laptop", cred.Name)
+		mockCredentialRepository.AssertExpectations(t)
+	})
+
+	failures := map[string]func(a *softAuthenticator, options *model.WebAuthnCreationOptions){
+		"Wrong origin": func(a *softAuthenticator, options *model.WebAuthnCreationOptions) {
+			a.Origin = "https://evil.test"
+		},
+		"Wrong relying party": func(a *softAuthenticator, options *model.WebAuthnCreationOptions) {
+			a.RPID = "evil.test"
+		},
+		"Wrong challenge": func(a *softAuthenticator, options *model.WebAuthnCreationOptions) {
+			options.Challenge = []byte("notthechallenge")
+		},
+		"User not verified": func(a *softAuthenticator, options *model.WebAuthnCreationOptions) {
+			a.Flags = authDataUserPresent
+		},
+	}
+
+	for name, tamper := range failures {
+		t.Run(name, func(t *testing.T) {
+			ws, mockCredentialRepository, mockChallengeRepository, options, challenge := begin(t)
+			authenticator := newSoftAuthenticator(t, testRPID, testOrigin)
+
+			mockChallengeRepository.On("ConsumeChallenge", mock.Anything, "registration:"+uid.String()).Return(challenge, nil)
+
+			tamper(authenticator, options)
+			cred, err := ws.FinishRegistration(context.TODO(), uid, "", authenticator.create(options))
+
+			assert.Nil(t, cred)
+			assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+			mockCredentialRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("Expired challenge", func(t *testing.T) {
+		ws, mockCredentialRepository, mockChallengeRepository, options, _ := begin(t)
+		authenticator := newSoftAuthenticator(t, testRPID, testOrigin)
+
+		mockChallengeRepository.On("ConsumeChallenge", mock.Anything, "registration:"+uid.String()).Return("", apperrors.NewNotFound("webauthn challenge", ""))
+
+		cred, err := ws.FinishRegistration(context.TODO(), uid, "", authenticator.create(options))
+
+		assert.Nil(t, cred)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockCredentialRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	u := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	// registered returns an authenticator with a credential registered to
+	// the user, along with the stored credential
+	registered := func(t *testing.T) (*softAuthenticator, *model.WebAuthnCredential) {
+		authenticator := newSoftAuthenticator(t, testRPID, testOrigin)
+		authenticator.UserHandle = uid[:]
+
+		ad, err := parseAuthenticatorData(authenticator.authData(true))
+		assert.NoError(t, err)
+
+		return authenticator, &model.WebAuthnCredential{
+			ID:        authenticator.CredentialID,
+			UID:       uid,
+			PublicKey: ad.PublicKey,
+		}
+	}
+
+	begin := func(t *testing.T) (model.WebAuthnService, *mocks.MockUserRepository, *mocks.MockWebAuthnCredentialRepository, *mocks.MockWebAuthnChallengeRepository, *model.WebAuthnRequestOptions) {
+		ws, mockUserRepository, mockCredentialRepository, mockChallengeRepository := newTestWebAuthnService()
+
+		mockChallengeRepository.On("SetChallenge", mock.Anything, mock.Anything, mock.Anything, 300*time.Second).Return(nil).Run(func(args mock.Arguments) {
+			challenge := args.String(2)
+			mockChallengeRepository.On("ConsumeChallenge", mock.Anything, "login:"+challenge).Return(challenge, nil).Once()
+		})
+
+		options, err := ws.BeginLogin(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, testRPID, options.RPID)
+		assert.Empty(t, options.AllowCredentials)
+
+		return ws, mockUserRepository, mockCredentialRepository, mockChallengeRepository, options
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ws, mockUserRepository, mockCredentialRepository, _, options := begin(t)
+		authenticator, cred := registered(t)
+
+		mockCredentialRepository.On("FindByID", mock.Anything, []byte(cred.ID)).Return(cred, nil)
+		mockCredentialRepository.On("UpdateSignCount", mock.Anything, []byte(cred.ID), uint32(1)).Return(nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+
+		user, err := ws.FinishLogin(context.TODO(), authenticator.get(options))
+
+		assert.NoError(t, err)
+		assert.Equal(t, u, user)
+		mockCredentialRepository.AssertExpectations(t)
+	})
+
+	t.Run("Challenge can only be answered once", func(t *testing.T) {
+		ws, mockUserRepository, mockCredentialRepository, mockChallengeRepository, options := begin(t)
+		authenticator, cred := registered(t)
+
+		mockCredentialRepository.On("FindByID", mock.Anything, []byte(cred.ID)).Return(cred, nil)
+		mockCredentialRepository.On("UpdateSignCount", mock.Anything, []byte(cred.ID), mock.Anything).Return(nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(u, nil)
+		mockChallengeRepository.On("ConsumeChallenge", mock.Anything, mock.Anything).Return("", apperrors.NewNotFound("webauthn challenge", ""))
+
+		assertion := authenticator.get(options)
+
+		_, err := ws.FinishLogin(context.TODO(), assertion)
+		assert.NoError(t, err)
+
+		user, err := ws.FinishLogin(context.TODO(), assertion)
+		assert.Nil(t, user)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Unknown credential", func(t *testing.T) {
+		ws, mockUserRepository, mockCredentialRepository, _, options := begin(t)
+		authenticator, cred := registered(t)
+
+		mockCredentialRepository.On("FindByID", mock.Anything, []byte(cred.ID)).Return(nil, apperrors.NewNotFound("credential", ""))
+
+		user, err := ws.FinishLogin(context.TODO(), authenticator.get(options))
+
+		assert.Nil(t, user)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	failures := map[string]func(a *softAuthenticator, cred *model.WebAuthnCredential, assertion *model.WebAuthnAssertion){
+		"Bad signature": func(a *softAuthenticator, cred *model.WebAuthnCredential, assertion *model.WebAuthnAssertion) {
+			// the credential was registered with another key
+			other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			cred.PublicKey = (&softAuthenticator{Key: other}).coseKey()
+		},
+		"Another user's handle": func(a *softAuthenticator, cred *model.WebAuthnCredential, assertion *model.WebAuthnAssertion) {
+			other, _ := uuid.NewRandom()
+			assertion.Response.UserHandle = other[:]
+		},
+		"Sign count did not increase": func(a *softAuthenticator, cred *model.WebAuthnCredential, assertion *model.WebAuthnAssertion) {
+			cred.SignCount = 5
+		},
+		"Tampered authenticator data": func(a *softAuthenticator, cred *model.WebAuthnCredential, assertion *model.WebAuthnAssertion) {
+			assertion.Response.AuthenticatorData[36]++
+		},
+	}
+
+	for name, tamper := range failures {
+		t.Run(name, func(t *testing.T) {
+			ws, mockUserRepository, mockCredentialRepository, _, options := begin(t)
+			authenticator, cred := registered(t)
+
+			mockCredentialRepository.On("FindByID", mock.Anything, []byte(cred.ID)).Return(cred, nil)
+
+			assertion := authenticator.get(options)
+			tamper(authenticator, cred, assertion)
+
+			user, err := ws.FinishLogin(context.TODO(), assertion)
+
+			assert.Nil(t, user)
+			assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+			mockCredentialRepository.AssertNotCalled(t, "UpdateSignCount", mock.Anything, mock.Anything, mock.Anything)
+			mockUserRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCOSEKeyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	raw := cborEncode(map[int]interface{}{
+		coseKeyType: coseKeyTypeRSA,
+		coseKeyAlg:  coseAlgRS256,
+		-1:          key.N.Bytes(),
+		-2:          []byte{1, 0, 1},
+	})
+
+	cose, err := parseCOSEKey(raw)
+	assert.NoError(t, err)
+
+	authData := []byte("authenticatordata")
+	clientDataJSON := []byte(`{"type":"webauthn.get"}`)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+
+	assert.NoError(t, cose.verifySignature(authData, clientDataJSON, sig))
+	assert.Error(t, cose.verifySignature(authData, []byte(`{"type":"other"}`), sig))
+}